@@ -25,7 +25,7 @@ func main() {
 	errLogger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 
 	var (
-		service    = clients.NewClientsServer(token.JWTEncoder{}, map[string]string{"John": "pass"})
+		service    = clients.NewClientsServer(token.JWTEncoder{}, map[string]string{"John": "pass", commons.RoomsAdmin: "admin"})
 		endpoints  = clients.MakeEndpoints(service)
 		grpcServer = clients.NewGRPCServer(endpoints)
 	)
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -16,14 +22,49 @@ import (
 
 	kitlog "github.com/go-kit/kit/log"
 	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
 	"github.com/oklog/oklog/pkg/group"
 	"google.golang.org/grpc"
 )
 
+var inventory = []rooms.RoomInfo{
+	{Id: 1, Name: "101", Type: rooms.SingleRoom, Capacity: 1},
+	{Id: 2, Name: "102", Type: rooms.DoubleRoom, Capacity: 2, Amenities: []string{"balcony"}},
+	{Id: 3, Name: "201", Type: rooms.SuiteRoom, Capacity: 4, Amenities: []string{"balcony", "bathtub"}},
+}
+
+// Booking rules by room type on top of the ones for every room
+var roomTypeRules = map[string][]rooms.Rule{
+	rooms.SuiteRoom: {rooms.MinStay(2, time.Saturday)},
+}
+
 func main() {
-	grpcAddr := commons.RoomsGrpcAddr
+	if runAdmin(os.Args[1:]) {
+		return
+	}
+
 	clientGrpcAddr := commons.ClientsGrpcAddr
 
+	grpcAddr := flag.String("addr", commons.RoomsGrpcAddr, "address the gRPC server listens on")
+	storeKind := flag.String("store", commons.RoomsStore, "booking store: memory, bolt or raft")
+	dbPath := flag.String("db", commons.RoomsDBPath, "path to the bolt database file")
+	cluster := flag.String("cluster", "", "nodes of the raft cluster as comma separated gRPC-address=raft-address pairs")
+	node := flag.String("node", "", "gRPC address of this node in the raft cluster, -addr if empty")
+	raftDir := flag.String("raft-dir", commons.RoomsRaftDir, "directory of the raft log and snapshots")
+	holdTTL := flag.Duration("hold-ttl", commons.RoomsHoldTTL, "how long holds keep their room until confirmed")
+	allocation := flag.String("allocation", commons.RoomsAllocation, "room assignment: first-fit, lru, round-robin, random or same-room")
+	maxLeadDays := flag.Int("max-lead-days", commons.RoomsMaxLeadDays, "how many days ahead stays can start")
+	sameDayCutoff := flag.Int("same-day-cutoff", commons.RoomsSameDayCutoff, "hour (UTC) same-day check-ins close at")
+	maxActiveBookings := flag.Int("max-active-bookings", commons.RoomsMaxActiveBookings, "bookings a user can have not checked out yet on this service (per shard when sharded), 0 for no limit")
+	journalPath := flag.String("journal", commons.RoomsJournalPath, "path to the journal of booking changes, empty to keep none")
+	restorePath := flag.String("restore", "", "path of a snapshot to load into an empty store at startup")
+	maxRoomsPerDate := flag.Int("max-rooms-per-date", commons.RoomsMaxRoomsPerDate, "rooms a user can book for the same night on this service (per shard when sharded), 0 for no limit")
+	webhookRetries := flag.Int("webhook-retries", commons.RoomsWebhookRetries, "times a failed webhook delivery is retried before it is dead-lettered")
+	webhookBackoff := flag.Duration("webhook-backoff", commons.RoomsWebhookBackoff, "wait before the first retry of a webhook delivery, doubled for each next one")
+	webhookTimeout := flag.Duration("webhook-timeout", commons.RoomsWebhookTimeout, "how long a webhook has to respond to a delivery")
+	flag.Parse()
+
 	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stdout))
 	errLogger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 
@@ -32,30 +73,125 @@ func main() {
 		errLogger.Log("transport", "gRPC", "message", "could not connect to clients service", "error", err)
 	}
 
-	roomsCollection := []rooms.Room{
-		{
-			map[time.Time]string{},
-			&sync.Mutex{},
-		},
-		{
-			map[time.Time]string{},
-			&sync.Mutex{},
-		},
-		{
-			map[time.Time]string{},
-			&sync.Mutex{},
-		},
+	var store rooms.BookingStore
+	var raftStore *rooms.RaftStore
+	switch *storeKind {
+	case "memory":
+		store = rooms.NewMemoryStore(inventoryRooms())
+	case "bolt":
+		boltStore, err := rooms.NewBoltStore(*dbPath, inventory)
+		if err != nil {
+			errLogger.Log("message", "could not open bolt database", "path", *dbPath, "error", err)
+			os.Exit(1)
+		}
+		defer boltStore.Close()
+		store = boltStore
+	case "raft":
+		if *node == "" {
+			*node = *grpcAddr
+		}
+		var closeRaft func()
+		raftStore, closeRaft, err = newRaftStore(*cluster, *node, *raftDir)
+		if err != nil {
+			errLogger.Log("message", "could not join raft cluster", "cluster", *cluster, "node", *node, "error", err)
+			os.Exit(1)
+		}
+		defer closeRaft()
+		store = raftStore
+	default:
+		errLogger.Log("message", "unknown booking store", "store", *storeKind)
+		os.Exit(1)
+	}
+
+	// A restored store would not match the bookings the journal rebuilds
+	if *restorePath != "" && *journalPath != "" {
+		errLogger.Log("message", "a snapshot can not be restored together with a journal")
+		os.Exit(1)
+	}
+	// The raft log replaces both on every node of the cluster
+	if raftStore != nil && (*restorePath != "" || *journalPath != "") {
+		errLogger.Log("message", "the raft store keeps no journal and restores no snapshot")
+		os.Exit(1)
+	}
+	if *restorePath != "" {
+		snapshot, err := readSnapshotFile(*restorePath)
+		if err == nil {
+			err = rooms.Restore(snapshot, store)
+		}
+		if err != nil {
+			errLogger.Log("message", "could not restore snapshot", "path", *restorePath, "error", err)
+			os.Exit(1)
+		}
+		logger.Log("message", "restored snapshot", "path", *restorePath, "taken", snapshot.Taken)
+	}
+
+	var journal rooms.Journal
+	if *journalPath != "" {
+		fileJournal, err := rooms.NewFileJournal(*journalPath)
+		if err != nil {
+			errLogger.Log("message", "could not open journal", "path", *journalPath, "error", err)
+			os.Exit(1)
+		}
+		defer fileJournal.Close()
+		// The bolt store already persists the bookings the journal rebuilds
+		if *storeKind == "memory" {
+			if err := rooms.Replay(fileJournal, store); err != nil {
+				errLogger.Log("message", "could not replay journal", "path", *journalPath, "error", err)
+				os.Exit(1)
+			}
+		}
+		journal = fileJournal
+	}
+
+	var allocator rooms.Allocator
+	switch *allocation {
+	case "first-fit":
+		allocator = rooms.NewFirstFitAllocator()
+	case "lru":
+		allocator = rooms.NewLRUAllocator()
+	case "round-robin":
+		allocator = rooms.NewRoundRobinAllocator()
+	case "random":
+		allocator = rooms.NewRandomAllocator(time.Now().UnixNano())
+	case "same-room":
+		allocator = rooms.NewSameRoomAllocator(store, rooms.NewFirstFitAllocator())
+	default:
+		errLogger.Log("message", "unknown room allocation", "allocation", *allocation)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := []rooms.Option{
+		rooms.WithAdmins(commons.RoomsAdmin),
+		rooms.WithHoldTTL(*holdTTL),
+		rooms.WithHoldReaper(ctx, commons.RoomsHoldReapInterval),
+		rooms.WithAllocator(allocator),
+		rooms.WithRules("", rooms.NoPastCheckIn(), rooms.MaxLeadTime(*maxLeadDays), rooms.SameDayCutoff(*sameDayCutoff)),
+		rooms.WithQuota(rooms.Quota{MaxActiveBookings: *maxActiveBookings, MaxRoomsPerDate: *maxRoomsPerDate}),
+		rooms.WithWebhooks(rooms.NewWebhookDispatcher(ctx, &http.Client{Timeout: *webhookTimeout}, *webhookRetries, *webhookBackoff)),
+	}
+	for roomType, rules := range roomTypeRules {
+		options = append(options, rooms.WithRules(roomType, rules...))
+	}
+	if journal != nil {
+		options = append(options, rooms.WithJournal(journal))
 	}
 
 	var (
-		service    = rooms.NewRoomsServer(roomsCollection, clients.NewGRPCClient(clientGRPCconn))
-		endpoints  = rooms.MakeEndpoints(service)
-		grpcServer = rooms.NewGRPCServer(endpoints)
+		service   = rooms.NewRoomsServer(store, clients.NewGRPCClient(clientGRPCconn), options...)
+		endpoints = rooms.MakeEndpoints(service)
 	)
+	// Followers forward changes to the leader of the cluster
+	if raftStore != nil {
+		endpoints = rooms.ForwardToLeader(endpoints, raftStore.Leader, commons.RoomsLeaderWait, grpc.WithInsecure())
+	}
+	grpcServer := rooms.NewGRPCServer(endpoints)
 
 	var g group.Group
 
-	grpcListener, err := net.Listen("tcp", grpcAddr)
+	grpcListener, err := net.Listen("tcp", *grpcAddr)
 	if err != nil {
 		errLogger.Log("message", "could not set up gRPC listner", "error", err)
 	}
@@ -82,6 +218,303 @@ func main() {
 		close(cancelInterrupt)
 	})
 
-	logger.Log("gRPC", "listening", "addr", grpcAddr)
+	logger.Log("gRPC", "listening", "addr", *grpcAddr, "store", *storeKind, "allocation", *allocation)
 	g.Run()
 }
+
+// Returns the rooms of the inventory with no bookings
+func inventoryRooms() []rooms.Room {
+	collection := make([]rooms.Room, len(inventory))
+	for i, info := range inventory {
+		collection[i] = rooms.Room{RoomInfo: info, Book: map[time.Time]string{}, Mux: &sync.Mutex{}}
+	}
+	return collection
+}
+
+// Joins the raft cluster of the nodes given as gRPC-address=raft-address pairs
+// as the node with a gRPC address, keeping the raft log and snapshots in a directory.
+// The cluster is bootstrapped the first time a node starts
+// Returns the store and a function stopping the node
+func newRaftStore(cluster, node, dir string) (*rooms.RaftStore, func(), error) {
+	var configuration raft.Configuration
+	var bind string
+	for _, pair := range strings.Split(cluster, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, nil, fmt.Errorf("invalid cluster node %q", pair)
+		}
+		configuration.Servers = append(configuration.Servers, raft.Server{
+			ID:      raft.ServerID(parts[0]),
+			Address: raft.ServerAddress(parts[1]),
+		})
+		if parts[0] == node {
+			bind = parts[1]
+		}
+	}
+	if bind == "" {
+		return nil, nil, fmt.Errorf("node %s is not in the cluster", node)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, err
+	}
+	logs, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshots, err := raft.NewFileSnapshotStore(dir, 2, os.Stderr)
+	if err != nil {
+		logs.Close()
+		return nil, nil, err
+	}
+	transport, err := raft.NewTCPTransport(bind, nil, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		logs.Close()
+		return nil, nil, err
+	}
+
+	config := raft.DefaultConfig()
+	config.LocalID = raft.ServerID(node)
+	fsm := rooms.NewStoreFSM(inventoryRooms())
+	r, err := raft.NewRaft(config, fsm, logs, logs, snapshots, transport)
+	if err != nil {
+		logs.Close()
+		return nil, nil, err
+	}
+	stop := func() {
+		r.Shutdown().Error()
+		logs.Close()
+	}
+	existing, err := raft.HasExistingState(logs, logs, snapshots)
+	if err == nil && !existing {
+		err = r.BootstrapCluster(configuration).Error()
+	}
+	if err != nil {
+		stop()
+		return nil, nil, err
+	}
+	return rooms.NewRaftStore(r, fsm, commons.RoomsRaftApplyTimeout), stop, nil
+}
+
+// Runs an admin command, returning false if the arguments do not name one
+func runAdmin(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	var err error
+	switch args[0] {
+	case "snapshot":
+		err = snapshotCommand(args[1:])
+	case "restore":
+		err = restoreCommand(args[1:])
+	case "add-webhook":
+		err = addWebhookCommand(args[1:])
+	case "webhooks":
+		err = webhooksCommand(args[1:])
+	case "remove-webhook":
+		err = removeWebhookCommand(args[1:])
+	case "dead-letters":
+		err = deadLettersCommand(args[1:])
+	default:
+		return false
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// Asks a running rooms service for a snapshot and writes it to a file
+func snapshotCommand(args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	addr := flags.String("addr", commons.RoomsGrpcAddr, "address of the rooms service")
+	token := flags.String("token", "", "token of an administrator")
+	out := flags.String("out", commons.RoomsSnapshotPath, "path to write the snapshot to")
+	timeout := flags.Duration("timeout", time.Minute, "how long to wait for the snapshot")
+	flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, *addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	snapshot, err := rooms.NewGRPCClient(conn).Snapshot(ctx, *token)
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshotFile(*out, snapshot); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d rooms, %d reservations and %d blackouts taken at %s to %s\n",
+		len(snapshot.Rooms), len(snapshot.Reservations), len(snapshot.Blackouts), snapshot.Taken.Format(time.RFC3339), *out)
+	return nil
+}
+
+// Restores a snapshot into a bolt database while the rooms service is stopped
+func restoreCommand(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("in", commons.RoomsSnapshotPath, "path of the snapshot to restore")
+	dbPath := flags.String("db", commons.RoomsDBPath, "path to the bolt database file")
+	flags.Parse(args)
+
+	snapshot, err := readSnapshotFile(*in)
+	if err != nil {
+		return err
+	}
+	store, err := rooms.NewBoltStore(*dbPath, nil)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := rooms.Restore(snapshot, store); err != nil {
+		return err
+	}
+	fmt.Printf("restored %d rooms, %d reservations and %d blackouts taken at %s into %s\n",
+		len(snapshot.Rooms), len(snapshot.Reservations), len(snapshot.Blackouts), snapshot.Taken.Format(time.RFC3339), *dbPath)
+	return nil
+}
+
+// Flags of the admin commands calling a running rooms service
+type adminFlags struct {
+	addr    *string
+	token   *string
+	timeout *time.Duration
+}
+
+func newAdminFlags(flags *flag.FlagSet) adminFlags {
+	return adminFlags{
+		addr:    flags.String("addr", commons.RoomsGrpcAddr, "address of the rooms service"),
+		token:   flags.String("token", "", "token of an administrator"),
+		timeout: flags.Duration("timeout", 10*time.Second, "how long to wait for the rooms service"),
+	}
+}
+
+// Calls the rooms service with a client, closing the connection after it
+func (a adminFlags) call(fn func(context.Context, rooms.RoomsService) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *a.timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, *a.addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return fn(ctx, rooms.NewGRPCClient(conn))
+}
+
+// Subscribes a webhook to booking events and prints its id and secret
+func addWebhookCommand(args []string) error {
+	flags := flag.NewFlagSet("add-webhook", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+	url := flags.String("url", "", "url the events are posted to")
+	events := flags.String("events", "", "comma separated types of the events posted, every type if empty")
+	secret := flags.String("secret", "", "key the payloads are signed with, generated if empty")
+	flags.Parse(args)
+
+	webhook := rooms.Webhook{Url: *url, Secret: *secret}
+	for _, event := range strings.Split(*events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			webhook.Events = append(webhook.Events, event)
+		}
+	}
+	return admin.call(func(ctx context.Context, service rooms.RoomsService) error {
+		added, err := service.AddWebhook(ctx, *admin.token, webhook)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added webhook %s posting to %s\nsecret: %s\n", added.Id, added.Url, added.Secret)
+		return nil
+	})
+}
+
+// Prints the webhooks, one per line
+func webhooksCommand(args []string) error {
+	flags := flag.NewFlagSet("webhooks", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+	flags.Parse(args)
+
+	return admin.call(func(ctx context.Context, service rooms.RoomsService) error {
+		webhooks, err := service.ListWebhooks(ctx, *admin.token)
+		if err != nil {
+			return err
+		}
+		for _, webhook := range webhooks {
+			events := "all"
+			if len(webhook.Events) > 0 {
+				events = strings.Join(webhook.Events, ",")
+			}
+			fmt.Printf("%s %s %s\n", webhook.Id, webhook.Url, events)
+		}
+		return nil
+	})
+}
+
+// Unsubscribes a webhook
+func removeWebhookCommand(args []string) error {
+	flags := flag.NewFlagSet("remove-webhook", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+	id := flags.String("id", "", "id of the webhook")
+	flags.Parse(args)
+
+	return admin.call(func(ctx context.Context, service rooms.RoomsService) error {
+		if err := service.RemoveWebhook(ctx, *admin.token, *id); err != nil {
+			return err
+		}
+		fmt.Printf("removed webhook %s\n", *id)
+		return nil
+	})
+}
+
+// Prints the events that could not be delivered to a webhook, one per line
+func deadLettersCommand(args []string) error {
+	flags := flag.NewFlagSet("dead-letters", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+	flags.Parse(args)
+
+	return admin.call(func(ctx context.Context, service rooms.RoomsService) error {
+		deadLetters, err := service.DeadLetters(ctx, *admin.token)
+		if err != nil {
+			return err
+		}
+		for _, deadLetter := range deadLetters {
+			event := deadLetter.Payload.Event
+			fmt.Printf("%s %s %s %s %s after %d attempts: %s\n",
+				deadLetter.Failed.Format(time.RFC3339), deadLetter.Payload.Webhook, event.Type, event.Reservation.Id,
+				deadLetter.Url, deadLetter.Attempts, deadLetter.Error)
+		}
+		return nil
+	})
+}
+
+func readSnapshotFile(path string) (rooms.Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return rooms.Snapshot{}, err
+	}
+	defer file.Close()
+	return rooms.ReadSnapshot(file)
+}
+
+// The snapshot is written next to the file and renamed over it
+// so an interrupted write never leaves a partial snapshot behind
+func writeSnapshotFile(path string, snapshot rooms.Snapshot) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := rooms.WriteSnapshot(tmp, snapshot); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
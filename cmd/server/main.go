@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"go-booking-service/commons"
 	"go-booking-service/pkg/clients"
 	"go-booking-service/pkg/rooms"
 	"go-booking-service/pkg/server"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	kitlog "github.com/go-kit/kit/log"
@@ -23,6 +29,9 @@ func main() {
 	clientsGrpcAddr := commons.ClientsGrpcAddr
 	roomsGrpcAddr := commons.RoomsGrpcAddr
 
+	shardsPath := flag.String("shards-file", "", "JSON file of the rooms shards and pinned months, reloaded on SIGHUP; empty for a single rooms service")
+	flag.Parse()
+
 	logger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stdout))
 	errLogger := kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 
@@ -31,13 +40,32 @@ func main() {
 		errLogger.Log("transport", "gRPC", "message", "could not connect to clients service", "error", err)
 	}
 
-	roomsGRPCconn, err := grpc.Dial(roomsGrpcAddr, grpc.WithInsecure())
-	if err != nil {
-		errLogger.Log("transport", "gRPC", "message", "could not connect to rooms service", "error", err)
+	var roomsClient server.RoomService
+	var sharded *server.ShardedRooms
+	// Connections to the shards by address, kept across reloads
+	shardConns := map[string]*grpc.ClientConn{}
+	if *shardsPath == "" {
+		roomsGRPCconn, err := grpc.Dial(roomsGrpcAddr, grpc.WithInsecure())
+		if err != nil {
+			errLogger.Log("transport", "gRPC", "message", "could not connect to rooms service", "error", err)
+		}
+		roomsClient = rooms.NewGRPCClient(roomsGRPCconn)
+	} else {
+		file, err := readShardsFile(*shardsPath)
+		var shards []server.Shard
+		if err == nil {
+			shards, err = dialShards(file, shardConns)
+		}
+		if err != nil {
+			errLogger.Log("message", "could not set up rooms shards", "path", *shardsPath, "error", err)
+			os.Exit(1)
+		}
+		sharded = server.NewShardedRooms(shards, file.Pins)
+		roomsClient = sharded
 	}
 
 	var (
-		service     = server.NewServer(clients.NewGRPCClient(clientsGRPCconn), rooms.NewGRPCClient(roomsGRPCconn))
+		service     = server.NewServer(clients.NewGRPCClient(clientsGRPCconn), roomsClient)
 		endpoints   = server.MakeEndpoints(service)
 		httpHandler = server.NewHTTPHandler(endpoints)
 	)
@@ -67,6 +95,113 @@ func main() {
 		close(cancelInterrupt)
 	})
 
+	if sharded != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, syscall.SIGHUP)
+			for {
+				select {
+				case <-c:
+				case <-ctx.Done():
+					return nil
+				}
+				moved, err := rebalanceShards(ctx, sharded, *shardsPath, shardConns)
+				if err != nil {
+					errLogger.Log("message", "could not rebalance rooms shards", "path", *shardsPath, "error", err)
+					continue
+				}
+				logger.Log("message", "rebalanced rooms shards", "moved", strings.Join(moved, ","), "pinned", len(sharded.Pins()))
+			}
+		}, func(error) {
+			cancel()
+		})
+	}
+
 	logger.Log("HTTP", "listening", "addr", httpAddr)
 	g.Run()
 }
+
+// shardsFile lists the rooms shards by name and the months pinned to them
+type shardsFile struct {
+	Shards []shardAddr       `json:"shards"`
+	Pins   map[string]string `json:"pins"`
+}
+
+type shardAddr struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+}
+
+func readShardsFile(path string) (shardsFile, error) {
+	var file shardsFile
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, err
+	}
+	if len(file.Shards) == 0 {
+		return file, fmt.Errorf("no shards in %s", path)
+	}
+	return file, nil
+}
+
+// Written to a temporary file renamed over the file, so it is never left half written
+func writeShardsFile(path string, file shardsFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// The shards are dialed once per address
+func dialShards(file shardsFile, conns map[string]*grpc.ClientConn) ([]server.Shard, error) {
+	shards := make([]server.Shard, len(file.Shards))
+	for i, shard := range file.Shards {
+		conn, ok := conns[shard.Addr]
+		if !ok {
+			var err error
+			conn, err = grpc.Dial(shard.Addr, grpc.WithInsecure())
+			if err != nil {
+				return nil, err
+			}
+			conns[shard.Addr] = conn
+		}
+		shards[i] = server.Shard{Name: shard.Name, Client: rooms.NewGRPCClient(conn)}
+	}
+	return shards, nil
+}
+
+// Rebalances to the shards of the file and writes the months pinned back to it
+// The pins of the file are only read at startup
+func rebalanceShards(ctx context.Context, sharded *server.ShardedRooms, path string, conns map[string]*grpc.ClientConn) ([]string, error) {
+	file, err := readShardsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := dialShards(file, conns)
+	if err != nil {
+		return nil, err
+	}
+	moved, err := sharded.Rebalance(ctx, shards)
+	if err != nil {
+		return nil, err
+	}
+	file.Pins = sharded.Pins()
+	return moved, writeShardsFile(path, file)
+}
@@ -9,4 +9,30 @@ const (
 
 	JWTSecret     = "very_secret"
 	JWTExpiration = 10 * time.Minute
+
+	RoomsStore  = "memory"
+	RoomsDBPath = "rooms.db"
+	RoomsAdmin  = "Admin"
+
+	RoomsJournalPath  = ""
+	RoomsSnapshotPath = "rooms.snapshot"
+
+	RoomsAllocation = "first-fit"
+
+	RoomsMaxLeadDays   = 365
+	RoomsSameDayCutoff = 22
+
+	RoomsMaxActiveBookings = 0
+	RoomsMaxRoomsPerDate   = 0
+
+	RoomsRaftDir          = "raft"
+	RoomsRaftApplyTimeout = 10 * time.Second
+	RoomsLeaderWait       = 5 * time.Second
+
+	RoomsHoldTTL          = 15 * time.Minute
+	RoomsHoldReapInterval = 30 * time.Second
+
+	RoomsWebhookRetries = 5
+	RoomsWebhookBackoff = time.Second
+	RoomsWebhookTimeout = 10 * time.Second
 )
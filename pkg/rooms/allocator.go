@@ -0,0 +1,164 @@
+package rooms
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Allocator picks the rooms of a stay among the free ones.
+// Allocators are used concurrently by every booking
+type Allocator interface {
+	// Returns count rooms of the free set for the stay of a user,
+	// nil if there are not enough free rooms
+	// Rooms count as used when they are picked, even if the booking fails later
+	Allocate(stay Reservation, free RoomSet, count int) []int
+}
+
+// WithAllocator sets how the rooms of a booking are picked, first-fit if not configured
+func WithAllocator(allocator Allocator) Option {
+	return func(r *roomsService) {
+		r.allocator = allocator
+	}
+}
+
+// NewFirstFitAllocator returns an Allocator that picks the free rooms with the lowest ids
+func NewFirstFitAllocator() Allocator {
+	return firstFitAllocator{}
+}
+
+type firstFitAllocator struct{}
+
+func (firstFitAllocator) Allocate(stay Reservation, free RoomSet, count int) []int {
+	ids := free.Ids()
+	if len(ids) < count {
+		return nil
+	}
+	return ids[:count]
+}
+
+// NewLRUAllocator returns an Allocator that picks the free rooms
+// that were picked the longest time ago, rooms never picked first
+func NewLRUAllocator() Allocator {
+	return &lruAllocator{used: map[int]uint64{}}
+}
+
+type lruAllocator struct {
+	// when each room was last picked, by room id
+	used  map[int]uint64
+	clock uint64
+	mux   sync.Mutex
+}
+
+func (a *lruAllocator) Allocate(stay Reservation, free RoomSet, count int) []int {
+	ids := free.Ids()
+	if len(ids) < count {
+		return nil
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	// ids are sorted, so rooms picked at the same time keep their order
+	sort.SliceStable(ids, func(i, j int) bool {
+		return a.used[ids[i]] < a.used[ids[j]]
+	})
+	a.clock++
+	for _, id := range ids[:count] {
+		a.used[id] = a.clock
+	}
+	return ids[:count]
+}
+
+// NewRoundRobinAllocator returns an Allocator that picks the free rooms
+// following the last room picked, going back to the lowest id after the highest
+func NewRoundRobinAllocator() Allocator {
+	return &roundRobinAllocator{}
+}
+
+type roundRobinAllocator struct {
+	last int
+	mux  sync.Mutex
+}
+
+func (a *roundRobinAllocator) Allocate(stay Reservation, free RoomSet, count int) []int {
+	ids := free.Ids()
+	if len(ids) < count {
+		return nil
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	start := sort.SearchInts(ids, a.last+1)
+	rooms := make([]int, count)
+	for i := range rooms {
+		rooms[i] = ids[(start+i)%len(ids)]
+	}
+	a.last = rooms[count-1]
+	return rooms
+}
+
+// NewRandomAllocator returns an Allocator that picks free rooms at random
+// The same seed picks the same rooms for the same bookings
+func NewRandomAllocator(seed int64) Allocator {
+	return &randomAllocator{rand: rand.New(rand.NewSource(seed))}
+}
+
+type randomAllocator struct {
+	rand *rand.Rand
+	mux  sync.Mutex
+}
+
+func (a *randomAllocator) Allocate(stay Reservation, free RoomSet, count int) []int {
+	ids := free.Ids()
+	if len(ids) < count {
+		return nil
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	// partial Fisher-Yates shuffle of the first count rooms
+	for i := 0; i < count; i++ {
+		j := i + a.rand.Intn(len(ids)-i)
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	return ids[:count]
+}
+
+// NewSameRoomAllocator returns an Allocator that keeps a user in the rooms
+// of their stays ending on check-in or starting on check-out, so consecutive
+// nights are spent in the same room
+// The rest of the rooms are picked by the fallback
+func NewSameRoomAllocator(store BookingStore, fallback Allocator) Allocator {
+	return sameRoomAllocator{store, fallback}
+}
+
+type sameRoomAllocator struct {
+	store    BookingStore
+	fallback Allocator
+}
+
+func (a sameRoomAllocator) Allocate(stay Reservation, free RoomSet, count int) []int {
+	if free.Len() < count {
+		return nil
+	}
+	reservations, err := a.store.Reservations(stay.User)
+	if err != nil {
+		return a.fallback.Allocate(stay, free, count)
+	}
+
+	var rooms []int
+	kept := RoomSet{}
+	for _, reservation := range reservations {
+		if len(rooms) == count {
+			return rooms
+		}
+		adjacent := reservation.To.Equal(stay.From) || reservation.From.Equal(stay.To)
+		if adjacent && free.Has(reservation.Room) && !kept.Has(reservation.Room) {
+			rooms = append(rooms, reservation.Room)
+			kept = kept.Add(reservation.Room)
+		}
+	}
+	if len(rooms) == count {
+		return rooms
+	}
+	return append(rooms, a.fallback.Allocate(stay, free.AndNot(kept), count-len(rooms))...)
+}
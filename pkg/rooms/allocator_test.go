@@ -0,0 +1,146 @@
+package rooms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var allocatorTestStay = Reservation{
+	User: "John",
+	From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:   time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+}
+
+// Returns how many times each room is picked allocating one room of a set n times
+func allocations(allocator Allocator, free RoomSet, n int) map[int]int {
+	picked := map[int]int{}
+	for i := 0; i < n; i++ {
+		for _, room := range allocator.Allocate(allocatorTestStay, free, 1) {
+			picked[room]++
+		}
+	}
+	return picked
+}
+
+var allocatorTest = []struct {
+	name      string
+	allocator func() Allocator
+	free      RoomSet
+	count     int
+	want      [][]int
+}{
+	{
+		name:      "first-fit should always pick the lowest free rooms",
+		allocator: NewFirstFitAllocator,
+		free:      NewRoomSet(2, 3, 5),
+		count:     2,
+		want:      [][]int{{2, 3}, {2, 3}, {2, 3}},
+	},
+	{
+		name:      "lru should pick the rooms picked the longest time ago",
+		allocator: NewLRUAllocator,
+		free:      NewRoomSet(2, 3, 5),
+		count:     2,
+		want:      [][]int{{2, 3}, {5, 2}, {3, 2}, {5, 2}},
+	},
+	{
+		name:      "round-robin should pick the rooms following the last one",
+		allocator: NewRoundRobinAllocator,
+		free:      NewRoomSet(2, 3, 5),
+		count:     2,
+		want:      [][]int{{2, 3}, {5, 2}, {3, 5}, {2, 3}},
+	},
+	{
+		name:      "round-robin should pick no rooms if there are not enough free",
+		allocator: NewRoundRobinAllocator,
+		free:      NewRoomSet(2),
+		count:     2,
+		want:      [][]int{nil},
+	},
+}
+
+func TestAllocator(t *testing.T) {
+	t.Log("Allocator")
+
+	for _, testcase := range allocatorTest {
+		t.Logf(testcase.name)
+
+		allocator := testcase.allocator()
+		for _, want := range testcase.want {
+			assert.DeepEqual(t, allocator.Allocate(allocatorTestStay, testcase.free, testcase.count), want)
+		}
+	}
+}
+
+func TestAllocatorDistribution(t *testing.T) {
+	t.Log("AllocatorDistribution")
+	free := NewRoomSet(1, 2, 3, 4)
+
+	t.Logf("first-fit should wear out the lowest room")
+	assert.DeepEqual(t, allocations(NewFirstFitAllocator(), free, 400), map[int]int{1: 400})
+
+	t.Logf("lru should spread bookings evenly")
+	assert.DeepEqual(t, allocations(NewLRUAllocator(), free, 400), map[int]int{1: 100, 2: 100, 3: 100, 4: 100})
+
+	t.Logf("round-robin should spread bookings evenly")
+	assert.DeepEqual(t, allocations(NewRoundRobinAllocator(), free, 400), map[int]int{1: 100, 2: 100, 3: 100, 4: 100})
+
+	t.Logf("random should spread bookings roughly evenly")
+	picked := allocations(NewRandomAllocator(1), free, 4000)
+	assert.Equal(t, len(picked), 4)
+	for room, n := range picked {
+		assert.Assert(t, n > 900 && n < 1100, "room %d picked %d times", room, n)
+	}
+
+	t.Logf("random should pick distinct free rooms")
+	allocator := NewRandomAllocator(1)
+	for i := 0; i < 100; i++ {
+		rooms := allocator.Allocate(allocatorTestStay, NewRoomSet(1, 3, 5, 7), 3)
+		assert.Equal(t, len(rooms), 3)
+		assert.Equal(t, NewRoomSet(rooms...).Len(), 3)
+		assert.Equal(t, NewRoomSet(rooms...).CountAndNot(NewRoomSet(1, 3, 5, 7)), 0)
+	}
+}
+
+func TestSameRoomAllocator(t *testing.T) {
+	t.Log("SameRoomAllocator")
+	store := NewMemoryStore(testRooms(4))
+	store.Reserve(stay("1", "John", 3, allocatorTestStay.From.AddDate(0, 0, -2), 2))
+	store.Reserve(stay("2", "John", 4, allocatorTestStay.To, 1))
+	store.Reserve(stay("3", "Anna", 2, allocatorTestStay.From.AddDate(0, 0, -1), 1))
+	allocator := NewSameRoomAllocator(store, NewFirstFitAllocator())
+	free := NewRoomSet(1, 2, 3, 4)
+
+	t.Logf("should keep the user in the room of the night before")
+	assert.DeepEqual(t, allocator.Allocate(allocatorTestStay, free, 1), []int{3})
+
+	t.Logf("should keep the user in the rooms of adjacent stays before the fallback")
+	assert.DeepEqual(t, allocator.Allocate(allocatorTestStay, free, 3), []int{3, 4, 1})
+
+	t.Logf("should fall back if the rooms are not free")
+	assert.DeepEqual(t, allocator.Allocate(allocatorTestStay, NewRoomSet(1, 2), 1), []int{1})
+
+	t.Logf("should fall back for users without adjacent stays")
+	anna := allocatorTestStay
+	anna.User = "Anna"
+	anna.From, anna.To = anna.From.AddDate(0, 0, 1), anna.To.AddDate(0, 0, 1)
+	assert.DeepEqual(t, allocator.Allocate(anna, free, 1), []int{1})
+}
+
+func TestServiceBookAllocator(t *testing.T) {
+	t.Log("ServiceBookAllocator")
+	ctx := context.Background()
+	from := allocatorTestStay.From
+	rs := NewRoomsServer(NewMemoryStore(testRooms(3)), validatorCorrect{}, WithAllocator(NewRoundRobinAllocator()))
+
+	t.Logf("should book the rooms picked by the allocator")
+	for _, want := range []int{1, 2, 3, 1} {
+		reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+		assert.NilError(t, err)
+		assert.Equal(t, reservations[0].Room, want)
+		assert.NilError(t, rs.Cancel(ctx, "jjj.www.ttt", reservations[0].Id, 0))
+	}
+}
@@ -0,0 +1,118 @@
+package rooms
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Number of locks the dates of the index are spread over
+const indexShards = 64
+
+// availabilityIndex keeps the rooms of the store and the rooms booked on every date
+// as immutable snapshots, so they are read without locking.
+// Writers copy the snapshot of a date, change it and replace it holding the lock of the date's shard
+type availabilityIndex struct {
+	rooms  atomic.Value // roomsSnapshot
+	days   sync.Map     // time.Time -> *atomic.Value holding a daySnapshot
+	shards [indexShards]sync.Mutex
+}
+
+type roomsSnapshot struct {
+	set   RoomSet
+	count int
+}
+
+type daySnapshot struct {
+	booked RoomSet
+	count  int
+}
+
+func newAvailabilityIndex() *availabilityIndex {
+	i := &availabilityIndex{}
+	i.rooms.Store(roomsSnapshot{set: RoomSet{}})
+	return i
+}
+
+// Returns every room and how many there are
+func (i *availabilityIndex) allRooms() roomsSnapshot {
+	return i.rooms.Load().(roomsSnapshot)
+}
+
+// Returns the rooms booked on a date and how many there are
+func (i *availabilityIndex) day(date time.Time) daySnapshot {
+	if v, ok := i.days.Load(date); ok {
+		return v.(*atomic.Value).Load().(daySnapshot)
+	}
+	return daySnapshot{}
+}
+
+// Returns how many rooms of a set are free on a date, nil stands for every room
+// Without a set the count takes constant time, but it may be off by one room
+// while rooms are added or removed
+func (i *availabilityIndex) countFree(date time.Time, rooms RoomSet) int {
+	if rooms != nil {
+		return rooms.And(i.allRooms().set).CountAndNot(i.day(date).booked)
+	}
+	free := i.allRooms().count - i.day(date).count
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// Returns the rooms of a set free for every night from a date to another,
+// nil stands for every room
+func (i *availabilityIndex) freeRooms(from, to time.Time, rooms RoomSet) RoomSet {
+	var free RoomSet
+	if rooms != nil {
+		free = i.allRooms().set.And(rooms)
+	} else {
+		free = i.allRooms().set.Clone()
+	}
+	for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+		booked := i.day(date).booked
+		for w := 0; w < len(free) && w < len(booked); w++ {
+			free[w] &^= booked[w]
+		}
+	}
+	return free
+}
+
+// Changes whether a room is booked on a date
+func (i *availabilityIndex) set(date time.Time, room int, booked bool) {
+	mux := &i.shards[uint64(date.Unix()/(24*60*60))%indexShards]
+	mux.Lock()
+	defer mux.Unlock()
+	v, _ := i.days.LoadOrStore(date, &atomic.Value{})
+	value := v.(*atomic.Value)
+	day, _ := value.Load().(daySnapshot)
+	if day.booked.Has(room) == booked {
+		return
+	}
+	next := day.booked.Clone()
+	if booked {
+		next = next.Add(room)
+		day.count++
+	} else {
+		next.Remove(room)
+		day.count--
+	}
+	value.Store(daySnapshot{booked: next, count: day.count})
+}
+
+// Adds a room that is not booked on any date
+// Must be called holding the store write lock
+func (i *availabilityIndex) addRoom(room int) {
+	all := i.allRooms()
+	i.rooms.Store(roomsSnapshot{set: all.set.Clone().Add(room), count: all.count + 1})
+}
+
+// Removes a room that is not booked on any of the dates in the index
+// Must be called holding the store write lock
+func (i *availabilityIndex) removeRoom(room int) {
+	all := i.allRooms()
+	set := all.set.Clone()
+	set.Remove(room)
+	i.rooms.Store(roomsSnapshot{set: set, count: all.count - 1})
+}
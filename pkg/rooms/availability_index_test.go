@@ -0,0 +1,163 @@
+package rooms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestServiceConcurrentBookAndCheck(t *testing.T) {
+	t.Log("ServiceConcurrentBookAndCheck")
+	ctx := context.Background()
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	rs := NewRoomsServer(NewMemoryStore(testRooms(20)), validatorCorrect{})
+
+	var wg sync.WaitGroup
+	booked := make(chan Reservation, 40)
+	for i := 0; i < 40; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 1, date, date.AddDate(0, 0, 1), RoomFilter{}); err == nil {
+				booked <- reservations[0]
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			available, err := rs.Check(ctx, date, RoomFilter{})
+			assert.Check(t, err)
+			assert.Check(t, available >= 0 && available <= 20)
+		}()
+	}
+	wg.Wait()
+	close(booked)
+
+	t.Logf("should book every room once")
+	rooms := map[int]bool{}
+	for reservation := range booked {
+		assert.Assert(t, !rooms[reservation.Room])
+		rooms[reservation.Room] = true
+	}
+	assert.Equal(t, len(rooms), 20)
+	available, _ := rs.Check(ctx, date, RoomFilter{})
+	assert.Equal(t, available, 0)
+}
+
+// Returns a memory store with n rooms where every other room is booked
+// for the first week of June 2020
+func benchmarkStore(n int) BookingStore {
+	store := NewMemoryStore(testRooms(n))
+	date := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	for room := 1; room <= n; room += 2 {
+		store.Reserve(stay(fmt.Sprint(room), "John", room, date, 7))
+	}
+	return store
+}
+
+var benchmarkSizes = []int{10, 100, 1000, 5000}
+
+// Counts the free rooms through the index
+func BenchmarkCheckIndex(b *testing.B) {
+	date := time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC)
+	for _, n := range benchmarkSizes {
+		rs := NewRoomsServer(benchmarkStore(n), validatorCorrect{})
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rs.Check(context.Background(), date, RoomFilter{})
+			}
+		})
+	}
+}
+
+// Counts the free rooms scanning the bookings of every room, as Check used to
+func BenchmarkCheckScan(b *testing.B) {
+	date := time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC)
+	for _, n := range benchmarkSizes {
+		store := benchmarkStore(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rooms, _ := store.Rooms()
+				users, _ := store.Query(date)
+				var count int
+				for _, room := range rooms {
+					if users[room.Id] == "" {
+						count++
+					}
+				}
+			}
+		})
+	}
+}
+
+// Finds the rooms free for a stay through the index
+func BenchmarkFreeRoomsIndex(b *testing.B) {
+	from := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, n := range benchmarkSizes {
+		store := benchmarkStore(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				store.FreeRooms(from, from.AddDate(0, 0, 7), nil)
+			}
+		})
+	}
+}
+
+// Finds the rooms free for a stay querying every night, as Book used to
+func BenchmarkFreeRoomsScan(b *testing.B) {
+	from := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, n := range benchmarkSizes {
+		store := benchmarkStore(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rooms, _ := store.Rooms()
+				booked := map[int]bool{}
+				for date := from; date.Before(from.AddDate(0, 0, 7)); date = date.AddDate(0, 0, 1) {
+					users, _ := store.Query(date)
+					for id := range users {
+						booked[id] = true
+					}
+				}
+				var free []int
+				for _, room := range rooms {
+					if !booked[room.Id] {
+						free = append(free, room.Id)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Books and cancels a stay in a hotel where half the rooms are booked
+func BenchmarkBookCancel(b *testing.B) {
+	ctx := context.Background()
+	from := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, n := range benchmarkSizes {
+		rs := NewRoomsServer(benchmarkStore(n), validatorCorrect{})
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 1, from, from.AddDate(0, 0, 7), RoomFilter{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				rs.Cancel(ctx, "jjj.www.ttt", reservations[0].Id, 0)
+			}
+		})
+	}
+}
+
+// Checks availability from every core while bookings are made
+func BenchmarkCheckParallel(b *testing.B) {
+	ctx := context.Background()
+	date := time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC)
+	rs := NewRoomsServer(benchmarkStore(1000), validatorCorrect{})
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rs.Check(ctx, date, RoomFilter{})
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package rooms
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Blackout takes a room, or the whole property if room is 0, out of service
+// for every night from one date (included) to another (excluded)
+type Blackout struct {
+	Id     string    `json:"id"`
+	Room   int       `json:"room"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Reason string    `json:"reason"`
+}
+
+// Returns whether the blackout covers any night from check-in to check-out
+func (b Blackout) Overlaps(from, to time.Time) bool {
+	return b.From.Before(to) && from.Before(b.To)
+}
+
+// Returns whether the blackout covers a room
+func (b Blackout) Covers(room int) bool {
+	return b.Room == 0 || b.Room == room
+}
+
+// Orders blackouts by start and id
+func sortBlackouts(blackouts []Blackout) {
+	sort.Slice(blackouts, func(i, j int) bool {
+		if !blackouts[i].From.Equal(blackouts[j].From) {
+			return blackouts[i].From.Before(blackouts[j].From)
+		}
+		return blackouts[i].Id < blackouts[j].Id
+	})
+}
+
+// Takes a room or the whole property out of service (write/blocking)
+// Rooms are not bookable nor counted as available during a blackout,
+// existing bookings are kept
+// Returns the blackout with its id and the reservations it conflicts with,
+// which have to be cancelled or moved
+// Returns an error if the token does not belong to an administrator,
+// the range is invalid or the room does not exist
+func (r roomsService) AddBlackout(ctx context.Context, token string, blackout Blackout) (Blackout, []Reservation, error) {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return Blackout{}, nil, err
+	}
+	if _, err := nights(blackout.From, blackout.To); err != nil {
+		return Blackout{}, nil, err
+	}
+	if blackout.Room != 0 {
+		rooms, err := r.store.Rooms()
+		if err != nil {
+			return Blackout{}, nil, err
+		}
+		i := sort.Search(len(rooms), func(i int) bool { return rooms[i].Id >= blackout.Room })
+		if i == len(rooms) || rooms[i].Id != blackout.Room {
+			return Blackout{}, nil, ErrRoomNotFound()
+		}
+	}
+
+	blackout.Id = newReservationID()
+	if err := r.store.AddBlackout(blackout); err != nil {
+		return Blackout{}, nil, err
+	}
+
+	reservations, err := r.store.ReservationsBetween(blackout.From, blackout.To)
+	if err != nil {
+		return Blackout{}, nil, err
+	}
+	conflicts := []Reservation{}
+	for _, reservation := range reservations {
+		if blackout.Covers(reservation.Room) {
+			conflicts = append(conflicts, reservation)
+		}
+	}
+	return blackout, conflicts, nil
+}
+
+// Returns every blackout ordered by start (read/non-blocking)
+// Returns an error if the token does not belong to an administrator
+func (r roomsService) ListBlackouts(ctx context.Context, token string) ([]Blackout, error) {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return nil, err
+	}
+	return r.store.Blackouts()
+}
+
+// Puts the rooms of a blackout back in service (write/blocking)
+// Returns an error if the token does not belong to an administrator
+// or the blackout does not exist
+func (r roomsService) RemoveBlackout(ctx context.Context, token, id string) error {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return err
+	}
+	if err := r.store.RemoveBlackout(id); err != nil {
+		return err
+	}
+	r.promoteWaitlist()
+	return nil
+}
+
+// Returns the rooms blacked out on any night from check-in to check-out
+// and whether the whole property is
+func (r roomsService) blackedOut(from, to time.Time) (RoomSet, bool, error) {
+	blackouts, err := r.store.Blackouts()
+	if err != nil {
+		return nil, false, err
+	}
+	rooms, all := blockedRooms(blackouts, from, to)
+	return rooms, all, nil
+}
+
+// Returns the rooms of the blackouts covering any night from check-in to check-out
+// and whether one covers the whole property
+func blockedRooms(blackouts []Blackout, from, to time.Time) (RoomSet, bool) {
+	rooms := RoomSet{}
+	for _, blackout := range blackouts {
+		if !blackout.Overlaps(from, to) {
+			continue
+		}
+		if blackout.Room == 0 {
+			return nil, true
+		}
+		rooms = rooms.Add(blackout.Room)
+	}
+	return rooms, false
+}
+
+// freeCounter counts the rooms of a set (nil for every room) free and not blacked out
+// on the dates of a request, reading the blackouts and the rooms of the store once
+type freeCounter struct {
+	store      BookingStore
+	candidates RoomSet
+	blackouts  []Blackout
+}
+
+// Returns a counter for the dates from one date to another (excluded)
+// The rooms are only read if a blackout of a room falls in the range and no set is given
+func (r roomsService) freeCounter(from, to time.Time, candidates RoomSet) (freeCounter, error) {
+	blackouts, err := r.store.Blackouts()
+	if err != nil {
+		return freeCounter{}, err
+	}
+	counter := freeCounter{store: r.store, candidates: candidates}
+	for _, blackout := range blackouts {
+		if blackout.Overlaps(from, to) {
+			counter.blackouts = append(counter.blackouts, blackout)
+		}
+	}
+	if candidates != nil || len(counter.blackouts) == 0 {
+		return counter, nil
+	}
+	rooms, err := r.store.Rooms()
+	if err != nil {
+		return freeCounter{}, err
+	}
+	counter.candidates = RoomSet{}
+	for _, room := range rooms {
+		counter.candidates = counter.candidates.Add(room.Id)
+	}
+	return counter, nil
+}
+
+func (c freeCounter) count(date time.Time) (int, error) {
+	blocked, all := blockedRooms(c.blackouts, date, date.AddDate(0, 0, 1))
+	if all {
+		return 0, nil
+	}
+	if blocked.Len() == 0 {
+		return c.store.CountFree(date, c.candidates)
+	}
+	return c.store.CountFree(date, c.candidates.AndNot(blocked))
+}
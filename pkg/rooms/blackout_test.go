@@ -0,0 +1,195 @@
+package rooms
+
+import (
+	"context"
+	"go-booking-service/pb"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var testBlackout = Blackout{
+	Id:     "B4CK0T",
+	Room:   1,
+	From:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:     time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+	Reason: "painting",
+}
+
+var testPBBlackout = &pb.Blackout{
+	Id:     "B4CK0T",
+	Room:   1,
+	From:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(),
+	To:     time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC).Unix(),
+	Reason: "painting",
+}
+
+func TestBlackoutOverlaps(t *testing.T) {
+	t.Log("BlackoutOverlaps")
+	from, to := testBlackout.From, testBlackout.To
+
+	assert.Assert(t, testBlackout.Overlaps(from.AddDate(0, 0, -1), from.AddDate(0, 0, 1)))
+	assert.Assert(t, testBlackout.Overlaps(to.AddDate(0, 0, -1), to.AddDate(0, 0, 1)))
+	assert.Assert(t, !testBlackout.Overlaps(from.AddDate(0, 0, -1), from))
+	assert.Assert(t, !testBlackout.Overlaps(to, to.AddDate(0, 0, 1)))
+	assert.Assert(t, testBlackout.Covers(1))
+	assert.Assert(t, !testBlackout.Covers(2))
+	assert.Assert(t, Blackout{}.Covers(2))
+}
+
+var serviceAddBlackoutTest = []struct {
+	name     string
+	blackout Blackout
+	admins   []string
+	want     []Reservation
+	err      error
+}{
+	{
+		name:     "should report the reservations of the room during the blackout",
+		blackout: Blackout{Room: 1, From: testBlackout.From, To: testBlackout.To},
+		admins:   []string{"John"},
+		want:     []Reservation{stay("1", "John", 1, testBlackout.From, 1)},
+	},
+	{
+		name:     "should report every reservation during a property-wide blackout",
+		blackout: Blackout{From: testBlackout.From, To: testBlackout.To},
+		admins:   []string{"John"},
+		want:     []Reservation{stay("1", "John", 1, testBlackout.From, 1), stay("2", "Anna", 2, testBlackout.From, 3)},
+	},
+	{
+		name:     "should return an error if the room does not exist",
+		blackout: Blackout{Room: 7, From: testBlackout.From, To: testBlackout.To},
+		admins:   []string{"John"},
+		err:      ErrRoomNotFound(),
+	},
+	{
+		name:     "should return an error if the range is invalid",
+		blackout: Blackout{Room: 1, From: testBlackout.To, To: testBlackout.From},
+		admins:   []string{"John"},
+		err:      ErrInvalidDateRange(),
+	},
+	{
+		name:     "should return an error if the user is not an administrator",
+		blackout: Blackout{Room: 1, From: testBlackout.From, To: testBlackout.To},
+		err:      ErrNotAdmin(),
+	},
+}
+
+func TestServiceAddBlackout(t *testing.T) {
+	t.Log("ServiceAddBlackout")
+
+	for _, testcase := range serviceAddBlackoutTest {
+		t.Logf(testcase.name)
+
+		store := NewMemoryStore(testRooms(2))
+		store.Reserve(stay("1", "John", 1, testBlackout.From, 1))
+		store.Reserve(stay("2", "Anna", 2, testBlackout.From, 3))
+		rs := NewRoomsServer(store, validatorCorrect{}, WithAdmins(testcase.admins...))
+
+		blackout, conflicts, err := rs.AddBlackout(context.Background(), "jjj.www.ttt", testcase.blackout)
+		assert.DeepEqual(t, err, testcase.err)
+		if err != nil {
+			continue
+		}
+		assert.Assert(t, blackout.Id != "")
+		assert.DeepEqual(t, conflicts, testcase.want)
+		blackouts, _ := rs.ListBlackouts(context.Background(), "jjj.www.ttt")
+		assert.DeepEqual(t, blackouts, []Blackout{blackout})
+	}
+}
+
+func TestServiceBlackout(t *testing.T) {
+	t.Log("ServiceBlackout")
+	ctx := context.Background()
+	from, to := testBlackout.From, testBlackout.To
+	rs := NewRoomsServer(NewMemoryStore(testRooms(2)), validatorCorrect{}, WithAdmins("John"))
+
+	blackout, _, err := rs.AddBlackout(ctx, "jjj.www.ttt", Blackout{Room: 1, From: from, To: to})
+	assert.NilError(t, err)
+
+	t.Logf("should not count blacked out rooms as available")
+	free, err := rs.Check(ctx, from, RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, free, 1)
+	free, _ = rs.Check(ctx, to, RoomFilter{})
+	assert.Equal(t, free, 2)
+	days, _ := rs.Availability(ctx, from, to.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, []int{days[0].Available, days[1].Available, days[2].Available}, []int{1, 1, 2})
+
+	t.Logf("should not book blacked out rooms")
+	reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, reservations[0].Room, 2)
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", 1, from.AddDate(0, 0, -1), from.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrNoRoomAvailable())
+
+	t.Logf("should not move bookings into blacked out rooms")
+	_, err = rs.ModifyBooking(ctx, "jjj.www.ttt", reservations[0].Id, time.Time{}, time.Time{}, 1, 0)
+	assert.DeepEqual(t, err, ErrRoomNotAvailable())
+
+	t.Logf("should not book anything during a property-wide blackout")
+	all, _, err := rs.AddBlackout(ctx, "jjj.www.ttt", Blackout{From: to, To: to.AddDate(0, 0, 1)})
+	assert.NilError(t, err)
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", 1, to, to.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrNoRoomAvailable())
+	free, _ = rs.Check(ctx, to, RoomFilter{})
+	assert.Equal(t, free, 0)
+
+	t.Logf("should put the rooms back in service when the blackout is removed")
+	assert.NilError(t, rs.RemoveBlackout(ctx, "jjj.www.ttt", blackout.Id))
+	assert.NilError(t, rs.RemoveBlackout(ctx, "jjj.www.ttt", all.Id))
+	assert.DeepEqual(t, rs.RemoveBlackout(ctx, "jjj.www.ttt", all.Id), ErrBlackoutNotFound())
+	free, _ = rs.Check(ctx, from, RoomFilter{})
+	assert.Equal(t, free, 1)
+	_, err = rs.ModifyBooking(ctx, "jjj.www.ttt", reservations[0].Id, time.Time{}, time.Time{}, 1, 0)
+	assert.NilError(t, err)
+
+	t.Logf("should only let administrators manage blackouts")
+	other := NewRoomsServer(NewMemoryStore(testRooms(2)), validatorCorrect{})
+	_, err = other.ListBlackouts(ctx, "jjj.www.ttt")
+	assert.DeepEqual(t, err, ErrNotAdmin())
+	assert.DeepEqual(t, other.RemoveBlackout(ctx, "jjj.www.ttt", blackout.Id), ErrNotAdmin())
+}
+
+// blackoutReadsStore counts the reads of the blackouts and the rooms of the store
+type blackoutReadsStore struct {
+	BookingStore
+	blackouts *int32
+	rooms     *int32
+}
+
+func (s blackoutReadsStore) Blackouts() ([]Blackout, error) {
+	atomic.AddInt32(s.blackouts, 1)
+	return s.BookingStore.Blackouts()
+}
+
+func (s blackoutReadsStore) Rooms() ([]RoomInfo, error) {
+	atomic.AddInt32(s.rooms, 1)
+	return s.BookingStore.Rooms()
+}
+
+func TestServiceAvailabilityBlackoutReads(t *testing.T) {
+	t.Log("ServiceAvailabilityBlackoutReads")
+	ctx := context.Background()
+	from, to := testBlackout.From, testBlackout.To
+	store := blackoutReadsStore{BookingStore: NewMemoryStore(testRooms(2)), blackouts: new(int32), rooms: new(int32)}
+	assert.NilError(t, store.AddBlackout(Blackout{Id: "1", Room: 1, From: from, To: to}))
+	rs := NewRoomsServer(store, validatorCorrect{})
+
+	t.Logf("should read the blackouts and the rooms once per request")
+	days, err := rs.Availability(ctx, from.AddDate(0, 0, -5), to.AddDate(0, 0, 5), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(days), 13)
+	assert.Equal(t, days[5].Available, 1)
+	assert.Equal(t, days[7].Available, 2)
+	assert.Equal(t, atomic.LoadInt32(store.blackouts), int32(1))
+	assert.Equal(t, atomic.LoadInt32(store.rooms), int32(1))
+
+	t.Logf("should not read the rooms when no blackout falls in the range")
+	_, err = rs.Availability(ctx, to, to.AddDate(0, 0, 5), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, atomic.LoadInt32(store.blackouts), int32(2))
+	assert.Equal(t, atomic.LoadInt32(store.rooms), int32(1))
+}
@@ -0,0 +1,766 @@
+package rooms
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	roomsBucket        = []byte("rooms")
+	bookingsBucket     = []byte("bookings")
+	reservationsBucket = []byte("reservations")
+	codesBucket        = []byte("codes")
+	blackoutsBucket    = []byte("blackouts")
+	webhooksBucket     = []byte("webhooks")
+	deadLettersBucket  = []byte("dead_letters")
+	waitlistBucket     = []byte("waitlist")
+)
+
+// Rolls back a reservation transaction when a room is already booked
+var errRoomTaken = errors.New("room taken")
+
+// BoltStore is a BookingStore persisted to a bbolt database file.
+// Every write runs in its own transaction, which is synced to disk
+// before returning, so bookings survive a crash or restart
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the database at path seeding
+// the given rooms if it has no rooms yet, so rooms decommissioned
+// at runtime do not come back on restart
+func NewBoltStore(path string, rooms []RoomInfo) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		infos, err := tx.CreateBucketIfNotExists(roomsBucket)
+		if err != nil {
+			return err
+		}
+		bookings, err := tx.CreateBucketIfNotExists(bookingsBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(reservationsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(codesBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(blackoutsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(webhooksBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(deadLettersBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(waitlistBucket); err != nil {
+			return err
+		}
+		if k, _ := infos.Cursor().First(); k != nil {
+			return nil
+		}
+		for _, room := range rooms {
+			info, err := json.Marshal(room)
+			if err != nil {
+				return err
+			}
+			if err := infos.Put(roomKey(room.Id), info); err != nil {
+				return err
+			}
+			if _, err := bookings.CreateBucketIfNotExists(roomKey(room.Id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db}, nil
+}
+
+// Close releases the database file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Rooms() ([]RoomInfo, error) {
+	rooms := []RoomInfo{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).ForEach(func(_, v []byte) error {
+			var room RoomInfo
+			if err := json.Unmarshal(v, &room); err != nil {
+				return err
+			}
+			rooms = append(rooms, room)
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+func (s *BoltStore) AddRoom(room RoomInfo) (RoomInfo, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		infos := tx.Bucket(roomsBucket)
+		if room.Id == 0 {
+			room.Id = 1
+			if last, _ := infos.Cursor().Last(); last != nil {
+				room.Id = int(binary.BigEndian.Uint32(last)) + 1
+			}
+		}
+		if infos.Get(roomKey(room.Id)) != nil {
+			return ErrRoomExists()
+		}
+		info, err := json.Marshal(room)
+		if err != nil {
+			return err
+		}
+		if err := infos.Put(roomKey(room.Id), info); err != nil {
+			return err
+		}
+		_, err = tx.Bucket(bookingsBucket).CreateBucketIfNotExists(roomKey(room.Id))
+		return err
+	})
+	if err != nil {
+		return RoomInfo{}, err
+	}
+	return room, nil
+}
+
+func (s *BoltStore) UpdateRoom(room RoomInfo) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		infos := tx.Bucket(roomsBucket)
+		if infos.Get(roomKey(room.Id)) == nil {
+			return ErrRoomNotFound()
+		}
+		info, err := json.Marshal(room)
+		if err != nil {
+			return err
+		}
+		return infos.Put(roomKey(room.Id), info)
+	})
+}
+
+func (s *BoltStore) RemoveRoom(room int, from time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bookingsBucket).Bucket(roomKey(room))
+		if b == nil {
+			return ErrRoomNotFound()
+		}
+		if k, _ := b.Cursor().Seek(dateKey(from)); k != nil {
+			return ErrRoomHasBookings()
+		}
+		if err := tx.Bucket(bookingsBucket).DeleteBucket(roomKey(room)); err != nil {
+			return err
+		}
+		return tx.Bucket(roomsBucket).Delete(roomKey(room))
+	})
+}
+
+func (s *BoltStore) Reserve(reservations ...Reservation) (bool, error) {
+	var booked bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		stored := tx.Bucket(reservationsBucket)
+		codes := tx.Bucket(codesBucket)
+		for _, reservation := range reservations {
+			b := tx.Bucket(bookingsBucket).Bucket(roomKey(reservation.Room))
+			if b == nil {
+				return ErrRoomNotFound()
+			}
+			if stored.Get([]byte(reservation.Id)) != nil || codes.Get([]byte(reservation.Code)) != nil {
+				return ErrReservationExists()
+			}
+			for _, date := range reservation.Nights() {
+				// nights are booked as they are checked, so a reservation
+				// overlapping a previous one of the same request is not free either
+				if b.Get(dateKey(date)) != nil {
+					return errRoomTaken
+				}
+				if err := b.Put(dateKey(date), []byte(reservation.User)); err != nil {
+					return err
+				}
+			}
+			value, err := json.Marshal(reservation)
+			if err != nil {
+				return err
+			}
+			if err := stored.Put([]byte(reservation.Id), value); err != nil {
+				return err
+			}
+			if err := codes.Put([]byte(reservation.Code), []byte(reservation.Id)); err != nil {
+				return err
+			}
+		}
+		booked = true
+		return nil
+	})
+	// the transaction is rolled back when any room is taken
+	if err == errRoomTaken {
+		return false, nil
+	}
+	return booked, err
+}
+
+func (s *BoltStore) Release(id string, version int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		reservation, err := getReservation(tx, []byte(id))
+		if err != nil {
+			return err
+		}
+		if err := reservation.checkVersion(version); err != nil {
+			return err
+		}
+		return releaseReservation(tx, reservation)
+	})
+}
+
+func releaseReservation(tx *bolt.Tx, reservation Reservation) error {
+	if err := tx.Bucket(reservationsBucket).Delete([]byte(reservation.Id)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(codesBucket).Delete([]byte(reservation.Code)); err != nil {
+		return err
+	}
+
+	// the room may have been decommissioned since
+	b := tx.Bucket(bookingsBucket).Bucket(roomKey(reservation.Room))
+	if b == nil {
+		return nil
+	}
+	for _, date := range reservation.Nights() {
+		if string(b.Get(dateKey(date))) != reservation.User {
+			continue
+		}
+		if err := b.Delete(dateKey(date)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) Move(id string, version, room int, from, to time.Time) (Reservation, bool, error) {
+	var moved Reservation
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		reservation, err := getReservation(tx, []byte(id))
+		if err != nil {
+			return err
+		}
+		if err := reservation.checkVersion(version); err != nil {
+			return err
+		}
+		b := tx.Bucket(bookingsBucket).Bucket(roomKey(room))
+		if b == nil {
+			return ErrRoomNotFound()
+		}
+
+		// the old nights are freed first so the new ones may overlap them
+		if err := releaseReservation(tx, reservation); err != nil {
+			return err
+		}
+		moved = reservation
+		moved.Room, moved.From, moved.To = room, from, to
+		moved.Version++
+		for _, date := range moved.Nights() {
+			if b.Get(dateKey(date)) != nil {
+				return errRoomTaken
+			}
+			if err := b.Put(dateKey(date), []byte(moved.User)); err != nil {
+				return err
+			}
+		}
+		value, err := json.Marshal(moved)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(reservationsBucket).Put([]byte(moved.Id), value); err != nil {
+			return err
+		}
+		return tx.Bucket(codesBucket).Put([]byte(moved.Code), []byte(moved.Id))
+	})
+	// the transaction is rolled back when the room is taken
+	if err == errRoomTaken {
+		return Reservation{}, false, nil
+	}
+	if err != nil {
+		return Reservation{}, false, err
+	}
+	return moved, true, nil
+}
+
+func (s *BoltStore) Confirm(id string, version int, now time.Time) (Reservation, error) {
+	var reservation Reservation
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		reservation, err = getReservation(tx, []byte(id))
+		if err != nil {
+			return err
+		}
+		if err := reservation.checkVersion(version); err != nil {
+			return err
+		}
+		if err := confirm(&reservation, now); err != nil {
+			return err
+		}
+		value, err := json.Marshal(reservation)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(reservationsBucket).Put([]byte(id), value)
+	})
+	if err != nil {
+		return Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (s *BoltStore) ReleaseExpired(now time.Time) ([]Reservation, error) {
+	released := []Reservation{}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(reservationsBucket).ForEach(func(_, v []byte) error {
+			var reservation Reservation
+			if err := json.Unmarshal(v, &reservation); err != nil {
+				return err
+			}
+			if reservation.Expired(now) {
+				released = append(released, reservation)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// buckets can not be modified while iterating them
+		for _, reservation := range released {
+			if err := releaseReservation(tx, reservation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortReservations(released)
+	return released, nil
+}
+
+func (s *BoltStore) Reservation(id string) (Reservation, error) {
+	var reservation Reservation
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		reservation, err = getReservation(tx, []byte(id))
+		return err
+	})
+	return reservation, err
+}
+
+func (s *BoltStore) ReservationByCode(code string) (Reservation, error) {
+	var reservation Reservation
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		reservation, err = getReservation(tx, tx.Bucket(codesBucket).Get([]byte(code)))
+		return err
+	})
+	return reservation, err
+}
+
+func (s *BoltStore) Reservations(user string) ([]Reservation, error) {
+	reservations := []Reservation{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(reservationsBucket).ForEach(func(_, v []byte) error {
+			var reservation Reservation
+			if err := json.Unmarshal(v, &reservation); err != nil {
+				return err
+			}
+			if reservation.User == user {
+				reservations = append(reservations, reservation)
+			}
+			return nil
+		})
+	})
+	sortReservations(reservations)
+	return reservations, err
+}
+
+func (s *BoltStore) ReservationsBetween(from, to time.Time) ([]Reservation, error) {
+	reservations := []Reservation{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(reservationsBucket).ForEach(func(_, v []byte) error {
+			var reservation Reservation
+			if err := json.Unmarshal(v, &reservation); err != nil {
+				return err
+			}
+			if reservation.From.Before(to) && from.Before(reservation.To) {
+				reservations = append(reservations, reservation)
+			}
+			return nil
+		})
+	})
+	sortReservations(reservations)
+	return reservations, err
+}
+
+func (s *BoltStore) AddBlackout(blackout Blackout) error {
+	value, err := json.Marshal(blackout)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(blackoutsBucket).Put([]byte(blackout.Id), value)
+	})
+}
+
+func (s *BoltStore) RemoveBlackout(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(blackoutsBucket)
+		if b.Get([]byte(id)) == nil {
+			return ErrBlackoutNotFound()
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) Blackouts() ([]Blackout, error) {
+	blackouts := []Blackout{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(blackoutsBucket).ForEach(func(_, v []byte) error {
+			var blackout Blackout
+			if err := json.Unmarshal(v, &blackout); err != nil {
+				return err
+			}
+			blackouts = append(blackouts, blackout)
+			return nil
+		})
+	})
+	sortBlackouts(blackouts)
+	return blackouts, err
+}
+
+func (s *BoltStore) AddWebhook(webhook Webhook) error {
+	value, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(webhooksBucket).Put([]byte(webhook.Id), value)
+	})
+}
+
+func (s *BoltStore) RemoveWebhook(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(webhooksBucket)
+		if b.Get([]byte(id)) == nil {
+			return ErrWebhookNotFound()
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) Webhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		webhooks, err = getWebhooks(tx)
+		return err
+	})
+	return webhooks, err
+}
+
+func getWebhooks(tx *bolt.Tx) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := tx.Bucket(webhooksBucket).ForEach(func(_, v []byte) error {
+		var webhook Webhook
+		if err := json.Unmarshal(v, &webhook); err != nil {
+			return err
+		}
+		webhooks = append(webhooks, webhook)
+		return nil
+	})
+	sortWebhooks(webhooks)
+	return webhooks, err
+}
+
+// Dead letters are keyed by the sequence of the bucket so they are iterated oldest first
+// and the ones beyond the most kept are dropped from the start
+func (s *BoltStore) AddDeadLetter(deadLetter DeadLetter) error {
+	value, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deadLettersBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := b.Put(seqKey(seq), value); err != nil {
+			return err
+		}
+		if seq <= maxDeadLetters {
+			return nil
+		}
+		// A cursor skips the key after the one it deleted, so it goes back to the first one
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= seq-maxDeadLetters; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) DeadLetters() ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		deadLetters, err = getDeadLetters(tx)
+		return err
+	})
+	return deadLetters, err
+}
+
+func getDeadLetters(tx *bolt.Tx) ([]DeadLetter, error) {
+	deadLetters := []DeadLetter{}
+	err := tx.Bucket(deadLettersBucket).ForEach(func(_, v []byte) error {
+		var deadLetter DeadLetter
+		if err := json.Unmarshal(v, &deadLetter); err != nil {
+			return err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+		return nil
+	})
+	return deadLetters, err
+}
+
+// Waitlist entries are keyed by the sequence of the bucket so they are iterated
+// in the order they were first stored, and looked up by id by scanning the bucket
+func (s *BoltStore) PutWaitlistEntry(entry WaitlistEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(waitlistBucket)
+		key, err := waitlistKey(b, entry.Id)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			key = seqKey(seq)
+		}
+		return b.Put(key, value)
+	})
+}
+
+func (s *BoltStore) RemoveWaitlistEntry(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(waitlistBucket)
+		key, err := waitlistKey(b, id)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return ErrWaitlistEntryNotFound()
+		}
+		return b.Delete(key)
+	})
+}
+
+// Returns the key of the waitlist entry with an id, nil if there is none
+func waitlistKey(b *bolt.Bucket, id string) ([]byte, error) {
+	var key []byte
+	err := b.ForEach(func(k, v []byte) error {
+		var entry WaitlistEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		if entry.Id == id {
+			key = append([]byte{}, k...)
+		}
+		return nil
+	})
+	return key, err
+}
+
+func (s *BoltStore) Waitlist() ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entries, err = getWaitlist(tx)
+		return err
+	})
+	return entries, err
+}
+
+func getWaitlist(tx *bolt.Tx) ([]WaitlistEntry, error) {
+	entries := []WaitlistEntry{}
+	err := tx.Bucket(waitlistBucket).ForEach(func(_, v []byte) error {
+		var entry WaitlistEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func getReservation(tx *bolt.Tx, id []byte) (Reservation, error) {
+	var reservation Reservation
+	value := tx.Bucket(reservationsBucket).Get(id)
+	if value == nil {
+		return reservation, ErrBookingNotFound()
+	}
+	err := json.Unmarshal(value, &reservation)
+	return reservation, err
+}
+
+func (s *BoltStore) Query(date time.Time) (map[int]string, error) {
+	users := map[int]string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bookings := tx.Bucket(bookingsBucket)
+		return bookings.ForEach(func(k, _ []byte) error {
+			if user := bookings.Bucket(k).Get(dateKey(date)); user != nil {
+				users[int(binary.BigEndian.Uint32(k))] = string(user)
+			}
+			return nil
+		})
+	})
+	return users, err
+}
+
+func (s *BoltStore) Bookings(room int) (map[time.Time]string, error) {
+	bookings := map[time.Time]string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bookingsBucket).Bucket(roomKey(room))
+		if b == nil {
+			return ErrRoomNotFound()
+		}
+		return b.ForEach(func(k, v []byte) error {
+			bookings[keyDate(k)] = string(v)
+			return nil
+		})
+	})
+	return bookings, err
+}
+
+func (s *BoltStore) CountFree(date time.Time, rooms RoomSet) (int, error) {
+	free, err := s.FreeRooms(date, date.AddDate(0, 0, 1), rooms)
+	return free.Len(), err
+}
+
+// Scans the bookings of every room of the set
+func (s *BoltStore) FreeRooms(from, to time.Time, rooms RoomSet) (RoomSet, error) {
+	free := RoomSet{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bookings := tx.Bucket(bookingsBucket)
+		return bookings.ForEach(func(k, _ []byte) error {
+			room := int(binary.BigEndian.Uint32(k))
+			if rooms != nil && !rooms.Has(room) {
+				return nil
+			}
+			b := bookings.Bucket(k)
+			for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+				if b.Get(dateKey(date)) != nil {
+					return nil
+				}
+			}
+			free = free.Add(room)
+			return nil
+		})
+	})
+	return free, err
+}
+
+// Room ids are stored big endian so they are iterated in order
+func roomKey(room int) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, uint32(room))
+	return key
+}
+
+// Dates are stored as big endian unix seconds so they are iterated in order
+// Dates before 1970 are not supported
+func dateKey(date time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(date.Unix()))
+	return key
+}
+
+func keyDate(key []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(key)), 0).UTC()
+}
+
+// Sequences are stored big endian so they are iterated in order
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// The snapshot is read in a single transaction, which does not block writes
+func (s *BoltStore) Snapshot() (Snapshot, error) {
+	snapshot := Snapshot{Rooms: []RoomInfo{}, Reservations: []Reservation{}, Blackouts: []Blackout{}}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(roomsBucket).ForEach(func(_, v []byte) error {
+			var room RoomInfo
+			if err := json.Unmarshal(v, &room); err != nil {
+				return err
+			}
+			snapshot.Rooms = append(snapshot.Rooms, room)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(reservationsBucket).ForEach(func(_, v []byte) error {
+			var reservation Reservation
+			if err := json.Unmarshal(v, &reservation); err != nil {
+				return err
+			}
+			snapshot.Reservations = append(snapshot.Reservations, reservation)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(blackoutsBucket).ForEach(func(_, v []byte) error {
+			var blackout Blackout
+			if err := json.Unmarshal(v, &blackout); err != nil {
+				return err
+			}
+			snapshot.Blackouts = append(snapshot.Blackouts, blackout)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if snapshot.Webhooks, err = getWebhooks(tx); err != nil {
+			return err
+		}
+		if snapshot.DeadLetters, err = getDeadLetters(tx); err != nil {
+			return err
+		}
+		snapshot.Waitlist, err = getWaitlist(tx)
+		return err
+	})
+	sortReservations(snapshot.Reservations)
+	sortBlackouts(snapshot.Blackouts)
+	return snapshot, err
+}
@@ -8,25 +8,87 @@ import (
 )
 
 type Endpoints struct {
-	BookEndpoint  endpoint.Endpoint
-	CheckEndpoint endpoint.Endpoint
+	BookEndpoint          endpoint.Endpoint
+	CheckEndpoint         endpoint.Endpoint
+	CancelEndpoint        endpoint.Endpoint
+	AvailabilityEndpoint  endpoint.Endpoint
+	WatchEndpoint         endpoint.Endpoint
+	ListRoomsEndpoint     endpoint.Endpoint
+	CreateRoomEndpoint    endpoint.Endpoint
+	UpdateRoomEndpoint    endpoint.Endpoint
+	DecommissionEndpoint  endpoint.Endpoint
+	ListBookingsEndpoint  endpoint.Endpoint
+	ReservationEndpoint   endpoint.Endpoint
+	JoinWaitlistEndpoint  endpoint.Endpoint
+	WaitlistEndpoint      endpoint.Endpoint
+	HoldEndpoint          endpoint.Endpoint
+	ConfirmEndpoint       endpoint.Endpoint
+	ModifyEndpoint        endpoint.Endpoint
+	AddBlackoutEndpoint   endpoint.Endpoint
+	BlackoutsEndpoint     endpoint.Endpoint
+	LiftBlackoutEndpoint  endpoint.Endpoint
+	HistoryEndpoint       endpoint.Endpoint
+	SnapshotEndpoint      endpoint.Endpoint
+	AddWebhookEndpoint    endpoint.Endpoint
+	WebhooksEndpoint      endpoint.Endpoint
+	RemoveWebhookEndpoint endpoint.Endpoint
+	DeadLettersEndpoint   endpoint.Endpoint
 }
 
-func (e Endpoints) Book(ctx context.Context, token string, date time.Time) (int, error) {
-	resp, err := e.BookEndpoint(ctx, BookRequest{Token: token, Date: date})
+func (e Endpoints) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter RoomFilter) ([]Reservation, error) {
+	resp, err := e.BookEndpoint(ctx, &BookRequest{Token: token, IdempotencyKey: key, Count: count, From: from, To: to, Filter: filter})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	response, ok := resp.(*BookResponse)
 	if !ok {
-		return 0, ErrInvalidResponseStructure()
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Reservations, response.Err
+}
+
+func (e Endpoints) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (Reservation, error) {
+	resp, err := e.ModifyEndpoint(ctx, &ModifyBookingRequest{Token: token, Id: id, From: from, To: to, Room: room, Version: version})
+	if err != nil {
+		return Reservation{}, err
+	}
+	response, ok := resp.(*BookResponse)
+	if !ok {
+		return Reservation{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Reservation, response.Err
+}
+
+func (e Endpoints) Hold(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (Reservation, error) {
+	resp, err := e.HoldEndpoint(ctx, &BookRequest{Token: token, From: from, To: to, Filter: filter})
+	if err != nil {
+		return Reservation{}, err
+	}
+	response, ok := resp.(*BookResponse)
+	if !ok {
+		return Reservation{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Reservation, response.Err
+}
+
+func (e Endpoints) Confirm(ctx context.Context, token, id string, version int) (Reservation, error) {
+	resp, err := e.ConfirmEndpoint(ctx, &ConfirmRequest{Token: token, Id: id, Version: version})
+	if err != nil {
+		return Reservation{}, err
+	}
+	response, ok := resp.(*BookResponse)
+	if !ok {
+		return Reservation{}, ErrInvalidResponseStructure()
 	}
 
-	return response.Id, response.Err
+	return response.Reservation, response.Err
 }
 
-func (e Endpoints) Check(ctx context.Context, date time.Time) (int, error) {
-	resp, err := e.CheckEndpoint(ctx, CheckRequest{Date: date})
+func (e Endpoints) Check(ctx context.Context, date time.Time, filter RoomFilter) (int, error) {
+	resp, err := e.CheckEndpoint(ctx, &CheckRequest{Date: date, Filter: filter})
 	if err != nil {
 		return 0, err
 	}
@@ -38,10 +100,288 @@ func (e Endpoints) Check(ctx context.Context, date time.Time) (int, error) {
 	return response.Available, response.Err
 }
 
+func (e Endpoints) Cancel(ctx context.Context, token, id string, version int) error {
+	resp, err := e.CancelEndpoint(ctx, &CancelRequest{Token: token, Id: id, Version: version})
+	if err != nil {
+		return err
+	}
+	response, ok := resp.(*CancelResponse)
+	if !ok {
+		return ErrInvalidResponseStructure()
+	}
+
+	return response.Err
+}
+
+func (e Endpoints) Availability(ctx context.Context, from, to time.Time, filter RoomFilter) ([]DayAvailability, error) {
+	resp, err := e.AvailabilityEndpoint(ctx, &AvailabilityRequest{From: from, To: to, Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*AvailabilityResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Days, response.Err
+}
+
+func (e Endpoints) WatchAvailability(ctx context.Context, from, to time.Time, filter RoomFilter) (<-chan []DayAvailability, error) {
+	resp, err := e.WatchEndpoint(ctx, &AvailabilityRequest{From: from, To: to, Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*WatchAvailabilityResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Updates, response.Err
+}
+
+func (e Endpoints) ListRooms(ctx context.Context) ([]RoomInfo, error) {
+	resp, err := e.ListRoomsEndpoint(ctx, &ListRoomsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*ListRoomsResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Rooms, response.Err
+}
+
+func (e Endpoints) CreateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	return roomEndpoint(ctx, e.CreateRoomEndpoint, token, room)
+}
+
+func (e Endpoints) UpdateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	return roomEndpoint(ctx, e.UpdateRoomEndpoint, token, room)
+}
+
+func roomEndpoint(ctx context.Context, e endpoint.Endpoint, token string, room RoomInfo) (RoomInfo, error) {
+	resp, err := e(ctx, &RoomRequest{Token: token, Room: room})
+	if err != nil {
+		return RoomInfo{}, err
+	}
+	response, ok := resp.(*RoomResponse)
+	if !ok {
+		return RoomInfo{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Room, response.Err
+}
+
+func (e Endpoints) DecommissionRoom(ctx context.Context, token string, id int) error {
+	resp, err := e.DecommissionEndpoint(ctx, &DecommissionRequest{Token: token, Id: id})
+	if err != nil {
+		return err
+	}
+	response, ok := resp.(*DecommissionResponse)
+	if !ok {
+		return ErrInvalidResponseStructure()
+	}
+
+	return response.Err
+}
+
+func (e Endpoints) AddBlackout(ctx context.Context, token string, blackout Blackout) (Blackout, []Reservation, error) {
+	resp, err := e.AddBlackoutEndpoint(ctx, &AddBlackoutRequest{Token: token, Blackout: blackout})
+	if err != nil {
+		return Blackout{}, nil, err
+	}
+	response, ok := resp.(*AddBlackoutResponse)
+	if !ok {
+		return Blackout{}, nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Blackout, response.Conflicts, response.Err
+}
+
+func (e Endpoints) ListBlackouts(ctx context.Context, token string) ([]Blackout, error) {
+	resp, err := e.BlackoutsEndpoint(ctx, &ListBlackoutsRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*ListBlackoutsResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Blackouts, response.Err
+}
+
+func (e Endpoints) RemoveBlackout(ctx context.Context, token, id string) error {
+	resp, err := e.LiftBlackoutEndpoint(ctx, &RemoveBlackoutRequest{Token: token, Id: id})
+	if err != nil {
+		return err
+	}
+	response, ok := resp.(*RemoveBlackoutResponse)
+	if !ok {
+		return ErrInvalidResponseStructure()
+	}
+
+	return response.Err
+}
+
+func (e Endpoints) History(ctx context.Context, token, id string, room int) ([]Event, error) {
+	resp, err := e.HistoryEndpoint(ctx, &HistoryRequest{Token: token, Id: id, Room: room})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*HistoryResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Events, response.Err
+}
+
+func (e Endpoints) Snapshot(ctx context.Context, token string) (Snapshot, error) {
+	resp, err := e.SnapshotEndpoint(ctx, &SnapshotRequest{Token: token})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	response, ok := resp.(*SnapshotResponse)
+	if !ok {
+		return Snapshot{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Snapshot, response.Err
+}
+
+func (e Endpoints) AddWebhook(ctx context.Context, token string, webhook Webhook) (Webhook, error) {
+	resp, err := e.AddWebhookEndpoint(ctx, &AddWebhookRequest{Token: token, Webhook: webhook})
+	if err != nil {
+		return Webhook{}, err
+	}
+	response, ok := resp.(*AddWebhookResponse)
+	if !ok {
+		return Webhook{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Webhook, response.Err
+}
+
+func (e Endpoints) ListWebhooks(ctx context.Context, token string) ([]Webhook, error) {
+	resp, err := e.WebhooksEndpoint(ctx, &ListWebhooksRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*ListWebhooksResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Webhooks, response.Err
+}
+
+func (e Endpoints) RemoveWebhook(ctx context.Context, token, id string) error {
+	resp, err := e.RemoveWebhookEndpoint(ctx, &RemoveWebhookRequest{Token: token, Id: id})
+	if err != nil {
+		return err
+	}
+	response, ok := resp.(*RemoveWebhookResponse)
+	if !ok {
+		return ErrInvalidResponseStructure()
+	}
+
+	return response.Err
+}
+
+func (e Endpoints) DeadLetters(ctx context.Context, token string) ([]DeadLetter, error) {
+	resp, err := e.DeadLettersEndpoint(ctx, &DeadLettersRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*DeadLettersResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.DeadLetters, response.Err
+}
+
+func (e Endpoints) ListBookings(ctx context.Context, token string, from, to time.Time) ([]Booking, error) {
+	resp, err := e.ListBookingsEndpoint(ctx, &ListBookingsRequest{Token: token, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	response, ok := resp.(*ListBookingsResponse)
+	if !ok {
+		return nil, ErrInvalidResponseStructure()
+	}
+
+	return response.Bookings, response.Err
+}
+
+func (e Endpoints) GetReservation(ctx context.Context, token, id string) (Reservation, error) {
+	resp, err := e.ReservationEndpoint(ctx, &GetReservationRequest{Token: token, Id: id})
+	if err != nil {
+		return Reservation{}, err
+	}
+	response, ok := resp.(*GetReservationResponse)
+	if !ok {
+		return Reservation{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Reservation, response.Err
+}
+
+func (e Endpoints) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (WaitlistEntry, error) {
+	resp, err := e.JoinWaitlistEndpoint(ctx, &JoinWaitlistRequest{Token: token, From: from, To: to, Filter: filter})
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	response, ok := resp.(*WaitlistResponse)
+	if !ok {
+		return WaitlistEntry{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Entry, response.Err
+}
+
+func (e Endpoints) WaitlistPosition(ctx context.Context, token, id string) (WaitlistEntry, error) {
+	resp, err := e.WaitlistEndpoint(ctx, &WaitlistPositionRequest{Token: token, Id: id})
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	response, ok := resp.(*WaitlistResponse)
+	if !ok {
+		return WaitlistEntry{}, ErrInvalidResponseStructure()
+	}
+
+	return response.Entry, response.Err
+}
+
 func MakeEndpoints(p RoomsService) Endpoints {
 	return Endpoints{
-		BookEndpoint:  MakeBookEndpoint(p),
-		CheckEndpoint: MakeCheckEndpoint(p),
+		BookEndpoint:          MakeBookEndpoint(p),
+		CheckEndpoint:         MakeCheckEndpoint(p),
+		CancelEndpoint:        MakeCancelEndpoint(p),
+		AvailabilityEndpoint:  MakeAvailabilityEndpoint(p),
+		WatchEndpoint:         MakeWatchEndpoint(p),
+		ListRoomsEndpoint:     MakeListRoomsEndpoint(p),
+		CreateRoomEndpoint:    MakeCreateRoomEndpoint(p),
+		UpdateRoomEndpoint:    MakeUpdateRoomEndpoint(p),
+		DecommissionEndpoint:  MakeDecommissionEndpoint(p),
+		ListBookingsEndpoint:  MakeListBookingsEndpoint(p),
+		ReservationEndpoint:   MakeReservationEndpoint(p),
+		JoinWaitlistEndpoint:  MakeJoinWaitlistEndpoint(p),
+		WaitlistEndpoint:      MakeWaitlistEndpoint(p),
+		HoldEndpoint:          MakeHoldEndpoint(p),
+		ConfirmEndpoint:       MakeConfirmEndpoint(p),
+		ModifyEndpoint:        MakeModifyEndpoint(p),
+		AddBlackoutEndpoint:   MakeAddBlackoutEndpoint(p),
+		BlackoutsEndpoint:     MakeBlackoutsEndpoint(p),
+		LiftBlackoutEndpoint:  MakeLiftBlackoutEndpoint(p),
+		HistoryEndpoint:       MakeHistoryEndpoint(p),
+		SnapshotEndpoint:      MakeSnapshotEndpoint(p),
+		AddWebhookEndpoint:    MakeAddWebhookEndpoint(p),
+		WebhooksEndpoint:      MakeWebhooksEndpoint(p),
+		RemoveWebhookEndpoint: MakeRemoveWebhookEndpoint(p),
+		DeadLettersEndpoint:   MakeDeadLettersEndpoint(p),
 	}
 }
 
@@ -51,9 +391,11 @@ func MakeBookEndpoint(p RoomsService) endpoint.Endpoint {
 		if !ok {
 			return &BookResponse{}, ErrInvalidRequestStructure()
 		}
-		id, err := p.Book(ctx, req.Token, req.Date)
-
-		return &BookResponse{id, err}, nil
+		reservations, err := p.Book(ctx, req.Token, req.IdempotencyKey, req.Count, req.From, req.To, req.Filter)
+		if err != nil {
+			return &BookResponse{Err: err}, nil
+		}
+		return &BookResponse{Reservation: reservations[0], Reservations: reservations}, nil
 	}
 }
 
@@ -63,8 +405,284 @@ func MakeCheckEndpoint(p RoomsService) endpoint.Endpoint {
 		if !ok {
 			return &CheckResponse{}, ErrInvalidRequestStructure()
 		}
-		available, err := p.Check(ctx, req.Date)
+		available, err := p.Check(ctx, req.Date, req.Filter)
 
 		return &CheckResponse{available, err}, nil
 	}
 }
+
+func MakeCancelEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*CancelRequest)
+		if !ok {
+			return &CancelResponse{}, ErrInvalidRequestStructure()
+		}
+		err := p.Cancel(ctx, req.Token, req.Id, req.Version)
+
+		return &CancelResponse{err}, nil
+	}
+}
+
+func MakeWatchEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*AvailabilityRequest)
+		if !ok {
+			return &WatchAvailabilityResponse{}, ErrInvalidRequestStructure()
+		}
+		updates, err := p.WatchAvailability(ctx, req.From, req.To, req.Filter)
+
+		return &WatchAvailabilityResponse{updates, err}, nil
+	}
+}
+
+func MakeAvailabilityEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*AvailabilityRequest)
+		if !ok {
+			return &AvailabilityResponse{}, ErrInvalidRequestStructure()
+		}
+		days, err := p.Availability(ctx, req.From, req.To, req.Filter)
+
+		return &AvailabilityResponse{days, err}, nil
+	}
+}
+
+func MakeListRoomsEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		_, ok := request.(*ListRoomsRequest)
+		if !ok {
+			return &ListRoomsResponse{}, ErrInvalidRequestStructure()
+		}
+		rooms, err := p.ListRooms(ctx)
+
+		return &ListRoomsResponse{rooms, err}, nil
+	}
+}
+
+func MakeCreateRoomEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*RoomRequest)
+		if !ok {
+			return &RoomResponse{}, ErrInvalidRequestStructure()
+		}
+		room, err := p.CreateRoom(ctx, req.Token, req.Room)
+
+		return &RoomResponse{room, err}, nil
+	}
+}
+
+func MakeUpdateRoomEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*RoomRequest)
+		if !ok {
+			return &RoomResponse{}, ErrInvalidRequestStructure()
+		}
+		room, err := p.UpdateRoom(ctx, req.Token, req.Room)
+
+		return &RoomResponse{room, err}, nil
+	}
+}
+
+func MakeDecommissionEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*DecommissionRequest)
+		if !ok {
+			return &DecommissionResponse{}, ErrInvalidRequestStructure()
+		}
+		err := p.DecommissionRoom(ctx, req.Token, req.Id)
+
+		return &DecommissionResponse{err}, nil
+	}
+}
+
+func MakeListBookingsEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ListBookingsRequest)
+		if !ok {
+			return &ListBookingsResponse{}, ErrInvalidRequestStructure()
+		}
+		bookings, err := p.ListBookings(ctx, req.Token, req.From, req.To)
+
+		return &ListBookingsResponse{bookings, err}, nil
+	}
+}
+
+func MakeReservationEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*GetReservationRequest)
+		if !ok {
+			return &GetReservationResponse{}, ErrInvalidRequestStructure()
+		}
+		reservation, err := p.GetReservation(ctx, req.Token, req.Id)
+
+		return &GetReservationResponse{reservation, err}, nil
+	}
+}
+
+func MakeJoinWaitlistEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*JoinWaitlistRequest)
+		if !ok {
+			return &WaitlistResponse{}, ErrInvalidRequestStructure()
+		}
+		entry, err := p.JoinWaitlist(ctx, req.Token, req.From, req.To, req.Filter)
+
+		return &WaitlistResponse{entry, err}, nil
+	}
+}
+
+func MakeWaitlistEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*WaitlistPositionRequest)
+		if !ok {
+			return &WaitlistResponse{}, ErrInvalidRequestStructure()
+		}
+		entry, err := p.WaitlistPosition(ctx, req.Token, req.Id)
+
+		return &WaitlistResponse{entry, err}, nil
+	}
+}
+
+func MakeHoldEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*BookRequest)
+		if !ok {
+			return &BookResponse{}, ErrInvalidRequestStructure()
+		}
+		reservation, err := p.Hold(ctx, req.Token, req.From, req.To, req.Filter)
+
+		return &BookResponse{Reservation: reservation, Err: err}, nil
+	}
+}
+
+func MakeConfirmEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ConfirmRequest)
+		if !ok {
+			return &BookResponse{}, ErrInvalidRequestStructure()
+		}
+		reservation, err := p.Confirm(ctx, req.Token, req.Id, req.Version)
+
+		return &BookResponse{Reservation: reservation, Err: err}, nil
+	}
+}
+
+func MakeModifyEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ModifyBookingRequest)
+		if !ok {
+			return &BookResponse{}, ErrInvalidRequestStructure()
+		}
+		reservation, err := p.ModifyBooking(ctx, req.Token, req.Id, req.From, req.To, req.Room, req.Version)
+
+		return &BookResponse{Reservation: reservation, Err: err}, nil
+	}
+}
+
+func MakeAddBlackoutEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*AddBlackoutRequest)
+		if !ok {
+			return &AddBlackoutResponse{}, ErrInvalidRequestStructure()
+		}
+		blackout, conflicts, err := p.AddBlackout(ctx, req.Token, req.Blackout)
+
+		return &AddBlackoutResponse{blackout, conflicts, err}, nil
+	}
+}
+
+func MakeBlackoutsEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ListBlackoutsRequest)
+		if !ok {
+			return &ListBlackoutsResponse{}, ErrInvalidRequestStructure()
+		}
+		blackouts, err := p.ListBlackouts(ctx, req.Token)
+
+		return &ListBlackoutsResponse{blackouts, err}, nil
+	}
+}
+
+func MakeLiftBlackoutEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*RemoveBlackoutRequest)
+		if !ok {
+			return &RemoveBlackoutResponse{}, ErrInvalidRequestStructure()
+		}
+		err := p.RemoveBlackout(ctx, req.Token, req.Id)
+
+		return &RemoveBlackoutResponse{err}, nil
+	}
+}
+
+func MakeHistoryEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*HistoryRequest)
+		if !ok {
+			return &HistoryResponse{}, ErrInvalidRequestStructure()
+		}
+		events, err := p.History(ctx, req.Token, req.Id, req.Room)
+
+		return &HistoryResponse{events, err}, nil
+	}
+}
+
+func MakeSnapshotEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*SnapshotRequest)
+		if !ok {
+			return &SnapshotResponse{}, ErrInvalidRequestStructure()
+		}
+		snapshot, err := p.Snapshot(ctx, req.Token)
+
+		return &SnapshotResponse{snapshot, err}, nil
+	}
+}
+
+func MakeAddWebhookEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*AddWebhookRequest)
+		if !ok {
+			return &AddWebhookResponse{}, ErrInvalidRequestStructure()
+		}
+		webhook, err := p.AddWebhook(ctx, req.Token, req.Webhook)
+
+		return &AddWebhookResponse{webhook, err}, nil
+	}
+}
+
+func MakeWebhooksEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*ListWebhooksRequest)
+		if !ok {
+			return &ListWebhooksResponse{}, ErrInvalidRequestStructure()
+		}
+		webhooks, err := p.ListWebhooks(ctx, req.Token)
+
+		return &ListWebhooksResponse{webhooks, err}, nil
+	}
+}
+
+func MakeRemoveWebhookEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*RemoveWebhookRequest)
+		if !ok {
+			return &RemoveWebhookResponse{}, ErrInvalidRequestStructure()
+		}
+		err := p.RemoveWebhook(ctx, req.Token, req.Id)
+
+		return &RemoveWebhookResponse{err}, nil
+	}
+}
+
+func MakeDeadLettersEndpoint(p RoomsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(*DeadLettersRequest)
+		if !ok {
+			return &DeadLettersResponse{}, ErrInvalidRequestStructure()
+		}
+		deadLetters, err := p.DeadLetters(ctx, req.Token)
+
+		return &DeadLettersResponse{deadLetters, err}, nil
+	}
+}
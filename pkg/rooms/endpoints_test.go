@@ -12,24 +12,27 @@ import (
 var endpointBookTest = []struct {
 	name         string
 	token        string
-	date         time.Time
+	from         time.Time
+	to           time.Time
 	bookEndpoint endpoint.Endpoint
-	want         int
+	want         []Reservation
 	err          error
 }{
 	{
-		name:  "should return booked room id",
+		name:  "should return the reservation",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		bookEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
-			return &BookResponse{5, nil}, nil
+			return &BookResponse{testReservation, []Reservation{testReservation}, nil}, nil
 		},
-		want: 5,
+		want: []Reservation{testReservation},
 	},
 	{
 		name:  "should return an error if the endpoint returns an error",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		bookEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
 			return &BookResponse{}, ErrNoRoomAvailable()
 		},
@@ -38,7 +41,8 @@ var endpointBookTest = []struct {
 	{
 		name:  "should return an error if response structure is incorrect",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		bookEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
 			return 5, nil
 		},
@@ -55,9 +59,9 @@ func TestEndpointBook(t *testing.T) {
 		endpointMock := Endpoints{
 			BookEndpoint: testcase.bookEndpoint,
 		}
-		result, err := endpointMock.Book(context.Background(), testcase.token, testcase.date)
+		result, err := endpointMock.Book(context.Background(), testcase.token, "", 0, testcase.from, testcase.to, RoomFilter{})
 
-		assert.Equal(t, result, testcase.want)
+		assert.DeepEqual(t, result, testcase.want)
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
@@ -104,33 +108,329 @@ func TestEndpointCheck(t *testing.T) {
 		endpointMock := Endpoints{
 			CheckEndpoint: testcase.checkEndpoint,
 		}
-		result, err := endpointMock.Check(context.Background(), testcase.date)
+		result, err := endpointMock.Check(context.Background(), testcase.date, RoomFilter{})
 
 		assert.Equal(t, result, testcase.want)
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
 
+var endpointCancelTest = []struct {
+	name           string
+	token          string
+	id             string
+	cancelEndpoint endpoint.Endpoint
+	err            error
+}{
+	{
+		name:  "should return no error if the booking was cancelled",
+		token: "jjj.www.ttt",
+		id:    "1-2020-06-13",
+		cancelEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &CancelResponse{nil}, nil
+		},
+	},
+	{
+		name:  "should return an error if the endpoint returns an error",
+		token: "jjj.www.ttt",
+		id:    "1-2020-06-13",
+		cancelEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &CancelResponse{}, ErrBookingNotFound()
+		},
+		err: ErrBookingNotFound(),
+	},
+	{
+		name:  "should return an error if response structure is incorrect",
+		token: "jjj.www.ttt",
+		id:    "1-2020-06-13",
+		cancelEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 5, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEndpointCancel(t *testing.T) {
+	t.Log("EndpointCancel")
+
+	for _, testcase := range endpointCancelTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			CancelEndpoint: testcase.cancelEndpoint,
+		}
+		err := endpointMock.Cancel(context.Background(), testcase.token, testcase.id, 0)
+
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointAvailabilityTest = []struct {
+	name                 string
+	from                 time.Time
+	to                   time.Time
+	availabilityEndpoint endpoint.Endpoint
+	want                 []DayAvailability
+	err                  error
+}{
+	{
+		name: "should return the number of available rooms for every day",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		availabilityEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &AvailabilityResponse{[]DayAvailability{
+				{time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 5},
+				{time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC), 4},
+			}, nil}, nil
+		},
+		want: []DayAvailability{
+			{time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 5},
+			{time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC), 4},
+		},
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		from: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		availabilityEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &AvailabilityResponse{}, ErrInvalidDateRange()
+		},
+		err: ErrInvalidDateRange(),
+	},
+	{
+		name: "should return an error if response structure is incorrect",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		availabilityEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 5, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEndpointAvailability(t *testing.T) {
+	t.Log("EndpointAvailability")
+
+	for _, testcase := range endpointAvailabilityTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			AvailabilityEndpoint: testcase.availabilityEndpoint,
+		}
+		result, err := endpointMock.Availability(context.Background(), testcase.from, testcase.to, RoomFilter{})
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
 type mockCorrectClientsService struct{}
 
-func (m mockCorrectClientsService) Book(ctx context.Context, token string, daet time.Time) (int, error) {
-	return 1, nil
+func (m mockCorrectClientsService) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter RoomFilter) ([]Reservation, error) {
+	return []Reservation{testReservation}, nil
 }
 
-func (m mockCorrectClientsService) Check(ctx context.Context, date time.Time) (int, error) {
+func (m mockCorrectClientsService) Check(ctx context.Context, date time.Time, filter RoomFilter) (int, error) {
 	return 5, nil
 }
 
+func (m mockCorrectClientsService) Cancel(ctx context.Context, token, id string, version int) error {
+	return nil
+}
+
+func (m mockCorrectClientsService) Availability(ctx context.Context, from, to time.Time, filter RoomFilter) ([]DayAvailability, error) {
+	return []DayAvailability{{from, 5}}, nil
+}
+
+func (m mockCorrectClientsService) WatchAvailability(ctx context.Context, from, to time.Time, filter RoomFilter) (<-chan []DayAvailability, error) {
+	updates := make(chan []DayAvailability, 1)
+	updates <- []DayAvailability{{from, 5}}
+	close(updates)
+	return updates, nil
+}
+
+func (m mockCorrectClientsService) ListRooms(ctx context.Context) ([]RoomInfo, error) {
+	return []RoomInfo{{Id: 1, Name: "101"}}, nil
+}
+
+func (m mockCorrectClientsService) CreateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	return room, nil
+}
+
+func (m mockCorrectClientsService) UpdateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	return room, nil
+}
+
+func (m mockCorrectClientsService) DecommissionRoom(ctx context.Context, token string, id int) error {
+	return nil
+}
+
+func (m mockCorrectClientsService) ListBookings(ctx context.Context, token string, from, to time.Time) ([]Booking, error) {
+	return []Booking{{testReservation.Id, testReservation.Code, 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)}}, nil
+}
+
+func (m mockCorrectClientsService) GetReservation(ctx context.Context, token, id string) (Reservation, error) {
+	return testReservation, nil
+}
+
+func (m mockCorrectClientsService) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (Reservation, error) {
+	return testReservation, nil
+}
+
+func (m mockCorrectClientsService) AddBlackout(ctx context.Context, token string, blackout Blackout) (Blackout, []Reservation, error) {
+	return testBlackout, []Reservation{testReservation}, nil
+}
+
+func (m mockCorrectClientsService) ListBlackouts(ctx context.Context, token string) ([]Blackout, error) {
+	return []Blackout{testBlackout}, nil
+}
+
+func (m mockCorrectClientsService) RemoveBlackout(ctx context.Context, token, id string) error {
+	return nil
+}
+
+func (m mockCorrectClientsService) History(ctx context.Context, token, id string, room int) ([]Event, error) {
+	return []Event{testEvent}, nil
+}
+
+func (m mockCorrectClientsService) Snapshot(ctx context.Context, token string) (Snapshot, error) {
+	return testSnapshot, nil
+}
+
+func (m mockCorrectClientsService) AddWebhook(ctx context.Context, token string, webhook Webhook) (Webhook, error) {
+	return testWebhook, nil
+}
+
+func (m mockCorrectClientsService) ListWebhooks(ctx context.Context, token string) ([]Webhook, error) {
+	return []Webhook{testWebhook}, nil
+}
+
+func (m mockCorrectClientsService) RemoveWebhook(ctx context.Context, token, id string) error {
+	return nil
+}
+
+func (m mockCorrectClientsService) DeadLetters(ctx context.Context, token string) ([]DeadLetter, error) {
+	return []DeadLetter{testDeadLetter}, nil
+}
+
+func (m mockCorrectClientsService) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (WaitlistEntry, error) {
+	return testWaitlistEntry, nil
+}
+
+func (m mockCorrectClientsService) WaitlistPosition(ctx context.Context, token, id string) (WaitlistEntry, error) {
+	return testWaitlistEntry, nil
+}
+
+func (m mockCorrectClientsService) Hold(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (Reservation, error) {
+	return testHold, nil
+}
+
+func (m mockCorrectClientsService) Confirm(ctx context.Context, token, id string, version int) (Reservation, error) {
+	return testReservation, nil
+}
+
 type mockErrorClientsService struct{}
 
-func (m mockErrorClientsService) Book(ctx context.Context, token string, daet time.Time) (int, error) {
-	return 0, ErrNoRoomAvailable()
+func (m mockErrorClientsService) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter RoomFilter) ([]Reservation, error) {
+	return nil, ErrNoRoomAvailable()
 }
 
-func (m mockErrorClientsService) Check(ctx context.Context, date time.Time) (int, error) {
+func (m mockErrorClientsService) Check(ctx context.Context, date time.Time, filter RoomFilter) (int, error) {
 	return 0, ErrNoRoomAvailable()
 }
 
+func (m mockErrorClientsService) Cancel(ctx context.Context, token, id string, version int) error {
+	return ErrBookingNotFound()
+}
+
+func (m mockErrorClientsService) Availability(ctx context.Context, from, to time.Time, filter RoomFilter) ([]DayAvailability, error) {
+	return nil, ErrInvalidDateRange()
+}
+
+func (m mockErrorClientsService) WatchAvailability(ctx context.Context, from, to time.Time, filter RoomFilter) (<-chan []DayAvailability, error) {
+	return nil, ErrInvalidDateRange()
+}
+
+func (m mockErrorClientsService) ListRooms(ctx context.Context) ([]RoomInfo, error) {
+	return nil, ErrNotAdmin()
+}
+
+func (m mockErrorClientsService) CreateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	return RoomInfo{}, ErrRoomExists()
+}
+
+func (m mockErrorClientsService) UpdateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	return RoomInfo{}, ErrRoomNotFound()
+}
+
+func (m mockErrorClientsService) DecommissionRoom(ctx context.Context, token string, id int) error {
+	return ErrRoomHasBookings()
+}
+
+func (m mockErrorClientsService) ListBookings(ctx context.Context, token string, from, to time.Time) ([]Booking, error) {
+	return nil, ErrInvalidDateRange()
+}
+
+func (m mockErrorClientsService) GetReservation(ctx context.Context, token, id string) (Reservation, error) {
+	return Reservation{}, ErrNotBookingOwner()
+}
+
+func (m mockErrorClientsService) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (Reservation, error) {
+	return Reservation{}, ErrRoomNotAvailable()
+}
+
+func (m mockErrorClientsService) AddBlackout(ctx context.Context, token string, blackout Blackout) (Blackout, []Reservation, error) {
+	return Blackout{}, nil, ErrRoomNotFound()
+}
+
+func (m mockErrorClientsService) ListBlackouts(ctx context.Context, token string) ([]Blackout, error) {
+	return nil, ErrNotAdmin()
+}
+
+func (m mockErrorClientsService) RemoveBlackout(ctx context.Context, token, id string) error {
+	return ErrBlackoutNotFound()
+}
+
+func (m mockErrorClientsService) History(ctx context.Context, token, id string, room int) ([]Event, error) {
+	return nil, ErrHistoryNotRecorded()
+}
+
+func (m mockErrorClientsService) Snapshot(ctx context.Context, token string) (Snapshot, error) {
+	return Snapshot{}, ErrNotAdmin()
+}
+
+func (m mockErrorClientsService) AddWebhook(ctx context.Context, token string, webhook Webhook) (Webhook, error) {
+	return Webhook{}, ErrInvalidWebhook()
+}
+
+func (m mockErrorClientsService) ListWebhooks(ctx context.Context, token string) ([]Webhook, error) {
+	return nil, ErrNotAdmin()
+}
+
+func (m mockErrorClientsService) RemoveWebhook(ctx context.Context, token, id string) error {
+	return ErrWebhookNotFound()
+}
+
+func (m mockErrorClientsService) DeadLetters(ctx context.Context, token string) ([]DeadLetter, error) {
+	return nil, ErrWebhooksNotEnabled()
+}
+
+func (m mockErrorClientsService) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (WaitlistEntry, error) {
+	return WaitlistEntry{}, ErrInvalidDateRange()
+}
+
+func (m mockErrorClientsService) Hold(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (Reservation, error) {
+	return Reservation{}, ErrNoRoomAvailable()
+}
+
+func (m mockErrorClientsService) Confirm(ctx context.Context, token, id string, version int) (Reservation, error) {
+	return Reservation{}, ErrHoldExpired()
+}
+
+func (m mockErrorClientsService) WaitlistPosition(ctx context.Context, token, id string) (WaitlistEntry, error) {
+	return WaitlistEntry{}, ErrWaitlistEntryNotFound()
+}
+
 var makeBookEndpointTest = []struct {
 	name    string
 	client  RoomsService
@@ -139,10 +439,10 @@ var makeBookEndpointTest = []struct {
 	err     error
 }{
 	{
-		name:    "should return the booked room id",
+		name:    "should return the reservation",
 		client:  mockCorrectClientsService{},
 		request: &BookRequest{},
-		want:    &BookResponse{1, nil},
+		want:    &BookResponse{testReservation, []Reservation{testReservation}, nil},
 	},
 	{
 		name:    "should return an error if the request has the wrong structure",
@@ -155,7 +455,7 @@ var makeBookEndpointTest = []struct {
 		name:    "should return an error if the endpoint returns an error",
 		client:  mockErrorClientsService{},
 		request: &BookRequest{},
-		want:    &BookResponse{0, ErrNoRoomAvailable()},
+		want:    &BookResponse{Err: ErrNoRoomAvailable()},
 	},
 }
 
@@ -214,3 +514,851 @@ func TestMakeCheckEndpoint(t *testing.T) {
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
+
+var makeCancelEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *CancelResponse
+	err     error
+}{
+	{
+		name:    "should return an empty response if the booking was cancelled",
+		client:  mockCorrectClientsService{},
+		request: &CancelRequest{},
+		want:    &CancelResponse{nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &CancelResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &CancelRequest{},
+		want:    &CancelResponse{ErrBookingNotFound()},
+	},
+}
+
+func TestMakeCancelEndpoint(t *testing.T) {
+	t.Log("MakeCancelEndpoint")
+
+	for _, testcase := range makeCancelEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeCancelEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeAvailabilityEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *AvailabilityResponse
+	err     error
+}{
+	{
+		name:    "should return the availability of every day",
+		client:  mockCorrectClientsService{},
+		request: &AvailabilityRequest{From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)},
+		want:    &AvailabilityResponse{[]DayAvailability{{time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 5}}, nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "2020-06-13",
+		want:    &AvailabilityResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &AvailabilityRequest{},
+		want:    &AvailabilityResponse{nil, ErrInvalidDateRange()},
+	},
+}
+
+func TestMakeAvailabilityEndpoint(t *testing.T) {
+	t.Log("MakeAvailabilityEndpoint")
+
+	for _, testcase := range makeAvailabilityEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeAvailabilityEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeCreateRoomEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *RoomResponse
+	err     error
+}{
+	{
+		name:    "should return the created room",
+		client:  mockCorrectClientsService{},
+		request: &RoomRequest{Room: RoomInfo{Id: 3, Name: "301"}},
+		want:    &RoomResponse{RoomInfo{Id: 3, Name: "301"}, nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "301",
+		want:    &RoomResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &RoomRequest{},
+		want:    &RoomResponse{RoomInfo{}, ErrRoomExists()},
+	},
+}
+
+func TestMakeCreateRoomEndpoint(t *testing.T) {
+	t.Log("MakeCreateRoomEndpoint")
+
+	for _, testcase := range makeCreateRoomEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeCreateRoomEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeDecommissionEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *DecommissionResponse
+	err     error
+}{
+	{
+		name:    "should return an empty response if the room was decommissioned",
+		client:  mockCorrectClientsService{},
+		request: &DecommissionRequest{Id: 3},
+		want:    &DecommissionResponse{nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: 3,
+		want:    &DecommissionResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &DecommissionRequest{},
+		want:    &DecommissionResponse{ErrRoomHasBookings()},
+	},
+}
+
+func TestMakeDecommissionEndpoint(t *testing.T) {
+	t.Log("MakeDecommissionEndpoint")
+
+	for _, testcase := range makeDecommissionEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeDecommissionEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeListBookingsEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *ListBookingsResponse
+	err     error
+}{
+	{
+		name:    "should return the bookings of the user",
+		client:  mockCorrectClientsService{},
+		request: &ListBookingsRequest{},
+		want:    &ListBookingsResponse{[]Booking{{testReservation.Id, testReservation.Code, 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)}}, nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &ListBookingsResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &ListBookingsRequest{},
+		want:    &ListBookingsResponse{nil, ErrInvalidDateRange()},
+	},
+}
+
+func TestMakeListBookingsEndpoint(t *testing.T) {
+	t.Log("MakeListBookingsEndpoint")
+
+	for _, testcase := range makeListBookingsEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeListBookingsEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeReservationEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *GetReservationResponse
+	err     error
+}{
+	{
+		name:    "should return the reservation",
+		client:  mockCorrectClientsService{},
+		request: &GetReservationRequest{Id: "K7QX9M"},
+		want:    &GetReservationResponse{testReservation, nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "K7QX9M",
+		want:    &GetReservationResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &GetReservationRequest{Id: "K7QX9M"},
+		want:    &GetReservationResponse{Reservation{}, ErrNotBookingOwner()},
+	},
+}
+
+func TestMakeReservationEndpoint(t *testing.T) {
+	t.Log("MakeReservationEndpoint")
+
+	for _, testcase := range makeReservationEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeReservationEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeJoinWaitlistEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *WaitlistResponse
+	err     error
+}{
+	{
+		name:    "should return the waitlist entry",
+		client:  mockCorrectClientsService{},
+		request: &JoinWaitlistRequest{},
+		want:    &WaitlistResponse{testWaitlistEntry, nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &WaitlistResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &JoinWaitlistRequest{},
+		want:    &WaitlistResponse{WaitlistEntry{}, ErrInvalidDateRange()},
+	},
+}
+
+func TestMakeJoinWaitlistEndpoint(t *testing.T) {
+	t.Log("MakeJoinWaitlistEndpoint")
+
+	for _, testcase := range makeJoinWaitlistEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeJoinWaitlistEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeWaitlistEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *WaitlistResponse
+	err     error
+}{
+	{
+		name:    "should return the waitlist entry",
+		client:  mockCorrectClientsService{},
+		request: &WaitlistPositionRequest{Id: testWaitlistEntry.Id},
+		want:    &WaitlistResponse{testWaitlistEntry, nil},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: testWaitlistEntry.Id,
+		want:    &WaitlistResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &WaitlistPositionRequest{Id: testWaitlistEntry.Id},
+		want:    &WaitlistResponse{WaitlistEntry{}, ErrWaitlistEntryNotFound()},
+	},
+}
+
+func TestMakeWaitlistEndpoint(t *testing.T) {
+	t.Log("MakeWaitlistEndpoint")
+
+	for _, testcase := range makeWaitlistEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeWaitlistEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeConfirmEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *BookResponse
+	err     error
+}{
+	{
+		name:    "should return the confirmed reservation",
+		client:  mockCorrectClientsService{},
+		request: &ConfirmRequest{Id: "K7QX9M"},
+		want:    &BookResponse{Reservation: testReservation},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "K7QX9M",
+		want:    &BookResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &ConfirmRequest{Id: "K7QX9M"},
+		want:    &BookResponse{Err: ErrHoldExpired()},
+	},
+}
+
+func TestMakeConfirmEndpoint(t *testing.T) {
+	t.Log("MakeConfirmEndpoint")
+
+	for _, testcase := range makeConfirmEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeConfirmEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeModifyEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *BookResponse
+	err     error
+}{
+	{
+		name:    "should return the moved reservation",
+		client:  mockCorrectClientsService{},
+		request: &ModifyBookingRequest{Id: "K7QX9M", Room: 2},
+		want:    &BookResponse{Reservation: testReservation},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "K7QX9M",
+		want:    &BookResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &ModifyBookingRequest{Id: "K7QX9M", Room: 2},
+		want:    &BookResponse{Err: ErrRoomNotAvailable()},
+	},
+}
+
+func TestMakeModifyEndpoint(t *testing.T) {
+	t.Log("MakeModifyEndpoint")
+
+	for _, testcase := range makeModifyEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeModifyEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeAddBlackoutEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *AddBlackoutResponse
+	err     error
+}{
+	{
+		name:    "should return the blackout and its conflicts",
+		client:  mockCorrectClientsService{},
+		request: &AddBlackoutRequest{Token: "jjj.www.ttt", Blackout: testBlackout},
+		want:    &AddBlackoutResponse{Blackout: testBlackout, Conflicts: []Reservation{testReservation}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: testBlackout,
+		want:    &AddBlackoutResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &AddBlackoutRequest{Token: "jjj.www.ttt", Blackout: testBlackout},
+		want:    &AddBlackoutResponse{Err: ErrRoomNotFound()},
+	},
+}
+
+func TestMakeAddBlackoutEndpoint(t *testing.T) {
+	t.Log("MakeAddBlackoutEndpoint")
+
+	for _, testcase := range makeAddBlackoutEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeAddBlackoutEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeBlackoutsEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *ListBlackoutsResponse
+	err     error
+}{
+	{
+		name:    "should return the blackouts",
+		client:  mockCorrectClientsService{},
+		request: &ListBlackoutsRequest{Token: "jjj.www.ttt"},
+		want:    &ListBlackoutsResponse{Blackouts: []Blackout{testBlackout}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &ListBlackoutsResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &ListBlackoutsRequest{Token: "jjj.www.ttt"},
+		want:    &ListBlackoutsResponse{Err: ErrNotAdmin()},
+	},
+}
+
+func TestMakeBlackoutsEndpoint(t *testing.T) {
+	t.Log("MakeBlackoutsEndpoint")
+
+	for _, testcase := range makeBlackoutsEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeBlackoutsEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeLiftBlackoutEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *RemoveBlackoutResponse
+	err     error
+}{
+	{
+		name:    "should remove the blackout",
+		client:  mockCorrectClientsService{},
+		request: &RemoveBlackoutRequest{Token: "jjj.www.ttt", Id: "B4CK0T"},
+		want:    &RemoveBlackoutResponse{},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "B4CK0T",
+		want:    &RemoveBlackoutResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &RemoveBlackoutRequest{Token: "jjj.www.ttt", Id: "B4CK0T"},
+		want:    &RemoveBlackoutResponse{Err: ErrBlackoutNotFound()},
+	},
+}
+
+func TestMakeLiftBlackoutEndpoint(t *testing.T) {
+	t.Log("MakeLiftBlackoutEndpoint")
+
+	for _, testcase := range makeLiftBlackoutEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeLiftBlackoutEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeHistoryEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *HistoryResponse
+	err     error
+}{
+	{
+		name:    "should return the history",
+		client:  mockCorrectClientsService{},
+		request: &HistoryRequest{Token: "jjj.www.ttt", Id: testReservation.Id},
+		want:    &HistoryResponse{Events: []Event{testEvent}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: testReservation.Id,
+		want:    &HistoryResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &HistoryRequest{Token: "jjj.www.ttt", Room: 1},
+		want:    &HistoryResponse{Err: ErrHistoryNotRecorded()},
+	},
+}
+
+func TestMakeHistoryEndpoint(t *testing.T) {
+	t.Log("MakeHistoryEndpoint")
+
+	for _, testcase := range makeHistoryEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeHistoryEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeSnapshotEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *SnapshotResponse
+	err     error
+}{
+	{
+		name:    "should return the snapshot",
+		client:  mockCorrectClientsService{},
+		request: &SnapshotRequest{Token: "jjj.www.ttt"},
+		want:    &SnapshotResponse{Snapshot: testSnapshot},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &SnapshotResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &SnapshotRequest{Token: "jjj.www.ttt"},
+		want:    &SnapshotResponse{Err: ErrNotAdmin()},
+	},
+}
+
+func TestMakeSnapshotEndpoint(t *testing.T) {
+	t.Log("MakeSnapshotEndpoint")
+
+	for _, testcase := range makeSnapshotEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeSnapshotEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeAddWebhookEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *AddWebhookResponse
+	err     error
+}{
+	{
+		name:    "should return the webhook added",
+		client:  mockCorrectClientsService{},
+		request: &AddWebhookRequest{Token: "jjj.www.ttt", Webhook: Webhook{Url: testWebhook.Url}},
+		want:    &AddWebhookResponse{Webhook: testWebhook},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: testWebhook,
+		want:    &AddWebhookResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &AddWebhookRequest{Token: "jjj.www.ttt", Webhook: Webhook{Url: testWebhook.Url}},
+		want:    &AddWebhookResponse{Err: ErrInvalidWebhook()},
+	},
+}
+
+func TestMakeAddWebhookEndpoint(t *testing.T) {
+	t.Log("MakeAddWebhookEndpoint")
+
+	for _, testcase := range makeAddWebhookEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeAddWebhookEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeWebhooksEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *ListWebhooksResponse
+	err     error
+}{
+	{
+		name:    "should return the webhooks",
+		client:  mockCorrectClientsService{},
+		request: &ListWebhooksRequest{Token: "jjj.www.ttt"},
+		want:    &ListWebhooksResponse{Webhooks: []Webhook{testWebhook}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &ListWebhooksResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &ListWebhooksRequest{Token: "jjj.www.ttt"},
+		want:    &ListWebhooksResponse{Err: ErrNotAdmin()},
+	},
+}
+
+func TestMakeWebhooksEndpoint(t *testing.T) {
+	t.Log("MakeWebhooksEndpoint")
+
+	for _, testcase := range makeWebhooksEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeWebhooksEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeRemoveWebhookEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *RemoveWebhookResponse
+	err     error
+}{
+	{
+		name:    "should remove the webhook",
+		client:  mockCorrectClientsService{},
+		request: &RemoveWebhookRequest{Token: "jjj.www.ttt", Id: testWebhook.Id},
+		want:    &RemoveWebhookResponse{},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: testWebhook.Id,
+		want:    &RemoveWebhookResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &RemoveWebhookRequest{Token: "jjj.www.ttt", Id: testWebhook.Id},
+		want:    &RemoveWebhookResponse{Err: ErrWebhookNotFound()},
+	},
+}
+
+func TestMakeRemoveWebhookEndpoint(t *testing.T) {
+	t.Log("MakeRemoveWebhookEndpoint")
+
+	for _, testcase := range makeRemoveWebhookEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeRemoveWebhookEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeDeadLettersEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    *DeadLettersResponse
+	err     error
+}{
+	{
+		name:    "should return the dead letters",
+		client:  mockCorrectClientsService{},
+		request: &DeadLettersRequest{Token: "jjj.www.ttt"},
+		want:    &DeadLettersResponse{DeadLetters: []DeadLetter{testDeadLetter}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: "jjj.www.ttt",
+		want:    &DeadLettersResponse{},
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &DeadLettersRequest{Token: "jjj.www.ttt"},
+		want:    &DeadLettersResponse{Err: ErrWebhooksNotEnabled()},
+	},
+}
+
+func TestMakeDeadLettersEndpoint(t *testing.T) {
+	t.Log("MakeDeadLettersEndpoint")
+
+	for _, testcase := range makeDeadLettersEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeDeadLettersEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var makeWatchEndpointTest = []struct {
+	name    string
+	client  RoomsService
+	request interface{}
+	want    [][]DayAvailability
+	err     error
+	respErr error
+}{
+	{
+		name:    "should return the updates",
+		client:  mockCorrectClientsService{},
+		request: &AvailabilityRequest{From: testReservation.From, To: testReservation.To},
+		want:    [][]DayAvailability{{{testReservation.From, 5}}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		client:  mockCorrectClientsService{},
+		request: testReservation.From,
+		err:     ErrInvalidRequestStructure(),
+	},
+	{
+		name:    "should return an error if the endpoint returns an error",
+		client:  mockErrorClientsService{},
+		request: &AvailabilityRequest{From: testReservation.To, To: testReservation.From},
+		respErr: ErrInvalidDateRange(),
+	},
+}
+
+func TestMakeWatchEndpoint(t *testing.T) {
+	t.Log("MakeWatchEndpoint")
+
+	for _, testcase := range makeWatchEndpointTest {
+		t.Logf(testcase.name)
+
+		endpoint := MakeWatchEndpoint(testcase.client)
+		result, err := endpoint(context.Background(), testcase.request)
+
+		response := result.(*WatchAvailabilityResponse)
+		var updates [][]DayAvailability
+		if response.Updates != nil {
+			for update := range response.Updates {
+				updates = append(updates, update)
+			}
+		}
+		assert.DeepEqual(t, updates, testcase.want)
+		assert.DeepEqual(t, response.Err, testcase.respErr)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
@@ -4,6 +4,32 @@ const (
 	InvalidRequestStructure  = "Invalid request structure"
 	InvalidResponseStructure = "Invalid response structure"
 	NoRoomAvailable          = "No room available"
+	RoomNotFound             = "Room not found"
+	BookingNotFound          = "Booking not found"
+	NotBookingOwner          = "Booking belongs to another user"
+	InvalidDateRange         = "Invalid date range"
+	InvalidRoomType          = "Invalid room type"
+	InvalidRoom              = "Invalid room"
+	RoomExists               = "Room already exists"
+	RoomHasBookings          = "Room has future bookings"
+	NotAdmin                 = "Operation requires an administrator"
+	ReservationExists        = "Reservation already exists"
+	WaitlistEntryNotFound    = "Waitlist entry not found"
+	NotHeld                  = "Reservation is not held"
+	HoldExpired              = "Hold has expired"
+	InvalidIdempotencyKey    = "Invalid idempotency key"
+	InvalidRoomCount         = "Invalid room count"
+	RoomNotAvailable         = "Room is not available for those dates"
+	BlackoutNotFound         = "Blackout not found"
+	QuotaExceeded            = "Booking quota exceeded"
+	HistoryNotRecorded       = "Booking history is not recorded"
+	NotLeader                = "Rooms node is not the leader"
+	ChangeNotCommitted       = "Change may not have been committed"
+	VersionConflict          = "Booking was changed by another request"
+	InvalidWebhook           = "Invalid webhook"
+	WebhookNotFound          = "Webhook not found"
+	WebhooksNotEnabled       = "Webhooks are not enabled"
+	IdempotencyKeyUsed       = "Idempotency key was already used"
 )
 
 type ErrorWithMsg struct {
@@ -25,3 +51,107 @@ func ErrInvalidResponseStructure() error {
 func ErrNoRoomAvailable() error {
 	return ErrorWithMsg{NoRoomAvailable}
 }
+
+func ErrRoomNotFound() error {
+	return ErrorWithMsg{RoomNotFound}
+}
+
+func ErrBookingNotFound() error {
+	return ErrorWithMsg{BookingNotFound}
+}
+
+func ErrNotBookingOwner() error {
+	return ErrorWithMsg{NotBookingOwner}
+}
+
+func ErrInvalidDateRange() error {
+	return ErrorWithMsg{InvalidDateRange}
+}
+
+func ErrInvalidRoomType() error {
+	return ErrorWithMsg{InvalidRoomType}
+}
+
+func ErrInvalidRoom() error {
+	return ErrorWithMsg{InvalidRoom}
+}
+
+func ErrRoomExists() error {
+	return ErrorWithMsg{RoomExists}
+}
+
+func ErrRoomHasBookings() error {
+	return ErrorWithMsg{RoomHasBookings}
+}
+
+func ErrNotAdmin() error {
+	return ErrorWithMsg{NotAdmin}
+}
+
+func ErrReservationExists() error {
+	return ErrorWithMsg{ReservationExists}
+}
+
+func ErrWaitlistEntryNotFound() error {
+	return ErrorWithMsg{WaitlistEntryNotFound}
+}
+
+func ErrNotHeld() error {
+	return ErrorWithMsg{NotHeld}
+}
+
+func ErrHoldExpired() error {
+	return ErrorWithMsg{HoldExpired}
+}
+
+func ErrInvalidIdempotencyKey() error {
+	return ErrorWithMsg{InvalidIdempotencyKey}
+}
+
+func ErrInvalidRoomCount() error {
+	return ErrorWithMsg{InvalidRoomCount}
+}
+
+func ErrRoomNotAvailable() error {
+	return ErrorWithMsg{RoomNotAvailable}
+}
+
+func ErrBlackoutNotFound() error {
+	return ErrorWithMsg{BlackoutNotFound}
+}
+
+func ErrQuotaExceeded() error {
+	return ErrorWithMsg{QuotaExceeded}
+}
+
+func ErrHistoryNotRecorded() error {
+	return ErrorWithMsg{HistoryNotRecorded}
+}
+
+func ErrNotLeader() error {
+	return ErrorWithMsg{NotLeader}
+}
+
+func ErrChangeNotCommitted() error {
+	return ErrorWithMsg{ChangeNotCommitted}
+}
+
+func ErrVersionConflict() error {
+	return ErrorWithMsg{VersionConflict}
+}
+
+func ErrInvalidWebhook() error {
+	return ErrorWithMsg{InvalidWebhook}
+}
+
+func ErrWebhookNotFound() error {
+	return ErrorWithMsg{WebhookNotFound}
+}
+
+func ErrWebhooksNotEnabled() error {
+	return ErrorWithMsg{WebhooksNotEnabled}
+}
+
+func ErrIdempotencyKeyUsed() error {
+	return ErrorWithMsg{IdempotencyKeyUsed}
+}
@@ -0,0 +1,121 @@
+package rooms
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// How often the leader is looked up while a cluster elects one
+	leaderPollInterval = 20 * time.Millisecond
+	// Marks requests forwarded to the leader so they are never forwarded again
+	forwardedHeader = "x-rooms-forwarded"
+)
+
+// leaderForwarder sends requests to the leader of a cluster
+type leaderForwarder struct {
+	leader  func() (string, bool)
+	wait    time.Duration
+	options []grpc.DialOption
+	nodes   map[string]Endpoints
+	mux     sync.Mutex
+}
+
+// ForwardToLeader returns the endpoints with the ones that change bookings, rooms,
+// blackouts, the waitlist or the webhooks calling the leader of the cluster
+// when the node is not the leader.
+// leader returns the gRPC address of the leader, empty if there is none, and whether it is this node,
+// which is dialed with the options given.
+// Requests wait up to a time for a leader to be elected and fail with ErrNotLeader after it.
+// Requests forwarded by another node are served by the node
+func ForwardToLeader(local Endpoints, leader func() (string, bool), wait time.Duration, options ...grpc.DialOption) Endpoints {
+	f := &leaderForwarder{leader: leader, wait: wait, options: options, nodes: map[string]Endpoints{}}
+	endpoints := local
+	endpoints.BookEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.BookEndpoint })
+	endpoints.CancelEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.CancelEndpoint })
+	endpoints.CreateRoomEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.CreateRoomEndpoint })
+	endpoints.UpdateRoomEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.UpdateRoomEndpoint })
+	endpoints.DecommissionEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.DecommissionEndpoint })
+	endpoints.JoinWaitlistEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.JoinWaitlistEndpoint })
+	endpoints.WaitlistEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.WaitlistEndpoint })
+	endpoints.HoldEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.HoldEndpoint })
+	endpoints.ConfirmEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.ConfirmEndpoint })
+	endpoints.ModifyEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.ModifyEndpoint })
+	endpoints.AddBlackoutEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.AddBlackoutEndpoint })
+	endpoints.LiftBlackoutEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.LiftBlackoutEndpoint })
+	endpoints.AddWebhookEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.AddWebhookEndpoint })
+	endpoints.WebhooksEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.WebhooksEndpoint })
+	endpoints.RemoveWebhookEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.RemoveWebhookEndpoint })
+	endpoints.DeadLettersEndpoint = f.forward(local, func(e Endpoints) endpoint.Endpoint { return e.DeadLettersEndpoint })
+	return endpoints
+}
+
+func (f *leaderForwarder) forward(local Endpoints, pick func(Endpoints) endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if forwarded(ctx) {
+			return pick(local)(ctx, request)
+		}
+		addr, self, err := f.waitLeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if self {
+			return pick(local)(ctx, request)
+		}
+		node, err := f.node(addr)
+		if err != nil {
+			return nil, err
+		}
+		return pick(node)(ctx, request)
+	}
+}
+
+func (f *leaderForwarder) waitLeader(ctx context.Context) (string, bool, error) {
+	deadline := time.NewTimer(f.wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(leaderPollInterval)
+	defer ticker.Stop()
+	for {
+		if addr, self := f.leader(); addr != "" || self {
+			return addr, self, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case <-deadline.C:
+			return "", false, ErrNotLeader()
+		case <-ticker.C:
+		}
+	}
+}
+
+// The endpoints of every node are dialed once
+func (f *leaderForwarder) node(addr string) (Endpoints, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if node, ok := f.nodes[addr]; ok {
+		return node, nil
+	}
+	options := append([]grpc.DialOption{grpc.WithUnaryInterceptor(markForwarded)}, f.options...)
+	conn, err := grpc.Dial(addr, options...)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	node := NewGRPCClient(conn)
+	f.nodes[addr] = node
+	return node, nil
+}
+
+func markForwarded(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(metadata.AppendToOutgoingContext(ctx, forwardedHeader, "true"), method, req, reply, cc, opts...)
+}
+
+func forwarded(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(forwardedHeader)) > 0
+}
@@ -1,9 +1,12 @@
 package rooms
 
 import (
+	"bytes"
 	"context"
 	"go-booking-service/pb"
+	"time"
 
+	"github.com/go-kit/kit/endpoint"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	"google.golang.org/grpc"
 )
@@ -27,9 +30,232 @@ func NewGRPCClient(conn *grpc.ClientConn) Endpoints {
 		pb.CheckResponse{},
 	).Endpoint()
 
+	cancelEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"Cancel",
+		encodeGRPCCancelRequest,
+		decodeGRPCCancelResponse,
+		pb.CancelResponse{},
+	).Endpoint()
+
+	availabilityEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"Availability",
+		encodeGRPCAvailabilityRequest,
+		decodeGRPCAvailabilityResponse,
+		pb.AvailabilityResponse{},
+	).Endpoint()
+
+	listRoomsEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"ListRooms",
+		encodeGRPCListRoomsRequest,
+		decodeGRPCListRoomsResponse,
+		pb.ListRoomsResponse{},
+	).Endpoint()
+
+	createRoomEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"CreateRoom",
+		encodeGRPCRoomRequest,
+		decodeGRPCRoomResponse,
+		pb.RoomResponse{},
+	).Endpoint()
+
+	updateRoomEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"UpdateRoom",
+		encodeGRPCRoomRequest,
+		decodeGRPCRoomResponse,
+		pb.RoomResponse{},
+	).Endpoint()
+
+	decommissionEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"DecommissionRoom",
+		encodeGRPCDecommissionRequest,
+		decodeGRPCDecommissionResponse,
+		pb.DecommissionResponse{},
+	).Endpoint()
+
+	listBookingsEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"ListBookings",
+		encodeGRPCListBookingsRequest,
+		decodeGRPCListBookingsResponse,
+		pb.ListBookingsResponse{},
+	).Endpoint()
+
+	reservationEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"GetReservation",
+		encodeGRPCGetReservationRequest,
+		decodeGRPCGetReservationResponse,
+		pb.GetReservationResponse{},
+	).Endpoint()
+
+	joinWaitlistEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"JoinWaitlist",
+		encodeGRPCJoinWaitlistRequest,
+		decodeGRPCWaitlistResponse,
+		pb.WaitlistResponse{},
+	).Endpoint()
+
+	waitlistEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"WaitlistPosition",
+		encodeGRPCWaitlistPositionRequest,
+		decodeGRPCWaitlistResponse,
+		pb.WaitlistResponse{},
+	).Endpoint()
+
+	holdEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"Hold",
+		encodeGRPCBookRequest,
+		decodeGRPCBookResponse,
+		pb.BookResponse{},
+	).Endpoint()
+
+	confirmEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"Confirm",
+		encodeGRPCConfirmRequest,
+		decodeGRPCBookResponse,
+		pb.BookResponse{},
+	).Endpoint()
+
+	modifyEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"ModifyBooking",
+		encodeGRPCModifyBookingRequest,
+		decodeGRPCBookResponse,
+		pb.BookResponse{},
+	).Endpoint()
+
+	addBlackoutEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"AddBlackout",
+		encodeGRPCAddBlackoutRequest,
+		decodeGRPCAddBlackoutResponse,
+		pb.AddBlackoutResponse{},
+	).Endpoint()
+
+	blackoutsEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"ListBlackouts",
+		encodeGRPCListBlackoutsRequest,
+		decodeGRPCListBlackoutsResponse,
+		pb.ListBlackoutsResponse{},
+	).Endpoint()
+
+	liftBlackoutEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"RemoveBlackout",
+		encodeGRPCRemoveBlackoutRequest,
+		decodeGRPCRemoveBlackoutResponse,
+		pb.RemoveBlackoutResponse{},
+	).Endpoint()
+
+	watchEndpoint := makeGRPCWatchEndpoint(conn)
+
+	historyEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"History",
+		encodeGRPCHistoryRequest,
+		decodeGRPCHistoryResponse,
+		pb.HistoryResponse{},
+	).Endpoint()
+
+	snapshotEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"Snapshot",
+		encodeGRPCSnapshotRequest,
+		decodeGRPCSnapshotResponse,
+		pb.SnapshotResponse{},
+	).Endpoint()
+
+	addWebhookEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"AddWebhook",
+		encodeGRPCAddWebhookRequest,
+		decodeGRPCAddWebhookResponse,
+		pb.AddWebhookResponse{},
+	).Endpoint()
+
+	webhooksEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"ListWebhooks",
+		encodeGRPCListWebhooksRequest,
+		decodeGRPCListWebhooksResponse,
+		pb.ListWebhooksResponse{},
+	).Endpoint()
+
+	removeWebhookEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"RemoveWebhook",
+		encodeGRPCRemoveWebhookRequest,
+		decodeGRPCRemoveWebhookResponse,
+		pb.RemoveWebhookResponse{},
+	).Endpoint()
+
+	deadLettersEndpoint := grpctransport.NewClient(
+		conn,
+		"pb.Rooms",
+		"DeadLetters",
+		encodeGRPCDeadLettersRequest,
+		decodeGRPCDeadLettersResponse,
+		pb.DeadLettersResponse{},
+	).Endpoint()
+
 	return Endpoints{
-		BookEndpoint:  bookEndpoint,
-		CheckEndpoint: checkEndpoint,
+		BookEndpoint:          bookEndpoint,
+		CheckEndpoint:         checkEndpoint,
+		CancelEndpoint:        cancelEndpoint,
+		AvailabilityEndpoint:  availabilityEndpoint,
+		WatchEndpoint:         watchEndpoint,
+		ListRoomsEndpoint:     listRoomsEndpoint,
+		CreateRoomEndpoint:    createRoomEndpoint,
+		UpdateRoomEndpoint:    updateRoomEndpoint,
+		DecommissionEndpoint:  decommissionEndpoint,
+		ListBookingsEndpoint:  listBookingsEndpoint,
+		ReservationEndpoint:   reservationEndpoint,
+		JoinWaitlistEndpoint:  joinWaitlistEndpoint,
+		WaitlistEndpoint:      waitlistEndpoint,
+		HoldEndpoint:          holdEndpoint,
+		ConfirmEndpoint:       confirmEndpoint,
+		ModifyEndpoint:        modifyEndpoint,
+		AddBlackoutEndpoint:   addBlackoutEndpoint,
+		BlackoutsEndpoint:     blackoutsEndpoint,
+		LiftBlackoutEndpoint:  liftBlackoutEndpoint,
+		HistoryEndpoint:       historyEndpoint,
+		SnapshotEndpoint:      snapshotEndpoint,
+		AddWebhookEndpoint:    addWebhookEndpoint,
+		WebhooksEndpoint:      webhooksEndpoint,
+		RemoveWebhookEndpoint: removeWebhookEndpoint,
+		DeadLettersEndpoint:   deadLettersEndpoint,
 	}
 }
 
@@ -39,8 +265,12 @@ func encodeGRPCBookRequest(_ context.Context, request interface{}) (interface{},
 		return &pb.BookRequest{}, ErrInvalidRequestStructure()
 	}
 	return &pb.BookRequest{
-		Token: req.Token,
-		Date:  req.Date.Unix(),
+		Token:          req.Token,
+		IdempotencyKey: req.IdempotencyKey,
+		Count:          int64(req.Count),
+		From:           req.From.Unix(),
+		To:             req.To.Unix(),
+		Filter:         encodeGRPCRoomFilter(req.Filter),
 	}, nil
 }
 
@@ -49,9 +279,14 @@ func decodeGRPCBookResponse(_ context.Context, grpcReply interface{}) (interface
 	if !ok {
 		return &BookResponse{}, ErrInvalidResponseStructure()
 	}
+	var reservations []Reservation
+	for _, reservation := range reply.Reservations {
+		reservations = append(reservations, decodeGRPCReservation(reservation))
+	}
 	return &BookResponse{
-		Id:  int(reply.Id),
-		Err: str2err(reply.Error),
+		Reservation:  decodeGRPCReservation(reply.Reservation),
+		Reservations: reservations,
+		Err:          reason2err(reply.Reason, reply.Error),
 	}, nil
 }
 
@@ -61,7 +296,8 @@ func encodeGRPCCheckRequest(_ context.Context, request interface{}) (interface{}
 		return &pb.CheckRequest{}, ErrInvalidRequestStructure()
 	}
 	return &pb.CheckRequest{
-		Date: req.Date.Unix(),
+		Date:   req.Date.Unix(),
+		Filter: encodeGRPCRoomFilter(req.Filter),
 	}, nil
 }
 
@@ -76,6 +312,580 @@ func decodeGRPCCheckResponse(_ context.Context, grpcReply interface{}) (interfac
 	}, nil
 }
 
+func encodeGRPCCancelRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*CancelRequest)
+	if !ok {
+		return &pb.CancelRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.CancelRequest{
+		Token:   req.Token,
+		Id:      req.Id,
+		Version: int64(req.Version),
+	}, nil
+}
+
+func decodeGRPCCancelResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.CancelResponse)
+	if !ok {
+		return &CancelResponse{}, ErrInvalidResponseStructure()
+	}
+	return &CancelResponse{
+		Err: str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCAvailabilityRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*AvailabilityRequest)
+	if !ok {
+		return &pb.AvailabilityRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.AvailabilityRequest{
+		From:   req.From.Unix(),
+		To:     req.To.Unix(),
+		Filter: encodeGRPCRoomFilter(req.Filter),
+	}, nil
+}
+
+func encodeGRPCRoomFilter(filter RoomFilter) *pb.RoomFilter {
+	return &pb.RoomFilter{
+		Type:        filter.Type,
+		MinCapacity: int64(filter.MinCapacity),
+		Amenities:   filter.Amenities,
+	}
+}
+
+// go-kit has no streaming transport, the stream is opened by the endpoint
+func makeGRPCWatchEndpoint(conn *grpc.ClientConn) endpoint.Endpoint {
+	client := pb.NewRoomsClient(conn)
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, err := encodeGRPCAvailabilityRequest(ctx, request)
+		if err != nil {
+			return &WatchAvailabilityResponse{}, err
+		}
+		stream, err := client.WatchAvailability(ctx, req.(*pb.AvailabilityRequest))
+		if err != nil {
+			return &WatchAvailabilityResponse{}, err
+		}
+		return decodeGRPCAvailabilityStream(ctx, stream)
+	}
+}
+
+type availabilityStream interface {
+	Recv() (*pb.AvailabilityResponse, error)
+}
+
+// Reads the first message of the stream, which carries the error if any,
+// then forwards the updates until the stream or the context ends
+func decodeGRPCAvailabilityStream(ctx context.Context, stream availabilityStream) (interface{}, error) {
+	reply, err := stream.Recv()
+	if err != nil {
+		return &WatchAvailabilityResponse{}, err
+	}
+	if reply.Error != "" {
+		return &WatchAvailabilityResponse{Err: str2err(reply.Error)}, nil
+	}
+	updates := make(chan []DayAvailability, 1)
+	updates <- decodeGRPCDays(reply.Days)
+	go func() {
+		defer close(updates)
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case updates <- decodeGRPCDays(reply.Days):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &WatchAvailabilityResponse{Updates: updates}, nil
+}
+
+func decodeGRPCAvailabilityResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.AvailabilityResponse)
+	if !ok {
+		return &AvailabilityResponse{}, ErrInvalidResponseStructure()
+	}
+	return &AvailabilityResponse{
+		Days: decodeGRPCDays(reply.Days),
+		Err:  str2err(reply.Error),
+	}, nil
+}
+
+func decodeGRPCDays(pbDays []*pb.DayAvailability) []DayAvailability {
+	var days []DayAvailability
+	for _, day := range pbDays {
+		days = append(days, DayAvailability{
+			Date:      time.Unix(day.Date, 0).UTC(),
+			Available: int(day.Available),
+		})
+	}
+	return days
+}
+
+func encodeGRPCListRoomsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	_, ok := request.(*ListRoomsRequest)
+	if !ok {
+		return &pb.ListRoomsRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.ListRoomsRequest{}, nil
+}
+
+func decodeGRPCListRoomsResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.ListRoomsResponse)
+	if !ok {
+		return &ListRoomsResponse{}, ErrInvalidResponseStructure()
+	}
+	var rooms []RoomInfo
+	for _, room := range reply.Rooms {
+		rooms = append(rooms, decodeGRPCRoomInfo(room))
+	}
+	return &ListRoomsResponse{
+		Rooms: rooms,
+		Err:   str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCRoomRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*RoomRequest)
+	if !ok {
+		return &pb.RoomRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.RoomRequest{
+		Token: req.Token,
+		Room:  encodeGRPCRoomInfo(req.Room),
+	}, nil
+}
+
+func decodeGRPCRoomResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.RoomResponse)
+	if !ok {
+		return &RoomResponse{}, ErrInvalidResponseStructure()
+	}
+	return &RoomResponse{
+		Room: decodeGRPCRoomInfo(reply.Room),
+		Err:  str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCDecommissionRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*DecommissionRequest)
+	if !ok {
+		return &pb.DecommissionRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.DecommissionRequest{
+		Token: req.Token,
+		Id:    int64(req.Id),
+	}, nil
+}
+
+func decodeGRPCDecommissionResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.DecommissionResponse)
+	if !ok {
+		return &DecommissionResponse{}, ErrInvalidResponseStructure()
+	}
+	return &DecommissionResponse{
+		Err: str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCListBookingsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*ListBookingsRequest)
+	if !ok {
+		return &pb.ListBookingsRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.ListBookingsRequest{
+		Token: req.Token,
+		From:  req.From.Unix(),
+		To:    req.To.Unix(),
+	}, nil
+}
+
+func decodeGRPCListBookingsResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.ListBookingsResponse)
+	if !ok {
+		return &ListBookingsResponse{}, ErrInvalidResponseStructure()
+	}
+	bookings := make([]Booking, len(reply.Bookings))
+	for i, booking := range reply.Bookings {
+		bookings[i] = Booking{
+			Id:   booking.Id,
+			Code: booking.Code,
+			Room: int(booking.Room),
+			Date: time.Unix(booking.Date, 0).UTC(),
+		}
+	}
+	return &ListBookingsResponse{
+		Bookings: bookings,
+		Err:      str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCGetReservationRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*GetReservationRequest)
+	if !ok {
+		return &pb.GetReservationRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.GetReservationRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCGetReservationResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.GetReservationResponse)
+	if !ok {
+		return &GetReservationResponse{}, ErrInvalidResponseStructure()
+	}
+	return &GetReservationResponse{
+		Reservation: decodeGRPCReservation(reply.Reservation),
+		Err:         str2err(reply.Error),
+	}, nil
+}
+
+// An empty reservation is decoded to the zero value
+// so responses with errors compare equal on both sides
+func encodeGRPCHistoryRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*HistoryRequest)
+	if !ok {
+		return &pb.HistoryRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.HistoryRequest{
+		Token: req.Token,
+		Id:    req.Id,
+		Room:  int64(req.Room),
+	}, nil
+}
+
+func decodeGRPCHistoryResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.HistoryResponse)
+	if !ok {
+		return &HistoryResponse{}, ErrInvalidResponseStructure()
+	}
+	events := make([]Event, len(reply.Events))
+	for i, event := range reply.Events {
+		events[i] = decodeGRPCEvent(event)
+	}
+	return &HistoryResponse{
+		Events: events,
+		Err:    str2err(reply.Error),
+	}, nil
+}
+
+func decodeGRPCEvent(event *pb.Event) Event {
+	return Event{
+		Seq:         event.GetSeq(),
+		Type:        event.GetType(),
+		Time:        time.Unix(event.GetTime(), 0).UTC(),
+		Reservation: decodeGRPCReservation(event.GetReservation()),
+		Previous:    decodeGRPCReservation(event.GetPrevious()),
+	}
+}
+
+func encodeGRPCAddWebhookRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*AddWebhookRequest)
+	if !ok {
+		return &pb.AddWebhookRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.AddWebhookRequest{
+		Token:   req.Token,
+		Webhook: encodeGRPCWebhook(req.Webhook),
+	}, nil
+}
+
+func decodeGRPCAddWebhookResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.AddWebhookResponse)
+	if !ok {
+		return &AddWebhookResponse{}, ErrInvalidResponseStructure()
+	}
+	return &AddWebhookResponse{
+		Webhook: decodeGRPCWebhook(reply.Webhook),
+		Err:     str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCListWebhooksRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*ListWebhooksRequest)
+	if !ok {
+		return &pb.ListWebhooksRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.ListWebhooksRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCListWebhooksResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.ListWebhooksResponse)
+	if !ok {
+		return &ListWebhooksResponse{}, ErrInvalidResponseStructure()
+	}
+	var webhooks []Webhook
+	for _, webhook := range reply.Webhooks {
+		webhooks = append(webhooks, decodeGRPCWebhook(webhook))
+	}
+	return &ListWebhooksResponse{
+		Webhooks: webhooks,
+		Err:      str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCRemoveWebhookRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*RemoveWebhookRequest)
+	if !ok {
+		return &pb.RemoveWebhookRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.RemoveWebhookRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCRemoveWebhookResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.RemoveWebhookResponse)
+	if !ok {
+		return &RemoveWebhookResponse{}, ErrInvalidResponseStructure()
+	}
+	return &RemoveWebhookResponse{Err: str2err(reply.Error)}, nil
+}
+
+func encodeGRPCDeadLettersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*DeadLettersRequest)
+	if !ok {
+		return &pb.DeadLettersRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.DeadLettersRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCDeadLettersResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.DeadLettersResponse)
+	if !ok {
+		return &DeadLettersResponse{}, ErrInvalidResponseStructure()
+	}
+	var deadLetters []DeadLetter
+	for _, deadLetter := range reply.DeadLetters {
+		deadLetters = append(deadLetters, DeadLetter{
+			Payload: WebhookPayload{
+				Id:      deadLetter.Id,
+				Webhook: deadLetter.Webhook,
+				Event:   decodeGRPCEvent(deadLetter.Event),
+			},
+			Url:      deadLetter.Url,
+			Attempts: int(deadLetter.Attempts),
+			Error:    deadLetter.Error,
+			Failed:   time.Unix(deadLetter.Failed, 0).UTC(),
+		})
+	}
+	return &DeadLettersResponse{
+		DeadLetters: deadLetters,
+		Err:         str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCSnapshotRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*SnapshotRequest)
+	if !ok {
+		return &pb.SnapshotRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.SnapshotRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCSnapshotResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.SnapshotResponse)
+	if !ok {
+		return &SnapshotResponse{}, ErrInvalidResponseStructure()
+	}
+	if reply.Error != "" {
+		return &SnapshotResponse{Err: str2err(reply.Error)}, nil
+	}
+	snapshot, err := ReadSnapshot(bytes.NewReader(reply.Snapshot))
+	if err != nil {
+		return &SnapshotResponse{}, err
+	}
+	return &SnapshotResponse{Snapshot: snapshot}, nil
+}
+
+func decodeGRPCReservation(reservation *pb.Reservation) Reservation {
+	if reservation == nil {
+		return Reservation{}
+	}
+	var expires time.Time
+	if reservation.Expires != 0 {
+		expires = time.Unix(reservation.Expires, 0).UTC()
+	}
+	return Reservation{
+		Id:      reservation.Id,
+		Code:    reservation.Code,
+		User:    reservation.User,
+		Room:    int(reservation.Room),
+		From:    time.Unix(reservation.From, 0).UTC(),
+		To:      time.Unix(reservation.To, 0).UTC(),
+		Expires: expires,
+		Version: int(reservation.Version),
+	}
+}
+
+func encodeGRPCConfirmRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*ConfirmRequest)
+	if !ok {
+		return &pb.ConfirmRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.ConfirmRequest{
+		Token:   req.Token,
+		Id:      req.Id,
+		Version: int64(req.Version),
+	}, nil
+}
+
+func encodeGRPCModifyBookingRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*ModifyBookingRequest)
+	if !ok {
+		return &pb.ModifyBookingRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.ModifyBookingRequest{
+		Token:   req.Token,
+		Id:      req.Id,
+		From:    req.From.Unix(),
+		To:      req.To.Unix(),
+		Room:    int64(req.Room),
+		Version: int64(req.Version),
+	}, nil
+}
+
+func encodeGRPCAddBlackoutRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*AddBlackoutRequest)
+	if !ok {
+		return &pb.AddBlackoutRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.AddBlackoutRequest{
+		Token:    req.Token,
+		Blackout: encodeGRPCBlackout(req.Blackout),
+	}, nil
+}
+
+func decodeGRPCAddBlackoutResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.AddBlackoutResponse)
+	if !ok {
+		return &AddBlackoutResponse{}, ErrInvalidResponseStructure()
+	}
+	var conflicts []Reservation
+	for _, reservation := range reply.Conflicts {
+		conflicts = append(conflicts, decodeGRPCReservation(reservation))
+	}
+	return &AddBlackoutResponse{
+		Blackout:  decodeGRPCBlackout(reply.Blackout),
+		Conflicts: conflicts,
+		Err:       str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCListBlackoutsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*ListBlackoutsRequest)
+	if !ok {
+		return &pb.ListBlackoutsRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.ListBlackoutsRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCListBlackoutsResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.ListBlackoutsResponse)
+	if !ok {
+		return &ListBlackoutsResponse{}, ErrInvalidResponseStructure()
+	}
+	var blackouts []Blackout
+	for _, blackout := range reply.Blackouts {
+		blackouts = append(blackouts, decodeGRPCBlackout(blackout))
+	}
+	return &ListBlackoutsResponse{
+		Blackouts: blackouts,
+		Err:       str2err(reply.Error),
+	}, nil
+}
+
+func encodeGRPCRemoveBlackoutRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*RemoveBlackoutRequest)
+	if !ok {
+		return &pb.RemoveBlackoutRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.RemoveBlackoutRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCRemoveBlackoutResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.RemoveBlackoutResponse)
+	if !ok {
+		return &RemoveBlackoutResponse{}, ErrInvalidResponseStructure()
+	}
+	return &RemoveBlackoutResponse{
+		Err: str2err(reply.Error),
+	}, nil
+}
+
+func decodeGRPCBlackout(blackout *pb.Blackout) Blackout {
+	if blackout == nil {
+		return Blackout{}
+	}
+	return Blackout{
+		Id:     blackout.Id,
+		Room:   int(blackout.Room),
+		From:   time.Unix(blackout.From, 0).UTC(),
+		To:     time.Unix(blackout.To, 0).UTC(),
+		Reason: blackout.Reason,
+	}
+}
+
+func encodeGRPCJoinWaitlistRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*JoinWaitlistRequest)
+	if !ok {
+		return &pb.JoinWaitlistRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.JoinWaitlistRequest{
+		Token:  req.Token,
+		From:   req.From.Unix(),
+		To:     req.To.Unix(),
+		Filter: encodeGRPCRoomFilter(req.Filter),
+	}, nil
+}
+
+func encodeGRPCWaitlistPositionRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req, ok := request.(*WaitlistPositionRequest)
+	if !ok {
+		return &pb.WaitlistPositionRequest{}, ErrInvalidRequestStructure()
+	}
+	return &pb.WaitlistPositionRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCWaitlistResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply, ok := grpcReply.(*pb.WaitlistResponse)
+	if !ok {
+		return &WaitlistResponse{}, ErrInvalidResponseStructure()
+	}
+	return &WaitlistResponse{
+		Entry: decodeGRPCWaitlistEntry(reply.Entry),
+		Err:   reason2err(reply.Reason, reply.Error),
+	}, nil
+}
+
+func decodeGRPCWaitlistEntry(entry *pb.WaitlistEntry) WaitlistEntry {
+	if entry == nil {
+		return WaitlistEntry{}
+	}
+	return WaitlistEntry{
+		Id:          entry.Id,
+		User:        entry.User,
+		From:        time.Unix(entry.From, 0).UTC(),
+		To:          time.Unix(entry.To, 0).UTC(),
+		Filter:      decodeGRPCRoomFilter(entry.Filter),
+		Position:    int(entry.Position),
+		Reservation: entry.Reservation,
+		Rejected:    entry.Rejected,
+	}
+}
+
 func str2err(s string) error {
 	switch s {
 	case "":
@@ -86,7 +896,82 @@ func str2err(s string) error {
 		return ErrInvalidResponseStructure()
 	case NoRoomAvailable:
 		return ErrNoRoomAvailable()
+	case RoomNotFound:
+		return ErrRoomNotFound()
+	case BookingNotFound:
+		return ErrBookingNotFound()
+	case NotBookingOwner:
+		return ErrNotBookingOwner()
+	case InvalidDateRange:
+		return ErrInvalidDateRange()
+	case InvalidRoomType:
+		return ErrInvalidRoomType()
+	case InvalidRoom:
+		return ErrInvalidRoom()
+	case RoomExists:
+		return ErrRoomExists()
+	case RoomHasBookings:
+		return ErrRoomHasBookings()
+	case NotAdmin:
+		return ErrNotAdmin()
+	case ReservationExists:
+		return ErrReservationExists()
+	case WaitlistEntryNotFound:
+		return ErrWaitlistEntryNotFound()
+	case NotHeld:
+		return ErrNotHeld()
+	case HoldExpired:
+		return ErrHoldExpired()
+	case InvalidIdempotencyKey:
+		return ErrInvalidIdempotencyKey()
+	case InvalidRoomCount:
+		return ErrInvalidRoomCount()
+	case RoomNotAvailable:
+		return ErrRoomNotAvailable()
+	case BlackoutNotFound:
+		return ErrBlackoutNotFound()
+	case QuotaExceeded:
+		return ErrQuotaExceeded()
+	case HistoryNotRecorded:
+		return ErrHistoryNotRecorded()
+	case NotLeader:
+		return ErrNotLeader()
+	case ChangeNotCommitted:
+		return ErrChangeNotCommitted()
+	case VersionConflict:
+		return ErrVersionConflict()
+	case InvalidWebhook:
+		return ErrInvalidWebhook()
+	case WebhookNotFound:
+		return ErrWebhookNotFound()
+	case WebhooksNotEnabled:
+		return ErrWebhooksNotEnabled()
+	case IdempotencyKeyUsed:
+		return ErrIdempotencyKeyUsed()
 	default:
 		return ErrorWithMsg{s}
 	}
 }
+
+// Rule violations are sent as their reason along the message
+func reason2err(reason, s string) error {
+	if reason == "" {
+		return str2err(s)
+	}
+	return RuleViolation{Reason: reason, Msg: s}
+}
+
+// Webhooks without event types are decoded with an empty list
+// so they compare equal on both sides
+func decodeGRPCWebhook(webhook *pb.Webhook) Webhook {
+	events := webhook.GetEvents()
+	if events == nil {
+		events = []string{}
+	}
+	return Webhook{
+		Id:     webhook.GetId(),
+		Url:    webhook.GetUrl(),
+		Secret: webhook.GetSecret(),
+		Events: events,
+	}
+}
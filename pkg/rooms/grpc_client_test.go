@@ -17,8 +17,23 @@ var encodeGRPCBookRequestTest = []struct {
 }{
 	{
 		name:    "should return the values in the pb structure",
-		request: &BookRequest{Token: "jjj.www.ttt", Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)},
-		want:    &pb.BookRequest{Token: "jjj.www.ttt", Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC).Unix()},
+		request: &BookRequest{Token: "jjj.www.ttt", From: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), To: time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)},
+		want:    &pb.BookRequest{Token: "jjj.www.ttt", From: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC).Unix(), To: time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC).Unix(), Filter: &pb.RoomFilter{}},
+	},
+	{
+		name:    "should return the filter in the pb structure",
+		request: &BookRequest{Token: "jjj.www.ttt", Filter: RoomFilter{Type: SuiteRoom, Amenities: []string{"balcony"}}},
+		want:    &pb.BookRequest{Token: "jjj.www.ttt", From: time.Time{}.Unix(), To: time.Time{}.Unix(), Filter: &pb.RoomFilter{Type: SuiteRoom, Amenities: []string{"balcony"}}},
+	},
+	{
+		name:    "should return the idempotency key in the pb structure",
+		request: &BookRequest{Token: "jjj.www.ttt", IdempotencyKey: "retry-1"},
+		want:    &pb.BookRequest{Token: "jjj.www.ttt", IdempotencyKey: "retry-1", From: time.Time{}.Unix(), To: time.Time{}.Unix(), Filter: &pb.RoomFilter{}},
+	},
+	{
+		name:    "should return the room count in the pb structure",
+		request: &BookRequest{Token: "jjj.www.ttt", Count: 3},
+		want:    &pb.BookRequest{Token: "jjj.www.ttt", Count: 3, From: time.Time{}.Unix(), To: time.Time{}.Unix(), Filter: &pb.RoomFilter{}},
 	},
 	{
 		name:    "should return an error if the request has the wrong structure",
@@ -50,7 +65,7 @@ var encodeGRPCCheckRequestTest = []struct {
 	{
 		name:    "should return the values in the pb structure",
 		request: &CheckRequest{Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)},
-		want:    &pb.CheckRequest{Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC).Unix()},
+		want:    &pb.CheckRequest{Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC).Unix(), Filter: &pb.RoomFilter{}},
 	},
 	{
 		name:    "should return an error if the request has the wrong structure",
@@ -81,8 +96,28 @@ var decodeGRPCBookResponseTest = []struct {
 }{
 	{
 		name:    "should return the values in the internal structure",
-		request: &pb.BookResponse{Id: 1, Error: ""},
-		want:    &BookResponse{Id: 1, Err: nil},
+		request: &pb.BookResponse{Reservation: testPBReservation, Error: ""},
+		want:    &BookResponse{Reservation: testReservation, Err: nil},
+	},
+	{
+		name:    "should return the expiry of a hold",
+		request: &pb.BookResponse{Reservation: testPBHold},
+		want:    &BookResponse{Reservation: testHold},
+	},
+	{
+		name:    "should return every reservation of a group",
+		request: &pb.BookResponse{Reservation: testPBReservation, Reservations: []*pb.Reservation{testPBReservation, testPBHold}},
+		want:    &BookResponse{Reservation: testReservation, Reservations: []Reservation{testReservation, testHold}},
+	},
+	{
+		name:    "should return the error without reservation",
+		request: &pb.BookResponse{Error: NoRoomAvailable},
+		want:    &BookResponse{Err: ErrNoRoomAvailable()},
+	},
+	{
+		name:    "should return the rule violation with its reason",
+		request: &pb.BookResponse{Error: "Stays must be at most 14 nights", Reason: ReasonMaxStay},
+		want:    &BookResponse{Err: RuleViolation{ReasonMaxStay, "Stays must be at most 14 nights"}},
 	},
 	{
 		name:    "should return an error if the request has the wrong structure",
@@ -136,3 +171,464 @@ func TestDecodeGRPCCheckResponse(t *testing.T) {
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
+
+var encodeGRPCCancelRequestTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.CancelRequest
+	err     error
+}{
+	{
+		name:    "should return the values in the pb structure",
+		request: &CancelRequest{Token: "jjj.www.ttt", Id: "1-2020-06-13"},
+		want:    &pb.CancelRequest{Token: "jjj.www.ttt", Id: "1-2020-06-13"},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "jjj.www.ttt",
+		want:    &pb.CancelRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestEncodeGRPCCancelRequest(t *testing.T) {
+	t.Log("encodeGRPCCancelRequest")
+
+	for _, testcase := range encodeGRPCCancelRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCCancelRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCCancelResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *CancelResponse
+	err     error
+}{
+	{
+		name:    "should return the values in the internal structure",
+		request: &pb.CancelResponse{Error: BookingNotFound},
+		want:    &CancelResponse{Err: ErrBookingNotFound()},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "jjj.www.ttt",
+		want:    &CancelResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCCancelResponse(t *testing.T) {
+	t.Log("decodeGRPCCancelResponse")
+
+	for _, testcase := range decodeGRPCCancelResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCCancelResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCAvailabilityRequestTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.AvailabilityRequest
+	err     error
+}{
+	{
+		name:    "should return the values in the pb structure",
+		request: &AvailabilityRequest{From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), To: time.Date(2020, 6, 20, 0, 0, 0, 0, time.UTC)},
+		want:    &pb.AvailabilityRequest{From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(), To: time.Date(2020, 6, 20, 0, 0, 0, 0, time.UTC).Unix(), Filter: &pb.RoomFilter{}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		want:    &pb.AvailabilityRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestEncodeGRPCAvailabilityRequest(t *testing.T) {
+	t.Log("encodeGRPCAvailabilityRequest")
+
+	for _, testcase := range encodeGRPCAvailabilityRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCAvailabilityRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCAvailabilityResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *AvailabilityResponse
+	err     error
+}{
+	{
+		name:    "should return the values in the internal structure",
+		request: &pb.AvailabilityResponse{Days: []*pb.DayAvailability{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(), Available: 5}}},
+		want:    &AvailabilityResponse{Days: []DayAvailability{{time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 5}}},
+	},
+	{
+		name:    "should return the error",
+		request: &pb.AvailabilityResponse{Error: InvalidDateRange},
+		want:    &AvailabilityResponse{Err: ErrInvalidDateRange()},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "Jhon",
+		want:    &AvailabilityResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCAvailabilityResponse(t *testing.T) {
+	t.Log("decodeGRPCAvailabilityResponse")
+
+	for _, testcase := range decodeGRPCAvailabilityResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCAvailabilityResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCRoomRequestTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.RoomRequest
+	err     error
+}{
+	{
+		name:    "should return the values in the pb structure",
+		request: &RoomRequest{Token: "jjj.www.ttt", Room: RoomInfo{Id: 3, Name: "301", Type: SuiteRoom, Capacity: 4}},
+		want:    &pb.RoomRequest{Token: "jjj.www.ttt", Room: &pb.RoomInfo{Id: 3, Name: "301", Type: SuiteRoom, Capacity: 4}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "jjj.www.ttt",
+		want:    &pb.RoomRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestEncodeGRPCRoomRequest(t *testing.T) {
+	t.Log("encodeGRPCRoomRequest")
+
+	for _, testcase := range encodeGRPCRoomRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCRoomRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCRoomResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the room in the internal structure",
+		request: &pb.RoomResponse{Room: &pb.RoomInfo{Id: 3, Name: "301", Type: SuiteRoom, Capacity: 4}},
+		want:    &RoomResponse{Room: RoomInfo{Id: 3, Name: "301", Type: SuiteRoom, Capacity: 4}},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.RoomResponse{Error: NotAdmin},
+		want:    &RoomResponse{Err: ErrNotAdmin()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: "301",
+		want:    &RoomResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCRoomResponse(t *testing.T) {
+	t.Log("decodeGRPCRoomResponse")
+
+	for _, testcase := range decodeGRPCRoomResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCRoomResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCListBookingsResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the bookings in the internal structure",
+		request: &pb.ListBookingsResponse{Bookings: []*pb.Booking{{Id: testReservation.Id, Code: testReservation.Code, Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix()}}},
+		want:    &ListBookingsResponse{Bookings: []Booking{{testReservation.Id, testReservation.Code, 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)}}},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.ListBookingsResponse{Error: InvalidDateRange},
+		want:    &ListBookingsResponse{Bookings: []Booking{}, Err: ErrInvalidDateRange()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: "1-2020-06-13",
+		want:    &ListBookingsResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCListBookingsResponse(t *testing.T) {
+	t.Log("decodeGRPCListBookingsResponse")
+
+	for _, testcase := range decodeGRPCListBookingsResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCListBookingsResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCGetReservationResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the reservation in the internal structure",
+		request: &pb.GetReservationResponse{Reservation: testPBReservation},
+		want:    &GetReservationResponse{Reservation: testReservation},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.GetReservationResponse{Error: BookingNotFound},
+		want:    &GetReservationResponse{Err: ErrBookingNotFound()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: "K7QX9M",
+		want:    &GetReservationResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCGetReservationResponse(t *testing.T) {
+	t.Log("decodeGRPCGetReservationResponse")
+
+	for _, testcase := range decodeGRPCGetReservationResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCGetReservationResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCWaitlistResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the waitlist entry in the internal structure",
+		request: &pb.WaitlistResponse{Entry: testPBWaitlistEntry},
+		want:    &WaitlistResponse{Entry: testWaitlistEntry},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.WaitlistResponse{Error: WaitlistEntryNotFound},
+		want:    &WaitlistResponse{Err: ErrWaitlistEntryNotFound()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: testWaitlistEntry.Id,
+		want:    &WaitlistResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCWaitlistResponse(t *testing.T) {
+	t.Log("decodeGRPCWaitlistResponse")
+
+	for _, testcase := range decodeGRPCWaitlistResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCWaitlistResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCAddBlackoutResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the blackout and its conflicts in the internal structure",
+		request: &pb.AddBlackoutResponse{Blackout: testPBBlackout, Conflicts: []*pb.Reservation{testPBReservation}},
+		want:    &AddBlackoutResponse{Blackout: testBlackout, Conflicts: []Reservation{testReservation}},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.AddBlackoutResponse{Error: BlackoutNotFound},
+		want:    &AddBlackoutResponse{Err: ErrBlackoutNotFound()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: testBlackout.Id,
+		want:    &AddBlackoutResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCAddBlackoutResponse(t *testing.T) {
+	t.Log("decodeGRPCAddBlackoutResponse")
+
+	for _, testcase := range decodeGRPCAddBlackoutResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCAddBlackoutResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCHistoryResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the events in the internal structure",
+		request: &pb.HistoryResponse{Events: []*pb.Event{testPBEvent}},
+		want:    &HistoryResponse{Events: []Event{testEvent}},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.HistoryResponse{Error: HistoryNotRecorded},
+		want:    &HistoryResponse{Events: []Event{}, Err: ErrHistoryNotRecorded()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: testEvent.Type,
+		want:    &HistoryResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCHistoryResponse(t *testing.T) {
+	t.Log("decodeGRPCHistoryResponse")
+
+	for _, testcase := range decodeGRPCHistoryResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCHistoryResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCAddWebhookResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the webhook in the internal structure",
+		request: &pb.AddWebhookResponse{Webhook: testPBWebhook},
+		want:    &AddWebhookResponse{Webhook: testWebhook},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.AddWebhookResponse{Error: InvalidWebhook},
+		want:    &AddWebhookResponse{Webhook: Webhook{Events: []string{}}, Err: ErrInvalidWebhook()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: testWebhook.Url,
+		want:    &AddWebhookResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCAddWebhookResponse(t *testing.T) {
+	t.Log("decodeGRPCAddWebhookResponse")
+
+	for _, testcase := range decodeGRPCAddWebhookResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCAddWebhookResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCDeadLettersResponseTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return the dead letters in the internal structure",
+		request: &pb.DeadLettersResponse{DeadLetters: []*pb.DeadLetter{testPBDeadLetter}},
+		want:    &DeadLettersResponse{DeadLetters: []DeadLetter{testDeadLetter}},
+	},
+	{
+		name:    "should return the error in the internal structure",
+		request: &pb.DeadLettersResponse{Error: WebhooksNotEnabled},
+		want:    &DeadLettersResponse{Err: ErrWebhooksNotEnabled()},
+	},
+	{
+		name:    "should return an error if the response has the wrong structure",
+		request: testDeadLetter.Url,
+		want:    &DeadLettersResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestDecodeGRPCDeadLettersResponse(t *testing.T) {
+	t.Log("decodeGRPCDeadLettersResponse")
+
+	for _, testcase := range decodeGRPCDeadLettersResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCDeadLettersResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
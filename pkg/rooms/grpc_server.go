@@ -1,16 +1,42 @@
 package rooms
 
 import (
+	"bytes"
 	"context"
 	"go-booking-service/pb"
 	"time"
 
+	"github.com/go-kit/kit/endpoint"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 )
 
 type GrpcServer struct {
-	book  grpctransport.Handler
-	check grpctransport.Handler
+	book          grpctransport.Handler
+	check         grpctransport.Handler
+	cancel        grpctransport.Handler
+	availability  grpctransport.Handler
+	listRooms     grpctransport.Handler
+	createRoom    grpctransport.Handler
+	updateRoom    grpctransport.Handler
+	decommission  grpctransport.Handler
+	listBookings  grpctransport.Handler
+	reservation   grpctransport.Handler
+	joinWaitlist  grpctransport.Handler
+	waitlist      grpctransport.Handler
+	hold          grpctransport.Handler
+	confirm       grpctransport.Handler
+	modify        grpctransport.Handler
+	addBlackout   grpctransport.Handler
+	blackouts     grpctransport.Handler
+	liftBlackout  grpctransport.Handler
+	history       grpctransport.Handler
+	snapshot      grpctransport.Handler
+	addWebhook    grpctransport.Handler
+	webhooks      grpctransport.Handler
+	removeWebhook grpctransport.Handler
+	deadLetters   grpctransport.Handler
+	// go-kit has no streaming transport, streams call the endpoint directly
+	watch endpoint.Endpoint
 }
 
 func NewGRPCServer(endpoints Endpoints) *GrpcServer {
@@ -25,6 +51,117 @@ func NewGRPCServer(endpoints Endpoints) *GrpcServer {
 			decodeGRPCCheckRequest,
 			encodeGRPCCheckResponse,
 		),
+		cancel: grpctransport.NewServer(
+			endpoints.CancelEndpoint,
+			decodeGRPCCancelRequest,
+			encodeGRPCCancelResponse,
+		),
+		availability: grpctransport.NewServer(
+			endpoints.AvailabilityEndpoint,
+			decodeGRPCAvailabilityRequest,
+			encodeGRPCAvailabilityResponse,
+		),
+		listRooms: grpctransport.NewServer(
+			endpoints.ListRoomsEndpoint,
+			decodeGRPCListRoomsRequest,
+			encodeGRPCListRoomsResponse,
+		),
+		createRoom: grpctransport.NewServer(
+			endpoints.CreateRoomEndpoint,
+			decodeGRPCRoomRequest,
+			encodeGRPCRoomResponse,
+		),
+		updateRoom: grpctransport.NewServer(
+			endpoints.UpdateRoomEndpoint,
+			decodeGRPCRoomRequest,
+			encodeGRPCRoomResponse,
+		),
+		decommission: grpctransport.NewServer(
+			endpoints.DecommissionEndpoint,
+			decodeGRPCDecommissionRequest,
+			encodeGRPCDecommissionResponse,
+		),
+		listBookings: grpctransport.NewServer(
+			endpoints.ListBookingsEndpoint,
+			decodeGRPCListBookingsRequest,
+			encodeGRPCListBookingsResponse,
+		),
+		reservation: grpctransport.NewServer(
+			endpoints.ReservationEndpoint,
+			decodeGRPCGetReservationRequest,
+			encodeGRPCGetReservationResponse,
+		),
+		joinWaitlist: grpctransport.NewServer(
+			endpoints.JoinWaitlistEndpoint,
+			decodeGRPCJoinWaitlistRequest,
+			encodeGRPCWaitlistResponse,
+		),
+		waitlist: grpctransport.NewServer(
+			endpoints.WaitlistEndpoint,
+			decodeGRPCWaitlistPositionRequest,
+			encodeGRPCWaitlistResponse,
+		),
+		hold: grpctransport.NewServer(
+			endpoints.HoldEndpoint,
+			decodeGRPCBookRequest,
+			encodeGRPCBookResponse,
+		),
+		confirm: grpctransport.NewServer(
+			endpoints.ConfirmEndpoint,
+			decodeGRPCConfirmRequest,
+			encodeGRPCBookResponse,
+		),
+		modify: grpctransport.NewServer(
+			endpoints.ModifyEndpoint,
+			decodeGRPCModifyBookingRequest,
+			encodeGRPCBookResponse,
+		),
+		addBlackout: grpctransport.NewServer(
+			endpoints.AddBlackoutEndpoint,
+			decodeGRPCAddBlackoutRequest,
+			encodeGRPCAddBlackoutResponse,
+		),
+		blackouts: grpctransport.NewServer(
+			endpoints.BlackoutsEndpoint,
+			decodeGRPCListBlackoutsRequest,
+			encodeGRPCListBlackoutsResponse,
+		),
+		liftBlackout: grpctransport.NewServer(
+			endpoints.LiftBlackoutEndpoint,
+			decodeGRPCRemoveBlackoutRequest,
+			encodeGRPCRemoveBlackoutResponse,
+		),
+		history: grpctransport.NewServer(
+			endpoints.HistoryEndpoint,
+			decodeGRPCHistoryRequest,
+			encodeGRPCHistoryResponse,
+		),
+		snapshot: grpctransport.NewServer(
+			endpoints.SnapshotEndpoint,
+			decodeGRPCSnapshotRequest,
+			encodeGRPCSnapshotResponse,
+		),
+		addWebhook: grpctransport.NewServer(
+			endpoints.AddWebhookEndpoint,
+			decodeGRPCAddWebhookRequest,
+			encodeGRPCAddWebhookResponse,
+		),
+		webhooks: grpctransport.NewServer(
+			endpoints.WebhooksEndpoint,
+			decodeGRPCListWebhooksRequest,
+			encodeGRPCListWebhooksResponse,
+		),
+		removeWebhook: grpctransport.NewServer(
+			endpoints.RemoveWebhookEndpoint,
+			decodeGRPCRemoveWebhookRequest,
+			encodeGRPCRemoveWebhookResponse,
+		),
+		deadLetters: grpctransport.NewServer(
+			endpoints.DeadLettersEndpoint,
+			decodeGRPCDeadLettersRequest,
+			encodeGRPCDeadLettersResponse,
+		),
+		watch: endpoints.WatchEndpoint,
 	}
 }
 
@@ -52,14 +189,313 @@ func (s *GrpcServer) Check(ctx context.Context, req *pb.CheckRequest) (*pb.Check
 	return response, nil
 }
 
+func (s *GrpcServer) Cancel(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	_, resp, err := s.cancel.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.CancelResponse{}, err
+	}
+	response, ok := resp.(*pb.CancelResponse)
+	if !ok {
+		return &pb.CancelResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) Availability(ctx context.Context, req *pb.AvailabilityRequest) (*pb.AvailabilityResponse, error) {
+	_, resp, err := s.availability.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.AvailabilityResponse{}, err
+	}
+	response, ok := resp.(*pb.AvailabilityResponse)
+	if !ok {
+		return &pb.AvailabilityResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+// Sends the availability, then every update, until the client leaves
+// Errors are sent as the only message
+func (s *GrpcServer) WatchAvailability(req *pb.AvailabilityRequest, stream pb.Rooms_WatchAvailabilityServer) error {
+	ctx := stream.Context()
+	request, err := decodeGRPCAvailabilityRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp, err := s.watch(ctx, request)
+	if err != nil {
+		return err
+	}
+	response, ok := resp.(*WatchAvailabilityResponse)
+	if !ok {
+		return ErrInvalidResponseStructure()
+	}
+	if response.Err != nil {
+		return stream.Send(&pb.AvailabilityResponse{Error: err2str(response.Err)})
+	}
+	for days := range response.Updates {
+		update, err := encodeGRPCAvailabilityResponse(ctx, &AvailabilityResponse{Days: days})
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(update.(*pb.AvailabilityResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GrpcServer) ListRooms(ctx context.Context, req *pb.ListRoomsRequest) (*pb.ListRoomsResponse, error) {
+	_, resp, err := s.listRooms.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.ListRoomsResponse{}, err
+	}
+	response, ok := resp.(*pb.ListRoomsResponse)
+	if !ok {
+		return &pb.ListRoomsResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) CreateRoom(ctx context.Context, req *pb.RoomRequest) (*pb.RoomResponse, error) {
+	_, resp, err := s.createRoom.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.RoomResponse{}, err
+	}
+	response, ok := resp.(*pb.RoomResponse)
+	if !ok {
+		return &pb.RoomResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) UpdateRoom(ctx context.Context, req *pb.RoomRequest) (*pb.RoomResponse, error) {
+	_, resp, err := s.updateRoom.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.RoomResponse{}, err
+	}
+	response, ok := resp.(*pb.RoomResponse)
+	if !ok {
+		return &pb.RoomResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) DecommissionRoom(ctx context.Context, req *pb.DecommissionRequest) (*pb.DecommissionResponse, error) {
+	_, resp, err := s.decommission.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.DecommissionResponse{}, err
+	}
+	response, ok := resp.(*pb.DecommissionResponse)
+	if !ok {
+		return &pb.DecommissionResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) ListBookings(ctx context.Context, req *pb.ListBookingsRequest) (*pb.ListBookingsResponse, error) {
+	_, resp, err := s.listBookings.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.ListBookingsResponse{}, err
+	}
+	response, ok := resp.(*pb.ListBookingsResponse)
+	if !ok {
+		return &pb.ListBookingsResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) GetReservation(ctx context.Context, req *pb.GetReservationRequest) (*pb.GetReservationResponse, error) {
+	_, resp, err := s.reservation.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.GetReservationResponse{}, err
+	}
+	response, ok := resp.(*pb.GetReservationResponse)
+	if !ok {
+		return &pb.GetReservationResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) JoinWaitlist(ctx context.Context, req *pb.JoinWaitlistRequest) (*pb.WaitlistResponse, error) {
+	_, resp, err := s.joinWaitlist.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.WaitlistResponse{}, err
+	}
+	response, ok := resp.(*pb.WaitlistResponse)
+	if !ok {
+		return &pb.WaitlistResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) WaitlistPosition(ctx context.Context, req *pb.WaitlistPositionRequest) (*pb.WaitlistResponse, error) {
+	_, resp, err := s.waitlist.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.WaitlistResponse{}, err
+	}
+	response, ok := resp.(*pb.WaitlistResponse)
+	if !ok {
+		return &pb.WaitlistResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) Hold(ctx context.Context, req *pb.BookRequest) (*pb.BookResponse, error) {
+	_, resp, err := s.hold.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.BookResponse{}, err
+	}
+	response, ok := resp.(*pb.BookResponse)
+	if !ok {
+		return &pb.BookResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) Confirm(ctx context.Context, req *pb.ConfirmRequest) (*pb.BookResponse, error) {
+	_, resp, err := s.confirm.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.BookResponse{}, err
+	}
+	response, ok := resp.(*pb.BookResponse)
+	if !ok {
+		return &pb.BookResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) ModifyBooking(ctx context.Context, req *pb.ModifyBookingRequest) (*pb.BookResponse, error) {
+	_, resp, err := s.modify.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.BookResponse{}, err
+	}
+	response, ok := resp.(*pb.BookResponse)
+	if !ok {
+		return &pb.BookResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) AddBlackout(ctx context.Context, req *pb.AddBlackoutRequest) (*pb.AddBlackoutResponse, error) {
+	_, resp, err := s.addBlackout.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.AddBlackoutResponse{}, err
+	}
+	response, ok := resp.(*pb.AddBlackoutResponse)
+	if !ok {
+		return &pb.AddBlackoutResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) ListBlackouts(ctx context.Context, req *pb.ListBlackoutsRequest) (*pb.ListBlackoutsResponse, error) {
+	_, resp, err := s.blackouts.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.ListBlackoutsResponse{}, err
+	}
+	response, ok := resp.(*pb.ListBlackoutsResponse)
+	if !ok {
+		return &pb.ListBlackoutsResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) History(ctx context.Context, req *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	_, resp, err := s.history.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.HistoryResponse{}, err
+	}
+	response, ok := resp.(*pb.HistoryResponse)
+	if !ok {
+		return &pb.HistoryResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) Snapshot(ctx context.Context, req *pb.SnapshotRequest) (*pb.SnapshotResponse, error) {
+	_, resp, err := s.snapshot.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.SnapshotResponse{}, err
+	}
+	response, ok := resp.(*pb.SnapshotResponse)
+	if !ok {
+		return &pb.SnapshotResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) AddWebhook(ctx context.Context, req *pb.AddWebhookRequest) (*pb.AddWebhookResponse, error) {
+	_, resp, err := s.addWebhook.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.AddWebhookResponse{}, err
+	}
+	response, ok := resp.(*pb.AddWebhookResponse)
+	if !ok {
+		return &pb.AddWebhookResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	_, resp, err := s.webhooks.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.ListWebhooksResponse{}, err
+	}
+	response, ok := resp.(*pb.ListWebhooksResponse)
+	if !ok {
+		return &pb.ListWebhooksResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) RemoveWebhook(ctx context.Context, req *pb.RemoveWebhookRequest) (*pb.RemoveWebhookResponse, error) {
+	_, resp, err := s.removeWebhook.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.RemoveWebhookResponse{}, err
+	}
+	response, ok := resp.(*pb.RemoveWebhookResponse)
+	if !ok {
+		return &pb.RemoveWebhookResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) DeadLetters(ctx context.Context, req *pb.DeadLettersRequest) (*pb.DeadLettersResponse, error) {
+	_, resp, err := s.deadLetters.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.DeadLettersResponse{}, err
+	}
+	response, ok := resp.(*pb.DeadLettersResponse)
+	if !ok {
+		return &pb.DeadLettersResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
+func (s *GrpcServer) RemoveBlackout(ctx context.Context, req *pb.RemoveBlackoutRequest) (*pb.RemoveBlackoutResponse, error) {
+	_, resp, err := s.liftBlackout.ServeGRPC(ctx, req)
+	if err != nil {
+		return &pb.RemoveBlackoutResponse{}, err
+	}
+	response, ok := resp.(*pb.RemoveBlackoutResponse)
+	if !ok {
+		return &pb.RemoveBlackoutResponse{}, ErrInvalidResponseStructure()
+	}
+	return response, nil
+}
+
 func decodeGRPCBookRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
 	req, ok := grpcReq.(*pb.BookRequest)
 	if !ok {
 		return &BookRequest{}, ErrInvalidRequestStructure()
 	}
 	return &BookRequest{
-		Token: req.Token,
-		Date:  time.Unix(req.Date, 0).UTC(),
+		Token:          req.Token,
+		IdempotencyKey: req.IdempotencyKey,
+		Count:          int(req.Count),
+		From:           time.Unix(req.From, 0).UTC(),
+		To:             time.Unix(req.To, 0).UTC(),
+		Filter:         decodeGRPCRoomFilter(req.Filter),
 	}, nil
 }
 
@@ -69,18 +505,259 @@ func decodeGRPCCheckRequest(ctx context.Context, grpcReq interface{}) (interface
 		return &CheckRequest{}, ErrInvalidRequestStructure()
 	}
 	return &CheckRequest{
-		Date: time.Unix(req.Date, 0).UTC(),
+		Date:   time.Unix(req.Date, 0).UTC(),
+		Filter: decodeGRPCRoomFilter(req.Filter),
+	}, nil
+}
+
+func decodeGRPCCancelRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.CancelRequest)
+	if !ok {
+		return &CancelRequest{}, ErrInvalidRequestStructure()
+	}
+	return &CancelRequest{
+		Token:   req.Token,
+		Id:      req.Id,
+		Version: int(req.Version),
+	}, nil
+}
+
+func decodeGRPCAvailabilityRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.AvailabilityRequest)
+	if !ok {
+		return &AvailabilityRequest{}, ErrInvalidRequestStructure()
+	}
+	return &AvailabilityRequest{
+		From:   time.Unix(req.From, 0).UTC(),
+		To:     time.Unix(req.To, 0).UTC(),
+		Filter: decodeGRPCRoomFilter(req.Filter),
+	}, nil
+}
+
+func decodeGRPCRoomFilter(filter *pb.RoomFilter) RoomFilter {
+	return RoomFilter{
+		Type:        filter.GetType(),
+		MinCapacity: int(filter.GetMinCapacity()),
+		Amenities:   filter.GetAmenities(),
+	}
+}
+
+func decodeGRPCListRoomsRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	_, ok := grpcReq.(*pb.ListRoomsRequest)
+	if !ok {
+		return &ListRoomsRequest{}, ErrInvalidRequestStructure()
+	}
+	return &ListRoomsRequest{}, nil
+}
+
+func decodeGRPCRoomRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.RoomRequest)
+	if !ok {
+		return &RoomRequest{}, ErrInvalidRequestStructure()
+	}
+	return &RoomRequest{
+		Token: req.Token,
+		Room:  decodeGRPCRoomInfo(req.Room),
+	}, nil
+}
+
+func decodeGRPCDecommissionRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.DecommissionRequest)
+	if !ok {
+		return &DecommissionRequest{}, ErrInvalidRequestStructure()
+	}
+	return &DecommissionRequest{
+		Token: req.Token,
+		Id:    int(req.Id),
+	}, nil
+}
+
+func decodeGRPCListBookingsRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.ListBookingsRequest)
+	if !ok {
+		return &ListBookingsRequest{}, ErrInvalidRequestStructure()
+	}
+	return &ListBookingsRequest{
+		Token: req.Token,
+		From:  time.Unix(req.From, 0).UTC(),
+		To:    time.Unix(req.To, 0).UTC(),
+	}, nil
+}
+
+func decodeGRPCGetReservationRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.GetReservationRequest)
+	if !ok {
+		return &GetReservationRequest{}, ErrInvalidRequestStructure()
+	}
+	return &GetReservationRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCJoinWaitlistRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.JoinWaitlistRequest)
+	if !ok {
+		return &JoinWaitlistRequest{}, ErrInvalidRequestStructure()
+	}
+	return &JoinWaitlistRequest{
+		Token:  req.Token,
+		From:   time.Unix(req.From, 0).UTC(),
+		To:     time.Unix(req.To, 0).UTC(),
+		Filter: decodeGRPCRoomFilter(req.Filter),
 	}, nil
 }
 
+func decodeGRPCWaitlistPositionRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.WaitlistPositionRequest)
+	if !ok {
+		return &WaitlistPositionRequest{}, ErrInvalidRequestStructure()
+	}
+	return &WaitlistPositionRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCConfirmRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.ConfirmRequest)
+	if !ok {
+		return &ConfirmRequest{}, ErrInvalidRequestStructure()
+	}
+	return &ConfirmRequest{
+		Token:   req.Token,
+		Id:      req.Id,
+		Version: int(req.Version),
+	}, nil
+}
+
+func decodeGRPCModifyBookingRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.ModifyBookingRequest)
+	if !ok {
+		return &ModifyBookingRequest{}, ErrInvalidRequestStructure()
+	}
+	return &ModifyBookingRequest{
+		Token:   req.Token,
+		Id:      req.Id,
+		From:    time.Unix(req.From, 0).UTC(),
+		To:      time.Unix(req.To, 0).UTC(),
+		Room:    int(req.Room),
+		Version: int(req.Version),
+	}, nil
+}
+
+func decodeGRPCAddBlackoutRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.AddBlackoutRequest)
+	if !ok {
+		return &AddBlackoutRequest{}, ErrInvalidRequestStructure()
+	}
+	return &AddBlackoutRequest{
+		Token:    req.Token,
+		Blackout: decodeGRPCBlackout(req.Blackout),
+	}, nil
+}
+
+func decodeGRPCListBlackoutsRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.ListBlackoutsRequest)
+	if !ok {
+		return &ListBlackoutsRequest{}, ErrInvalidRequestStructure()
+	}
+	return &ListBlackoutsRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCRemoveBlackoutRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.RemoveBlackoutRequest)
+	if !ok {
+		return &RemoveBlackoutRequest{}, ErrInvalidRequestStructure()
+	}
+	return &RemoveBlackoutRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCHistoryRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.HistoryRequest)
+	if !ok {
+		return &HistoryRequest{}, ErrInvalidRequestStructure()
+	}
+	return &HistoryRequest{
+		Token: req.Token,
+		Id:    req.Id,
+		Room:  int(req.Room),
+	}, nil
+}
+
+func decodeGRPCSnapshotRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.SnapshotRequest)
+	if !ok {
+		return &SnapshotRequest{}, ErrInvalidRequestStructure()
+	}
+	return &SnapshotRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCAddWebhookRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.AddWebhookRequest)
+	if !ok {
+		return &AddWebhookRequest{}, ErrInvalidRequestStructure()
+	}
+	return &AddWebhookRequest{
+		Token:   req.Token,
+		Webhook: decodeGRPCWebhook(req.Webhook),
+	}, nil
+}
+
+func decodeGRPCListWebhooksRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.ListWebhooksRequest)
+	if !ok {
+		return &ListWebhooksRequest{}, ErrInvalidRequestStructure()
+	}
+	return &ListWebhooksRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCRemoveWebhookRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.RemoveWebhookRequest)
+	if !ok {
+		return &RemoveWebhookRequest{}, ErrInvalidRequestStructure()
+	}
+	return &RemoveWebhookRequest{
+		Token: req.Token,
+		Id:    req.Id,
+	}, nil
+}
+
+func decodeGRPCDeadLettersRequest(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+	req, ok := grpcReq.(*pb.DeadLettersRequest)
+	if !ok {
+		return &DeadLettersRequest{}, ErrInvalidRequestStructure()
+	}
+	return &DeadLettersRequest{Token: req.Token}, nil
+}
+
+func decodeGRPCRoomInfo(room *pb.RoomInfo) RoomInfo {
+	return RoomInfo{
+		Id:        int(room.GetId()),
+		Name:      room.GetName(),
+		Type:      room.GetType(),
+		Capacity:  int(room.GetCapacity()),
+		Amenities: room.GetAmenities(),
+	}
+}
+
 func encodeGRPCBookResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp, ok := response.(*BookResponse)
 	if !ok {
 		return &pb.BookResponse{}, ErrInvalidResponseStructure()
 	}
+	var reservations []*pb.Reservation
+	for _, reservation := range resp.Reservations {
+		reservations = append(reservations, encodeGRPCReservation(reservation))
+	}
 	return &pb.BookResponse{
-		Id:    int64(resp.Id),
-		Error: err2str(resp.Err),
+		Reservation:  encodeGRPCReservation(resp.Reservation),
+		Reservations: reservations,
+		Error:        err2str(resp.Err),
+		Reason:       err2reason(resp.Err),
 	}, nil
 }
 
@@ -95,9 +772,331 @@ func encodeGRPCCheckResponse(_ context.Context, response interface{}) (interface
 	}, nil
 }
 
+func encodeGRPCCancelResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*CancelResponse)
+	if !ok {
+		return &pb.CancelResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.CancelResponse{
+		Error: err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCAvailabilityResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*AvailabilityResponse)
+	if !ok {
+		return &pb.AvailabilityResponse{}, ErrInvalidResponseStructure()
+	}
+	days := make([]*pb.DayAvailability, len(resp.Days))
+	for i, day := range resp.Days {
+		days[i] = &pb.DayAvailability{
+			Date:      day.Date.Unix(),
+			Available: int64(day.Available),
+		}
+	}
+	return &pb.AvailabilityResponse{
+		Days:  days,
+		Error: err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCListRoomsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*ListRoomsResponse)
+	if !ok {
+		return &pb.ListRoomsResponse{}, ErrInvalidResponseStructure()
+	}
+	rooms := make([]*pb.RoomInfo, len(resp.Rooms))
+	for i, room := range resp.Rooms {
+		rooms[i] = encodeGRPCRoomInfo(room)
+	}
+	return &pb.ListRoomsResponse{
+		Rooms: rooms,
+		Error: err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCRoomResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*RoomResponse)
+	if !ok {
+		return &pb.RoomResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.RoomResponse{
+		Room:  encodeGRPCRoomInfo(resp.Room),
+		Error: err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCDecommissionResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*DecommissionResponse)
+	if !ok {
+		return &pb.DecommissionResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.DecommissionResponse{
+		Error: err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCListBookingsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*ListBookingsResponse)
+	if !ok {
+		return &pb.ListBookingsResponse{}, ErrInvalidResponseStructure()
+	}
+	bookings := make([]*pb.Booking, len(resp.Bookings))
+	for i, booking := range resp.Bookings {
+		bookings[i] = &pb.Booking{
+			Id:   booking.Id,
+			Code: booking.Code,
+			Room: int64(booking.Room),
+			Date: booking.Date.Unix(),
+		}
+	}
+	return &pb.ListBookingsResponse{
+		Bookings: bookings,
+		Error:    err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCGetReservationResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*GetReservationResponse)
+	if !ok {
+		return &pb.GetReservationResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.GetReservationResponse{
+		Reservation: encodeGRPCReservation(resp.Reservation),
+		Error:       err2str(resp.Err),
+	}, nil
+}
+
+// The zero reservation (returned along errors) is encoded as nil
+// and the expiry of confirmed reservations as 0
+func encodeGRPCReservation(reservation Reservation) *pb.Reservation {
+	if reservation.Id == "" {
+		return nil
+	}
+	var expires int64
+	if reservation.Held() {
+		expires = reservation.Expires.Unix()
+	}
+	return &pb.Reservation{
+		Id:      reservation.Id,
+		Code:    reservation.Code,
+		User:    reservation.User,
+		Room:    int64(reservation.Room),
+		From:    reservation.From.Unix(),
+		To:      reservation.To.Unix(),
+		Expires: expires,
+		Version: int64(reservation.Version),
+	}
+}
+
+func encodeGRPCWaitlistResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*WaitlistResponse)
+	if !ok {
+		return &pb.WaitlistResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.WaitlistResponse{
+		Entry:  encodeGRPCWaitlistEntry(resp.Entry),
+		Error:  err2str(resp.Err),
+		Reason: err2reason(resp.Err),
+	}, nil
+}
+
+// The zero entry (returned along errors) is encoded as nil
+func encodeGRPCWaitlistEntry(entry WaitlistEntry) *pb.WaitlistEntry {
+	if entry.Id == "" {
+		return nil
+	}
+	return &pb.WaitlistEntry{
+		Id:          entry.Id,
+		User:        entry.User,
+		From:        entry.From.Unix(),
+		To:          entry.To.Unix(),
+		Filter:      encodeGRPCRoomFilter(entry.Filter),
+		Position:    int64(entry.Position),
+		Reservation: entry.Reservation,
+		Rejected:    entry.Rejected,
+	}
+}
+
+func encodeGRPCAddBlackoutResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*AddBlackoutResponse)
+	if !ok {
+		return &pb.AddBlackoutResponse{}, ErrInvalidResponseStructure()
+	}
+	var conflicts []*pb.Reservation
+	for _, reservation := range resp.Conflicts {
+		conflicts = append(conflicts, encodeGRPCReservation(reservation))
+	}
+	return &pb.AddBlackoutResponse{
+		Blackout:  encodeGRPCBlackout(resp.Blackout),
+		Conflicts: conflicts,
+		Error:     err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCListBlackoutsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*ListBlackoutsResponse)
+	if !ok {
+		return &pb.ListBlackoutsResponse{}, ErrInvalidResponseStructure()
+	}
+	blackouts := make([]*pb.Blackout, len(resp.Blackouts))
+	for i, blackout := range resp.Blackouts {
+		blackouts[i] = encodeGRPCBlackout(blackout)
+	}
+	return &pb.ListBlackoutsResponse{
+		Blackouts: blackouts,
+		Error:     err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCRemoveBlackoutResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*RemoveBlackoutResponse)
+	if !ok {
+		return &pb.RemoveBlackoutResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.RemoveBlackoutResponse{
+		Error: err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCBlackout(blackout Blackout) *pb.Blackout {
+	return &pb.Blackout{
+		Id:     blackout.Id,
+		Room:   int64(blackout.Room),
+		From:   blackout.From.Unix(),
+		To:     blackout.To.Unix(),
+		Reason: blackout.Reason,
+	}
+}
+
+func encodeGRPCHistoryResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*HistoryResponse)
+	if !ok {
+		return &pb.HistoryResponse{}, ErrInvalidResponseStructure()
+	}
+	events := make([]*pb.Event, len(resp.Events))
+	for i, event := range resp.Events {
+		events[i] = encodeGRPCEvent(event)
+	}
+	return &pb.HistoryResponse{
+		Events: events,
+		Error:  err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCEvent(event Event) *pb.Event {
+	return &pb.Event{
+		Seq:         event.Seq,
+		Type:        event.Type,
+		Time:        event.Time.Unix(),
+		Reservation: encodeGRPCReservation(event.Reservation),
+		Previous:    encodeGRPCReservation(event.Previous),
+	}
+}
+
+// The snapshot is sent in the file format so it is not limited to the fields of the messages
+func encodeGRPCSnapshotResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*SnapshotResponse)
+	if !ok {
+		return &pb.SnapshotResponse{}, ErrInvalidResponseStructure()
+	}
+	if resp.Err != nil {
+		return &pb.SnapshotResponse{Error: err2str(resp.Err)}, nil
+	}
+	var snapshot bytes.Buffer
+	if err := WriteSnapshot(&snapshot, resp.Snapshot); err != nil {
+		return &pb.SnapshotResponse{}, err
+	}
+	return &pb.SnapshotResponse{Snapshot: snapshot.Bytes()}, nil
+}
+
+func encodeGRPCRoomInfo(room RoomInfo) *pb.RoomInfo {
+	return &pb.RoomInfo{
+		Id:        int64(room.Id),
+		Name:      room.Name,
+		Type:      room.Type,
+		Capacity:  int64(room.Capacity),
+		Amenities: room.Amenities,
+	}
+}
+
 func err2str(err error) string {
 	if err == nil {
 		return ""
 	}
 	return err.Error()
 }
+
+// Returns the reason of a rule violation, empty for other errors
+func err2reason(err error) string {
+	if violation, ok := err.(RuleViolation); ok {
+		return violation.Reason
+	}
+	return ""
+}
+
+func encodeGRPCAddWebhookResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*AddWebhookResponse)
+	if !ok {
+		return &pb.AddWebhookResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.AddWebhookResponse{
+		Webhook: encodeGRPCWebhook(resp.Webhook),
+		Error:   err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCListWebhooksResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*ListWebhooksResponse)
+	if !ok {
+		return &pb.ListWebhooksResponse{}, ErrInvalidResponseStructure()
+	}
+	webhooks := make([]*pb.Webhook, len(resp.Webhooks))
+	for i, webhook := range resp.Webhooks {
+		webhooks[i] = encodeGRPCWebhook(webhook)
+	}
+	return &pb.ListWebhooksResponse{
+		Webhooks: webhooks,
+		Error:    err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCRemoveWebhookResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*RemoveWebhookResponse)
+	if !ok {
+		return &pb.RemoveWebhookResponse{}, ErrInvalidResponseStructure()
+	}
+	return &pb.RemoveWebhookResponse{Error: err2str(resp.Err)}, nil
+}
+
+func encodeGRPCDeadLettersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp, ok := response.(*DeadLettersResponse)
+	if !ok {
+		return &pb.DeadLettersResponse{}, ErrInvalidResponseStructure()
+	}
+	deadLetters := make([]*pb.DeadLetter, len(resp.DeadLetters))
+	for i, deadLetter := range resp.DeadLetters {
+		deadLetters[i] = &pb.DeadLetter{
+			Id:       deadLetter.Payload.Id,
+			Webhook:  deadLetter.Payload.Webhook,
+			Event:    encodeGRPCEvent(deadLetter.Payload.Event),
+			Url:      deadLetter.Url,
+			Attempts: int64(deadLetter.Attempts),
+			Error:    deadLetter.Error,
+			Failed:   deadLetter.Failed.Unix(),
+		}
+	}
+	return &pb.DeadLettersResponse{
+		DeadLetters: deadLetters,
+		Error:       err2str(resp.Err),
+	}, nil
+}
+
+func encodeGRPCWebhook(webhook Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:     webhook.Id,
+		Url:    webhook.Url,
+		Secret: webhook.Secret,
+		Events: webhook.Events,
+	}
+}
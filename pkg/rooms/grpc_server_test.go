@@ -12,7 +12,7 @@ import (
 type grpcBookCorrectMock struct{}
 
 func (b grpcBookCorrectMock) ServeGRPC(ctx context.Context, request interface{}) (context.Context, interface{}, error) {
-	return ctx, &pb.BookResponse{Id: 1, Error: ""}, nil
+	return ctx, &pb.BookResponse{Reservation: testPBReservation, Error: ""}, nil
 }
 
 type grpcCheckCorrectMock struct{}
@@ -45,6 +45,24 @@ func (a grpcCheckErrorMock) ServeGRPC(ctx context.Context, request interface{})
 	return ctx, &pb.CheckResponse{}, ErrNoRoomAvailable()
 }
 
+type grpcCancelCorrectMock struct{}
+
+func (a grpcCancelCorrectMock) ServeGRPC(ctx context.Context, request interface{}) (context.Context, interface{}, error) {
+	return ctx, &pb.CancelResponse{Error: ""}, nil
+}
+
+type grpcCancelWrongResponseMock struct{}
+
+func (a grpcCancelWrongResponseMock) ServeGRPC(ctx context.Context, request interface{}) (context.Context, interface{}, error) {
+	return ctx, "", nil
+}
+
+type grpcCancelErrorMock struct{}
+
+func (a grpcCancelErrorMock) ServeGRPC(ctx context.Context, request interface{}) (context.Context, interface{}, error) {
+	return ctx, &pb.CancelResponse{}, ErrBookingNotFound()
+}
+
 var grpcServerBookTest = []struct {
 	name    string
 	server  *GrpcServer
@@ -53,13 +71,13 @@ var grpcServerBookTest = []struct {
 	err     error
 }{
 	{
-		name: "should return the booked room id",
+		name: "should return the reservation",
 		server: &GrpcServer{
 			book:  grpcBookCorrectMock{},
 			check: grpcCheckCorrectMock{},
 		},
 		request: &pb.BookRequest{},
-		want:    &pb.BookResponse{Id: 1, Error: ""},
+		want:    &pb.BookResponse{Reservation: testPBReservation, Error: ""},
 	},
 	{
 		name: "should return en error if ServeGRPC returns an error",
@@ -147,6 +165,54 @@ func TestGRPCServerCheck(t *testing.T) {
 	}
 }
 
+var grpcServerCancelTest = []struct {
+	name    string
+	server  *GrpcServer
+	request *pb.CancelRequest
+	want    *pb.CancelResponse
+	err     error
+}{
+	{
+		name: "should return an empty response",
+		server: &GrpcServer{
+			cancel: grpcCancelCorrectMock{},
+		},
+		request: &pb.CancelRequest{},
+		want:    &pb.CancelResponse{Error: ""},
+	},
+	{
+		name: "should return en error if ServeGRPC returns an error",
+		server: &GrpcServer{
+			cancel: grpcCancelErrorMock{},
+		},
+		request: &pb.CancelRequest{},
+		want:    &pb.CancelResponse{},
+		err:     ErrBookingNotFound(),
+	},
+	{
+		name: "should return en error if the ServeGRPC response is the wrong type",
+		server: &GrpcServer{
+			cancel: grpcCancelWrongResponseMock{},
+		},
+		request: &pb.CancelRequest{},
+		want:    &pb.CancelResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestGRPCServerCancel(t *testing.T) {
+	t.Log("GRPCServerCancel")
+
+	for _, testcase := range grpcServerCancelTest {
+		t.Logf(testcase.name)
+
+		result, err := testcase.server.Cancel(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
 var decodeGRPCBookRequestTest = []struct {
 	name    string
 	request interface{}
@@ -155,8 +221,23 @@ var decodeGRPCBookRequestTest = []struct {
 }{
 	{
 		name:    "should return values in the internal structure",
-		request: &pb.BookRequest{Token: "jjj.www.ttt", Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC).Unix()},
-		want:    &BookRequest{Token: "jjj.www.ttt", Date: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)},
+		request: &pb.BookRequest{Token: "jjj.www.ttt", From: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC).Unix(), To: time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC).Unix()},
+		want:    &BookRequest{Token: "jjj.www.ttt", From: time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), To: time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)},
+	},
+	{
+		name:    "should return the filter in the internal structure",
+		request: &pb.BookRequest{Filter: &pb.RoomFilter{Type: DoubleRoom, MinCapacity: 2, Amenities: []string{"balcony"}}},
+		want:    &BookRequest{From: time.Unix(0, 0).UTC(), To: time.Unix(0, 0).UTC(), Filter: RoomFilter{Type: DoubleRoom, MinCapacity: 2, Amenities: []string{"balcony"}}},
+	},
+	{
+		name:    "should return the idempotency key in the internal structure",
+		request: &pb.BookRequest{Token: "jjj.www.ttt", IdempotencyKey: "retry-1"},
+		want:    &BookRequest{Token: "jjj.www.ttt", IdempotencyKey: "retry-1", From: time.Unix(0, 0).UTC(), To: time.Unix(0, 0).UTC()},
+	},
+	{
+		name:    "should return the room count",
+		request: &pb.BookRequest{Token: "jjj.www.ttt", Count: 3},
+		want:    &BookRequest{Token: "jjj.www.ttt", Count: 3, From: time.Unix(0, 0).UTC(), To: time.Unix(0, 0).UTC()},
 	},
 	{
 		name:    "should return an error if the request has the wrong structure",
@@ -219,8 +300,28 @@ var encodeGRPCBookResponseTest = []struct {
 }{
 	{
 		name:    "should return the pb structure with the id",
-		request: &BookResponse{Id: 1, Err: nil},
-		want:    &pb.BookResponse{Id: 1, Error: ""},
+		request: &BookResponse{Reservation: testReservation, Err: nil},
+		want:    &pb.BookResponse{Reservation: testPBReservation, Error: ""},
+	},
+	{
+		name:    "should return the pb structure with the expiry of a hold",
+		request: &BookResponse{Reservation: testHold},
+		want:    &pb.BookResponse{Reservation: testPBHold},
+	},
+	{
+		name:    "should return the pb structure with every reservation of a group",
+		request: &BookResponse{Reservation: testReservation, Reservations: []Reservation{testReservation, testHold}},
+		want:    &pb.BookResponse{Reservation: testPBReservation, Reservations: []*pb.Reservation{testPBReservation, testPBHold}},
+	},
+	{
+		name:    "should return the pb structure without reservation if there is an error",
+		request: &BookResponse{Err: ErrNoRoomAvailable()},
+		want:    &pb.BookResponse{Error: NoRoomAvailable},
+	},
+	{
+		name:    "should return the pb structure with the reason of a rule violation",
+		request: &BookResponse{Err: RuleViolation{ReasonMaxStay, "Stays must be at most 14 nights"}},
+		want:    &pb.BookResponse{Error: "Stays must be at most 14 nights", Reason: ReasonMaxStay},
 	},
 	{
 		name:    "should return an error if the request has the wrong structure",
@@ -274,3 +375,550 @@ func TestEncodeGRPCCheckResponse(t *testing.T) {
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
+
+var decodeGRPCCancelRequestTest = []struct {
+	name    string
+	request interface{}
+	want    *CancelRequest
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.CancelRequest{Token: "jjj.www.ttt", Id: "1-2020-06-13"},
+		want:    &CancelRequest{Token: "jjj.www.ttt", Id: "1-2020-06-13"},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "jjj.www.ttt",
+		want:    &CancelRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCCancelRequest(t *testing.T) {
+	t.Log("decodeGRPCCancelRequest")
+
+	for _, testcase := range decodeGRPCCancelRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCCancelRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCCancelResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.CancelResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with the error",
+		request: &CancelResponse{Err: ErrNotBookingOwner()},
+		want:    &pb.CancelResponse{Error: NotBookingOwner},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.CancelResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCCancelResponse(t *testing.T) {
+	t.Log("encodeGRPCCancelResponse")
+
+	for _, testcase := range encodeGRPCCancelResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCCancelResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCAvailabilityRequestTest = []struct {
+	name    string
+	request interface{}
+	want    *AvailabilityRequest
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.AvailabilityRequest{From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(), To: time.Date(2020, 6, 20, 0, 0, 0, 0, time.UTC).Unix()},
+		want:    &AvailabilityRequest{From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), To: time.Date(2020, 6, 20, 0, 0, 0, 0, time.UTC)},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "2020-06-13",
+		want:    &AvailabilityRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCAvailabilityRequest(t *testing.T) {
+	t.Log("decodeGRPCAvailabilityRequest")
+
+	for _, testcase := range decodeGRPCAvailabilityRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCAvailabilityRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCAvailabilityResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.AvailabilityResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with every day",
+		request: &AvailabilityResponse{Days: []DayAvailability{{time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 5}}},
+		want:    &pb.AvailabilityResponse{Days: []*pb.DayAvailability{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(), Available: 5}}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 5,
+		want:    &pb.AvailabilityResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCAvailabilityResponse(t *testing.T) {
+	t.Log("encodeGRPCAvailabilityResponse")
+
+	for _, testcase := range encodeGRPCAvailabilityResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCAvailabilityResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCRoomRequestTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.RoomRequest{Token: "jjj.www.ttt", Room: &pb.RoomInfo{Id: 3, Name: "301", Type: SuiteRoom, Capacity: 4, Amenities: []string{"balcony"}}},
+		want:    &RoomRequest{Token: "jjj.www.ttt", Room: RoomInfo{Id: 3, Name: "301", Type: SuiteRoom, Capacity: 4, Amenities: []string{"balcony"}}},
+	},
+	{
+		name:    "should return an empty room if it is missing",
+		request: &pb.RoomRequest{Token: "jjj.www.ttt"},
+		want:    &RoomRequest{Token: "jjj.www.ttt"},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "jjj.www.ttt",
+		want:    &RoomRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCRoomRequest(t *testing.T) {
+	t.Log("decodeGRPCRoomRequest")
+
+	for _, testcase := range decodeGRPCRoomRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCRoomRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCListRoomsResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.ListRoomsResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with every room",
+		request: &ListRoomsResponse{Rooms: []RoomInfo{{Id: 1, Name: "101", Type: SingleRoom, Capacity: 1}}},
+		want:    &pb.ListRoomsResponse{Rooms: []*pb.RoomInfo{{Id: 1, Name: "101", Type: SingleRoom, Capacity: 1}}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.ListRoomsResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCListRoomsResponse(t *testing.T) {
+	t.Log("encodeGRPCListRoomsResponse")
+
+	for _, testcase := range encodeGRPCListRoomsResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCListRoomsResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCDecommissionResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.DecommissionResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with the error",
+		request: &DecommissionResponse{Err: ErrRoomHasBookings()},
+		want:    &pb.DecommissionResponse{Error: RoomHasBookings},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.DecommissionResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCDecommissionResponse(t *testing.T) {
+	t.Log("encodeGRPCDecommissionResponse")
+
+	for _, testcase := range encodeGRPCDecommissionResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCDecommissionResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCListBookingsResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.ListBookingsResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with every booking",
+		request: &ListBookingsResponse{Bookings: []Booking{{testReservation.Id, testReservation.Code, 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)}}},
+		want:    &pb.ListBookingsResponse{Bookings: []*pb.Booking{{Id: testReservation.Id, Code: testReservation.Code, Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix()}}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.ListBookingsResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCListBookingsResponse(t *testing.T) {
+	t.Log("encodeGRPCListBookingsResponse")
+
+	for _, testcase := range encodeGRPCListBookingsResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCListBookingsResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCGetReservationRequestTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.GetReservationRequest{Token: "jjj.www.ttt", Id: "K7QX9M"},
+		want:    &GetReservationRequest{Token: "jjj.www.ttt", Id: "K7QX9M"},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "K7QX9M",
+		want:    &GetReservationRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCGetReservationRequest(t *testing.T) {
+	t.Log("decodeGRPCGetReservationRequest")
+
+	for _, testcase := range decodeGRPCGetReservationRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCGetReservationRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCModifyBookingRequestTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.ModifyBookingRequest{Token: "jjj.www.ttt", Id: "K7QX9M", From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(), To: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC).Unix(), Room: 2},
+		want:    &ModifyBookingRequest{Token: "jjj.www.ttt", Id: "K7QX9M", From: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), To: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), Room: 2},
+	},
+	{
+		name:    "should keep dates left out as zero",
+		request: &pb.ModifyBookingRequest{Token: "jjj.www.ttt", Id: "K7QX9M", From: time.Time{}.Unix(), To: time.Time{}.Unix(), Room: 2},
+		want:    &ModifyBookingRequest{Token: "jjj.www.ttt", Id: "K7QX9M", Room: 2},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: "K7QX9M",
+		want:    &ModifyBookingRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCModifyBookingRequest(t *testing.T) {
+	t.Log("decodeGRPCModifyBookingRequest")
+
+	for _, testcase := range decodeGRPCModifyBookingRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCModifyBookingRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCGetReservationResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.GetReservationResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with the reservation",
+		request: &GetReservationResponse{Reservation: testReservation},
+		want:    &pb.GetReservationResponse{Reservation: testPBReservation},
+	},
+	{
+		name:    "should return the pb structure with the error",
+		request: &GetReservationResponse{Err: ErrNotBookingOwner()},
+		want:    &pb.GetReservationResponse{Error: NotBookingOwner},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.GetReservationResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCGetReservationResponse(t *testing.T) {
+	t.Log("encodeGRPCGetReservationResponse")
+
+	for _, testcase := range encodeGRPCGetReservationResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCGetReservationResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCAddBlackoutRequestTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.AddBlackoutRequest{Token: "jjj.www.ttt", Blackout: testPBBlackout},
+		want:    &AddBlackoutRequest{Token: "jjj.www.ttt", Blackout: testBlackout},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: testPBBlackout,
+		want:    &AddBlackoutRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCAddBlackoutRequest(t *testing.T) {
+	t.Log("decodeGRPCAddBlackoutRequest")
+
+	for _, testcase := range decodeGRPCAddBlackoutRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCAddBlackoutRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCAddBlackoutResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.AddBlackoutResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with the blackout and its conflicts",
+		request: &AddBlackoutResponse{Blackout: testBlackout, Conflicts: []Reservation{testReservation}},
+		want:    &pb.AddBlackoutResponse{Blackout: testPBBlackout, Conflicts: []*pb.Reservation{testPBReservation}},
+	},
+	{
+		name:    "should return the pb structure with the error",
+		request: &AddBlackoutResponse{Err: ErrRoomNotFound()},
+		want:    &pb.AddBlackoutResponse{Blackout: encodeGRPCBlackout(Blackout{}), Error: RoomNotFound},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.AddBlackoutResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCAddBlackoutResponse(t *testing.T) {
+	t.Log("encodeGRPCAddBlackoutResponse")
+
+	for _, testcase := range encodeGRPCAddBlackoutResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCAddBlackoutResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCHistoryResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.HistoryResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with the events",
+		request: &HistoryResponse{Events: []Event{testEvent}},
+		want:    &pb.HistoryResponse{Events: []*pb.Event{testPBEvent}},
+	},
+	{
+		name:    "should return the pb structure with the error",
+		request: &HistoryResponse{Err: ErrHistoryNotRecorded()},
+		want:    &pb.HistoryResponse{Events: []*pb.Event{}, Error: HistoryNotRecorded},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.HistoryResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCHistoryResponse(t *testing.T) {
+	t.Log("encodeGRPCHistoryResponse")
+
+	for _, testcase := range encodeGRPCHistoryResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCHistoryResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var decodeGRPCAddWebhookRequestTest = []struct {
+	name    string
+	request interface{}
+	want    interface{}
+	err     error
+}{
+	{
+		name:    "should return values in the internal structure",
+		request: &pb.AddWebhookRequest{Token: "jjj.www.ttt", Webhook: testPBWebhook},
+		want:    &AddWebhookRequest{Token: "jjj.www.ttt", Webhook: testWebhook},
+	},
+	{
+		name:    "should return a webhook for every event if it has no event types",
+		request: &pb.AddWebhookRequest{Token: "jjj.www.ttt", Webhook: &pb.Webhook{Url: testWebhook.Url}},
+		want:    &AddWebhookRequest{Token: "jjj.www.ttt", Webhook: Webhook{Url: testWebhook.Url, Events: []string{}}},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: testPBWebhook,
+		want:    &AddWebhookRequest{},
+		err:     ErrInvalidRequestStructure(),
+	},
+}
+
+func TestDecodeGRPCAddWebhookRequest(t *testing.T) {
+	t.Log("decodeGRPCAddWebhookRequest")
+
+	for _, testcase := range decodeGRPCAddWebhookRequestTest {
+		t.Logf(testcase.name)
+
+		result, err := decodeGRPCAddWebhookRequest(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var encodeGRPCDeadLettersResponseTest = []struct {
+	name    string
+	request interface{}
+	want    *pb.DeadLettersResponse
+	err     error
+}{
+	{
+		name:    "should return the pb structure with the dead letters",
+		request: &DeadLettersResponse{DeadLetters: []DeadLetter{testDeadLetter}},
+		want:    &pb.DeadLettersResponse{DeadLetters: []*pb.DeadLetter{testPBDeadLetter}},
+	},
+	{
+		name:    "should return the pb structure with the error",
+		request: &DeadLettersResponse{Err: ErrWebhooksNotEnabled()},
+		want:    &pb.DeadLettersResponse{DeadLetters: []*pb.DeadLetter{}, Error: WebhooksNotEnabled},
+	},
+	{
+		name:    "should return an error if the request has the wrong structure",
+		request: 1,
+		want:    &pb.DeadLettersResponse{},
+		err:     ErrInvalidResponseStructure(),
+	},
+}
+
+func TestEncodeGRPCDeadLettersResponse(t *testing.T) {
+	t.Log("encodeGRPCDeadLettersResponse")
+
+	for _, testcase := range encodeGRPCDeadLettersResponseTest {
+		t.Logf(testcase.name)
+
+		result, err := encodeGRPCDeadLettersResponse(context.Background(), testcase.request)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
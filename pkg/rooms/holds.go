@@ -0,0 +1,85 @@
+package rooms
+
+import (
+	"context"
+	"time"
+)
+
+// How long a hold keeps its room if not configured
+const DefaultHoldTTL = 15 * time.Minute
+
+// WithHoldTTL sets how long holds keep their room until they are confirmed
+func WithHoldTTL(ttl time.Duration) Option {
+	return func(r *roomsService) {
+		r.holdTTL = ttl
+	}
+}
+
+// WithHoldReaper releases the expired holds every interval until the context is done
+func WithHoldReaper(ctx context.Context, interval time.Duration) Option {
+	return func(r *roomsService) {
+		r.reaper = reaper{ctx, interval}
+	}
+}
+
+type reaper struct {
+	ctx      context.Context
+	interval time.Duration
+}
+
+// Holds a room matching the filter available for every night
+// from check-in to check-out until the hold expires (write/blocking)
+// The room is counted as booked until the hold is confirmed or released
+// Returns the hold with its id, confirmation code and expiry
+// Returns an error if authentication token is invalid
+// or there are no rooms available
+func (r roomsService) Hold(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (Reservation, error) {
+
+	// validate token
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return Reservation{}, err
+	}
+	expires := r.now().UTC().Add(r.holdTTL).Truncate(time.Second)
+	return r.book(Reservation{User: user, From: from, To: to, Expires: expires}, filter)
+}
+
+// Turns a hold, identified by its id or confirmation code, into a booking (write/blocking)
+// Returns the confirmed reservation
+// Returns an error if authentication token is invalid, the hold does not exist,
+// belongs to another user, has another version than the one expected (0 for any),
+// has expired or is already confirmed
+func (r roomsService) Confirm(ctx context.Context, token, ref string, version int) (Reservation, error) {
+	reservation, err := r.GetReservation(ctx, token, ref)
+	if err != nil {
+		return Reservation{}, err
+	}
+	return r.store.Confirm(reservation.Id, version, r.now().UTC())
+}
+
+// Releases the holds expired by the clock of the service every interval until the context is done
+func (r roomsService) reapHolds(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.releaseExpiredHolds(r.now().UTC())
+		}
+	}
+}
+
+// Releases the holds expired at a time and books the waitlisted stays
+// that fit in the released rooms
+func (r roomsService) releaseExpiredHolds(now time.Time) error {
+	released, err := r.store.ReleaseExpired(now)
+	if err != nil {
+		return err
+	}
+	if len(released) > 0 {
+		r.promoteWaitlist()
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package rooms
+
+import (
+	"context"
+	jwt "go-booking-service/pkg/token"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var serviceHoldTest = []struct {
+	name         string
+	reservations []Reservation
+	validator    Validator
+	want         int
+	err          error
+}{
+	{
+		name:      "should hold an available room",
+		validator: validatorCorrect{},
+		want:      1,
+	},
+	{
+		name:         "should return an error if there are no rooms available",
+		reservations: []Reservation{testReservation},
+		validator:    validatorCorrect{},
+		err:          ErrNoRoomAvailable(),
+	},
+	{
+		name:      "should return an error if the token is invalid",
+		validator: validatorIncorrect{},
+		err:       jwt.ErrInvalidToken(),
+	},
+}
+
+func TestServiceHold(t *testing.T) {
+	t.Log("ServiceHold")
+	from := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 16, 0, 0, 0, 0, time.UTC)
+
+	for _, testcase := range serviceHoldTest {
+		t.Logf(testcase.name)
+
+		rs := NewRoomsServer(newReservationsTestStore(testcase.reservations...), testcase.validator, WithHoldTTL(time.Hour))
+		start := time.Now().UTC()
+		hold, err := rs.Hold(context.Background(), "jjj.www.ttt", from, to, RoomFilter{})
+
+		assert.DeepEqual(t, err, testcase.err)
+		assert.Equal(t, hold.Room, testcase.want)
+		if err != nil {
+			continue
+		}
+		assert.Assert(t, hold.Held())
+		assert.Assert(t, !hold.Expires.Before(start.Add(time.Hour).Truncate(time.Second)))
+		available, _ := rs.Check(context.Background(), from, RoomFilter{})
+		assert.Equal(t, available, 0)
+	}
+}
+
+func TestServiceConfirm(t *testing.T) {
+	t.Log("ServiceConfirm")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 16, 0, 0, 0, 0, time.UTC)
+	store := newReservationsTestStore()
+	rs := NewRoomsServer(store, validatorCorrect{})
+
+	hold, err := rs.Hold(ctx, "jjj.www.ttt", from, to, RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should return an error if the hold has another version than expected")
+	_, err = rs.Confirm(ctx, "jjj.www.ttt", hold.Code, 2)
+	assert.DeepEqual(t, err, ErrVersionConflict())
+
+	t.Logf("should confirm a hold by its confirmation code")
+	confirmed, err := rs.Confirm(ctx, "jjj.www.ttt", hold.Code, 1)
+	assert.NilError(t, err)
+	hold.Expires = time.Time{}
+	hold.Version = 2
+	assert.DeepEqual(t, confirmed, hold)
+
+	t.Logf("should return an error if the reservation is not held")
+	_, err = rs.Confirm(ctx, "jjj.www.ttt", hold.Id, 0)
+	assert.DeepEqual(t, err, ErrNotHeld())
+
+	t.Logf("should return an error if the hold does not exist")
+	_, err = rs.Confirm(ctx, "jjj.www.ttt", "unknown", 0)
+	assert.DeepEqual(t, err, ErrBookingNotFound())
+
+	t.Logf("should not release confirmed reservations")
+	assert.NilError(t, rs.(roomsService).releaseExpiredHolds(time.Now().UTC().Add(DefaultHoldTTL)))
+	_, err = store.Reservation(hold.Id)
+	assert.NilError(t, err)
+}
+
+func TestServiceReleaseExpiredHolds(t *testing.T) {
+	t.Log("ServiceReleaseExpiredHolds")
+	ctx := context.Background()
+	date := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	store := newReservationsTestStore()
+	rs := NewRoomsServer(store, validatorUser{}, WithHoldTTL(time.Minute), WithClock(waitlistTestClock))
+
+	hold, err := rs.Hold(ctx, "John", date, date.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	entry, err := rs.JoinWaitlist(ctx, "Anna", date, date.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, entry.Position, 1)
+
+	t.Logf("should keep the holds that have not expired")
+	assert.NilError(t, rs.(roomsService).releaseExpiredHolds(hold.Expires.Add(-time.Second)))
+	available, _ := rs.Check(ctx, date, RoomFilter{})
+	assert.Equal(t, available, 0)
+
+	t.Logf("should release the expired holds and book the waitlist")
+	assert.NilError(t, rs.(roomsService).releaseExpiredHolds(hold.Expires))
+	_, err = rs.Confirm(ctx, "John", hold.Id, 0)
+	assert.DeepEqual(t, err, ErrBookingNotFound())
+	users, _ := store.Query(date)
+	assert.DeepEqual(t, users, map[int]string{1: "Anna"})
+}
+
+func TestHoldReaper(t *testing.T) {
+	t.Log("HoldReaper")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	date := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	store := newReservationsTestStore()
+	rs := NewRoomsServer(store, validatorCorrect{}, WithHoldTTL(-time.Second), WithHoldReaper(ctx, time.Millisecond))
+
+	_, err := rs.Hold(ctx, "jjj.www.ttt", date, date.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		available, err := rs.Check(ctx, date, RoomFilter{})
+		assert.NilError(t, err)
+		if available == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the expired hold was not released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHoldReaperClock(t *testing.T) {
+	t.Log("HoldReaperClock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	date := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	var now atomic.Value
+	now.Store(time.Now().AddDate(1, 0, 0))
+	clock := func() time.Time { return now.Load().(time.Time) }
+	store := newReservationsTestStore()
+	rs := NewRoomsServer(store, validatorCorrect{}, WithClock(clock), WithHoldTTL(time.Hour), WithHoldReaper(ctx, time.Millisecond))
+
+	_, err := rs.Hold(ctx, "jjj.www.ttt", date, date.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should release the holds expired by the clock of the service")
+	now.Store(clock().Add(2 * time.Hour))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		available, err := rs.Check(ctx, date, RoomFilter{})
+		assert.NilError(t, err)
+		if available == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the expired hold was not released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,116 @@
+package rooms
+
+import (
+	"sync"
+	"time"
+)
+
+// How long the result of a Book request is kept for its idempotency key if not configured
+const DefaultIdempotencyRetention = 24 * time.Hour
+
+// Longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
+// WithIdempotencyRetention sets how long the result of a Book request
+// is returned again for requests with the same idempotency key
+func WithIdempotencyRetention(retention time.Duration) Option {
+	return func(r *roomsService) {
+		r.idempotency.retention = retention
+	}
+}
+
+// idempotencyCache keeps the result of every request by user and idempotency key.
+// Concurrent requests with the same key wait for the first one to finish
+type idempotencyCache struct {
+	retention time.Duration
+	results   map[string]*idempotentResult
+	// completed results in the order they expire
+	expiry []*idempotentResult
+	mux    *sync.Mutex
+}
+
+type idempotentResult struct {
+	key          string
+	done         chan struct{}
+	reservations []Reservation
+	err          error
+	expires      time.Time
+}
+
+func newIdempotencyCache(retention time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		retention: retention,
+		results:   map[string]*idempotentResult{},
+		mux:       &sync.Mutex{},
+	}
+}
+
+// Returns the result of the request of a user with a key if it was already made,
+// otherwise books and keeps the result from the time of the request
+// Only bookings and rejections a retry would get again are kept,
+// so requests failing for lack of rooms, a leader or a commit can be retried
+func (c *idempotencyCache) do(user, key string, now time.Time, book func() ([]Reservation, error)) ([]Reservation, error) {
+	key = user + "\x00" + key
+
+	c.mux.Lock()
+	c.evict(now)
+	if result, ok := c.results[key]; ok {
+		c.mux.Unlock()
+		<-result.done
+		return result.reservations, result.err
+	}
+	result := &idempotentResult{key: key, done: make(chan struct{})}
+	c.results[key] = result
+	c.mux.Unlock()
+
+	result.reservations, result.err = book()
+
+	c.mux.Lock()
+	if result.err != nil && !deterministic(result.err) {
+		delete(c.results, key)
+	} else {
+		result.expires = now.Add(c.retention)
+		c.expiry = append(c.expiry, result)
+	}
+	c.mux.Unlock()
+	close(result.done)
+	return result.reservations, result.err
+}
+
+// Must be called holding the cache lock
+func (c *idempotencyCache) evict(now time.Time) {
+	for len(c.expiry) > 0 && !now.Before(c.expiry[0].expires) {
+		delete(c.results, c.expiry[0].key)
+		c.expiry[0] = nil
+		c.expiry = c.expiry[1:]
+	}
+}
+
+// Returns whether a Book request failing with an error fails the same way when retried
+func deterministic(err error) bool {
+	switch err := err.(type) {
+	case RuleViolation:
+		return true
+	case ErrorWithMsg:
+		switch err.Msg {
+		case InvalidDateRange, InvalidRoomType, InvalidRoomCount:
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the reservations a user booked with an idempotency key
+func keyedReservations(store BookingStore, user, key string) ([]Reservation, error) {
+	reservations, err := store.Reservations(user)
+	if err != nil {
+		return nil, err
+	}
+	var keyed []Reservation
+	for _, reservation := range reservations {
+		if reservation.Key == key {
+			keyed = append(keyed, reservation)
+		}
+	}
+	return keyed, nil
+}
@@ -0,0 +1,154 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestServiceBookIdempotency(t *testing.T) {
+	t.Log("ServiceBookIdempotency")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(testRooms(2))
+	rs := NewRoomsServer(store, validatorUser{})
+
+	first, err := rs.Book(ctx, "John", "retry-1", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should return the original reservation for a repeated key")
+	again, err := rs.Book(ctx, "John", "retry-1", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, again, first)
+	reservations, _ := store.Reservations("John")
+	assert.Equal(t, len(reservations), 1)
+
+	t.Logf("should keep the keys of every user apart")
+	other, err := rs.Book(ctx, "Anna", "retry-1", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+	assert.Assert(t, other[0].Id != first[0].Id)
+
+	t.Logf("should book a repeated key once rooms are released")
+	_, err = rs.Book(ctx, "Bob", "retry-1", 1, from, to, RoomFilter{})
+	assert.DeepEqual(t, err, ErrNoRoomAvailable())
+	assert.NilError(t, rs.Cancel(ctx, "John", first[0].Id, 0))
+	_, err = rs.Book(ctx, "Bob", "retry-1", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should return the original error for a repeated key if a retry would get it again")
+	_, err = rs.Book(ctx, "Bob", "retry-2", 1, to, from, RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+	_, err = rs.Book(ctx, "Bob", "retry-2", 1, from, to, RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+
+	t.Logf("should book again without key")
+	assert.NilError(t, rs.Cancel(ctx, "Anna", other[0].Id, 0))
+	_, err = rs.Book(ctx, "Bob", "", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should return an error if the key is too long")
+	_, err = rs.Book(ctx, "John", strings.Repeat("k", maxIdempotencyKeyLength+1), 1, from, to, RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidIdempotencyKey())
+}
+
+func TestServiceBookIdempotencyConcurrent(t *testing.T) {
+	t.Log("ServiceBookIdempotencyConcurrent")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(testRooms(5))
+	rs := NewRoomsServer(store, validatorUser{})
+
+	results := make([][]Reservation, 10)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reservations, err := rs.Book(ctx, "John", "retry-1", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+			assert.Check(t, err)
+			results[i] = reservations
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		assert.DeepEqual(t, result, results[0])
+	}
+	reservations, _ := store.Reservations("John")
+	assert.Equal(t, len(reservations), 1)
+}
+
+func TestIdempotencyCache(t *testing.T) {
+	t.Log("IdempotencyCache")
+	cache := newIdempotencyCache(time.Hour)
+	calls := 0
+	book := func(err error) func() ([]Reservation, error) {
+		return func() ([]Reservation, error) {
+			calls++
+			return []Reservation{testReservation}, err
+		}
+	}
+
+	now := time.Date(2020, 6, 12, 12, 0, 0, 0, time.UTC)
+
+	t.Logf("should not keep errors a retry may not get again")
+	for _, retried := range []error{errors.New("store unavailable"), ErrNoRoomAvailable(), ErrReservationExists(), ErrQuotaExceeded(), ErrNotLeader(), ErrChangeNotCommitted()} {
+		_, err := cache.do("John", "retry-1", now, book(retried))
+		assert.Error(t, err, retried.Error())
+	}
+	reservations, err := cache.do("John", "retry-1", now, book(nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reservations, []Reservation{testReservation})
+	assert.Equal(t, calls, 7)
+
+	t.Logf("should keep the rejections a retry would get again")
+	violation := RuleViolation{ReasonMinStay, "Stays must be at least 2 nights"}
+	_, err = cache.do("John", "retry-2", now, book(violation))
+	assert.DeepEqual(t, err, violation)
+	_, err = cache.do("John", "retry-2", now, book(nil))
+	assert.DeepEqual(t, err, violation)
+	assert.Equal(t, calls, 8)
+
+	t.Logf("should forget the results once they expire")
+	_, err = cache.do("John", "retry-1", now.Add(time.Hour), book(nil))
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 9)
+}
+
+func TestServiceBookIdempotencyClock(t *testing.T) {
+	t.Log("ServiceBookIdempotencyClock")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2020, 6, 12, 12, 0, 0, 0, time.UTC)
+	rs := NewRoomsServer(NewMemoryStore(testRooms(2)), validatorUser{}, WithClock(func() time.Time { return now }), WithIdempotencyRetention(time.Hour))
+
+	t.Logf("should retain the results for the time of the service clock")
+	_, err := rs.Book(ctx, "John", "retry-1", 1, from.AddDate(0, 0, 1), from, RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+	now = now.Add(time.Hour - time.Second)
+	_, err = rs.Book(ctx, "John", "retry-1", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+	now = now.Add(time.Second)
+	first, err := rs.Book(ctx, "John", "retry-1", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, first[0].Key, "retry-1")
+
+	t.Logf("should return the bookings made with a key after its result expires")
+	now = now.Add(time.Hour)
+	again, err := rs.Book(ctx, "John", "retry-1", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, again, first)
+
+	t.Logf("should book again once the bookings are cancelled and the result expires")
+	assert.NilError(t, rs.Cancel(ctx, "John", first[0].Id, 0))
+	now = now.Add(time.Hour)
+	other, err := rs.Book(ctx, "John", "retry-1", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Assert(t, other[0].Id != first[0].Id)
+}
@@ -0,0 +1,75 @@
+package rooms
+
+import "context"
+
+// Returns every room of the inventory (read/non-blocking)
+func (r roomsService) ListRooms(ctx context.Context) ([]RoomInfo, error) {
+	return r.store.Rooms()
+}
+
+// Adds a room to the inventory, a room without id gets the next free one (write/blocking)
+// Returns an error if the token does not belong to an administrator,
+// the room is invalid or its id is already taken
+func (r roomsService) CreateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return RoomInfo{}, err
+	}
+	if err := validateRoom(room); err != nil {
+		return RoomInfo{}, err
+	}
+	room, err := r.store.AddRoom(room)
+	if err != nil {
+		return RoomInfo{}, err
+	}
+	r.promoteWaitlist()
+	return room, nil
+}
+
+// Replaces the description of a room (write/blocking)
+// Returns an error if the token does not belong to an administrator,
+// the room is invalid or does not exist
+func (r roomsService) UpdateRoom(ctx context.Context, token string, room RoomInfo) (RoomInfo, error) {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return RoomInfo{}, err
+	}
+	if err := validateRoom(room); err != nil {
+		return RoomInfo{}, err
+	}
+	if err := r.store.UpdateRoom(room); err != nil {
+		return RoomInfo{}, err
+	}
+	r.promoteWaitlist()
+	return room, nil
+}
+
+// Removes a room from the inventory (write/blocking)
+// Rooms booked from today on can not be decommissioned,
+// their bookings have to be cancelled or moved first
+// Returns an error if the token does not belong to an administrator or the room does not exist
+func (r roomsService) DecommissionRoom(ctx context.Context, token string, id int) error {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return err
+	}
+	return r.store.RemoveRoom(id, today(r.now()))
+}
+
+func (r roomsService) authorizeAdmin(ctx context.Context, token string) error {
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !r.admins[user] {
+		return ErrNotAdmin()
+	}
+	return nil
+}
+
+func validateRoom(room RoomInfo) error {
+	if !validRoomType(room.Type) {
+		return ErrInvalidRoomType()
+	}
+	if room.Id < 0 || room.Id > maxRoomID || room.Name == "" || room.Capacity < 1 {
+		return ErrInvalidRoom()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package rooms
+
+import (
+	"context"
+	jwt "go-booking-service/pkg/token"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var inventoryTestRooms = []Room{
+	{
+		RoomInfo{Id: 1, Name: "101", Type: SingleRoom, Capacity: 1},
+		map[time.Time]string{},
+		&sync.Mutex{},
+	},
+}
+
+var serviceCreateRoomTest = []struct {
+	name      string
+	room      RoomInfo
+	admins    []string
+	validator Validator
+	want      RoomInfo
+	err       error
+}{
+	{
+		name:      "should return the created room with the next free id",
+		room:      RoomInfo{Name: "102", Type: DoubleRoom, Capacity: 2},
+		admins:    []string{"John"},
+		validator: validatorCorrect{},
+		want:      RoomInfo{Id: 2, Name: "102", Type: DoubleRoom, Capacity: 2},
+	},
+	{
+		name:      "should return an error if the id is taken",
+		room:      RoomInfo{Id: 1, Name: "102", Type: DoubleRoom, Capacity: 2},
+		admins:    []string{"John"},
+		validator: validatorCorrect{},
+		err:       ErrRoomExists(),
+	},
+	{
+		name:      "should return an error if the room type is unknown",
+		room:      RoomInfo{Name: "102", Type: "penthouse", Capacity: 2},
+		admins:    []string{"John"},
+		validator: validatorCorrect{},
+		err:       ErrInvalidRoomType(),
+	},
+	{
+		name:      "should return an error if the room has no capacity",
+		room:      RoomInfo{Name: "102", Type: DoubleRoom},
+		admins:    []string{"John"},
+		validator: validatorCorrect{},
+		err:       ErrInvalidRoom(),
+	},
+	{
+		name:      "should return an error if the user is not an administrator",
+		room:      RoomInfo{Name: "102", Type: DoubleRoom, Capacity: 2},
+		validator: validatorCorrect{},
+		err:       ErrNotAdmin(),
+	},
+	{
+		name:      "should return an error if the token is invalid",
+		room:      RoomInfo{Name: "102", Type: DoubleRoom, Capacity: 2},
+		admins:    []string{"John"},
+		validator: validatorIncorrect{},
+		err:       jwt.ErrInvalidToken(),
+	},
+}
+
+func TestServiceCreateRoom(t *testing.T) {
+	t.Log("ServiceCreateRoom")
+
+	for _, testcase := range serviceCreateRoomTest {
+		t.Logf(testcase.name)
+
+		store := NewMemoryStore(nil)
+		store.AddRoom(inventoryTestRooms[0].RoomInfo)
+		rs := NewRoomsServer(store, testcase.validator, WithAdmins(testcase.admins...))
+		result, err := rs.CreateRoom(context.Background(), "jjj.www.ttt", testcase.room)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var serviceUpdateRoomTest = []struct {
+	name   string
+	room   RoomInfo
+	admins []string
+	want   RoomInfo
+	err    error
+}{
+	{
+		name:   "should return the updated room",
+		room:   RoomInfo{Id: 1, Name: "101", Type: DoubleRoom, Capacity: 2},
+		admins: []string{"John"},
+		want:   RoomInfo{Id: 1, Name: "101", Type: DoubleRoom, Capacity: 2},
+	},
+	{
+		name:   "should return an error if the room does not exist",
+		room:   RoomInfo{Id: 2, Name: "102", Type: DoubleRoom, Capacity: 2},
+		admins: []string{"John"},
+		err:    ErrRoomNotFound(),
+	},
+	{
+		name: "should return an error if the user is not an administrator",
+		room: RoomInfo{Id: 1, Name: "101", Type: DoubleRoom, Capacity: 2},
+		err:  ErrNotAdmin(),
+	},
+}
+
+func TestServiceUpdateRoom(t *testing.T) {
+	t.Log("ServiceUpdateRoom")
+
+	for _, testcase := range serviceUpdateRoomTest {
+		t.Logf(testcase.name)
+
+		store := NewMemoryStore(nil)
+		store.AddRoom(inventoryTestRooms[0].RoomInfo)
+		rs := NewRoomsServer(store, validatorCorrect{}, WithAdmins(testcase.admins...))
+		result, err := rs.UpdateRoom(context.Background(), "jjj.www.ttt", testcase.room)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+// Time the rooms are decommissioned at
+var decommissionTestNow = time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+
+var serviceDecommissionRoomTest = []struct {
+	name    string
+	id      int
+	booking time.Time
+	admins  []string
+	want    []RoomInfo
+	err     error
+}{
+	{
+		name:    "should remove a room only booked in the past",
+		id:      1,
+		booking: time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC),
+		admins:  []string{"John"},
+		want:    []RoomInfo{},
+	},
+	{
+		name:    "should return an error if the room is booked in the future",
+		id:      1,
+		booking: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		admins:  []string{"John"},
+		want:    []RoomInfo{inventoryTestRooms[0].RoomInfo},
+		err:     ErrRoomHasBookings(),
+	},
+	{
+		name:    "should return an error if the room does not exist",
+		id:      2,
+		booking: time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC),
+		admins:  []string{"John"},
+		want:    []RoomInfo{inventoryTestRooms[0].RoomInfo},
+		err:     ErrRoomNotFound(),
+	},
+	{
+		name:    "should return an error if the user is not an administrator",
+		id:      1,
+		booking: time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC),
+		want:    []RoomInfo{inventoryTestRooms[0].RoomInfo},
+		err:     ErrNotAdmin(),
+	},
+}
+
+func TestServiceDecommissionRoom(t *testing.T) {
+	t.Log("ServiceDecommissionRoom")
+
+	for _, testcase := range serviceDecommissionRoomTest {
+		t.Logf(testcase.name)
+
+		store := NewMemoryStore(nil)
+		store.AddRoom(inventoryTestRooms[0].RoomInfo)
+		store.Reserve(stay("1", "Charles", 1, testcase.booking, 1))
+		rs := NewRoomsServer(store, validatorCorrect{}, WithAdmins(testcase.admins...), WithClock(func() time.Time { return decommissionTestNow }))
+		err := rs.DecommissionRoom(context.Background(), "jjj.www.ttt", testcase.id)
+		rooms, _ := rs.ListRooms(context.Background())
+
+		assert.DeepEqual(t, rooms, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
@@ -0,0 +1,519 @@
+package rooms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the changes to a reservation recorded in a journal
+const (
+	EventBooked    = "booked"
+	EventHeld      = "held"
+	EventConfirmed = "confirmed"
+	EventCancelled = "cancelled"
+	EventMoved     = "moved"
+	EventExpired   = "expired"
+)
+
+// Types of the changes to the inventory, the blackouts, the webhooks and the waitlist recorded in a journal,
+// so the rooms the reservations point to are rebuilt before them
+// They are not posted to webhooks
+const (
+	EventRoomAdded            = "room_added"
+	EventRoomUpdated          = "room_updated"
+	EventRoomRemoved          = "room_removed"
+	EventBlackoutAdded        = "blackout_added"
+	EventBlackoutRemoved      = "blackout_removed"
+	EventWebhookAdded         = "webhook_added"
+	EventWebhookRemoved       = "webhook_removed"
+	EventDeadLettered         = "dead_lettered"
+	EventWaitlistEntryStored  = "waitlist_entry_stored"
+	EventWaitlistEntryRemoved = "waitlist_entry_removed"
+)
+
+// Event is a change to a reservation, a room, a blackout, a webhook or the waitlist
+type Event struct {
+	// Position of the event in the journal, starting at 1, zero if no journal is kept
+	Seq  uint64    `json:"seq"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// The reservation after the change
+	Reservation Reservation `json:"reservation"`
+	// The reservation before it was moved, zero for other events
+	Previous Reservation `json:"previous"`
+	// The room added or updated, only its id when removed, nil for other events
+	Room *RoomInfo `json:"room,omitempty"`
+	// The blackout added, only its id when removed, nil for other events
+	Blackout *Blackout `json:"blackout,omitempty"`
+	// The webhook added, only its id when removed, nil for other events
+	Webhook *Webhook `json:"webhook,omitempty"`
+	// The event that could not be delivered, nil for other events
+	DeadLetter *DeadLetter `json:"dead_letter,omitempty"`
+	// The waitlist entry stored, only its id when removed, nil for other events
+	WaitlistEntry *WaitlistEntry `json:"waitlist_entry,omitempty"`
+}
+
+// Returns whether the event changed the bookings of a room
+func (e Event) Touches(room int) bool {
+	if e.Reservation.Id == "" {
+		return false
+	}
+	return e.Reservation.Room == room || (e.Type == EventMoved && e.Previous.Room == room)
+}
+
+// Journal is an append-only log of the changes to reservations
+type Journal interface {
+	// Appends an event with the next sequence number
+	// Returns the appended event
+	Append(event Event) (Event, error)
+	// Returns every event in the order they were appended
+	Events() ([]Event, error)
+}
+
+// NewMemoryJournal returns a Journal kept in memory
+func NewMemoryJournal() Journal {
+	return &memoryJournal{}
+}
+
+type memoryJournal struct {
+	events []Event
+	mux    sync.Mutex
+}
+
+func (j *memoryJournal) Append(event Event) (Event, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	event.Seq = uint64(len(j.events)) + 1
+	j.events = append(j.events, event)
+	return event, nil
+}
+
+func (j *memoryJournal) Events() ([]Event, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	events := make([]Event, len(j.events))
+	copy(events, j.events)
+	return events, nil
+}
+
+// FileJournal is a Journal appended to a file, one JSON event per line
+// Events are read back into memory when the file is opened
+type FileJournal struct {
+	file   *os.File
+	events []Event
+	mux    sync.Mutex
+}
+
+// NewFileJournal opens the journal in a file, creating it if it does not exist
+// A last line without its newline was torn by a crash while it was appended,
+// before the change was acknowledged, and is cut off the file
+// Returns an error if any complete line is not an event
+func NewFileJournal(path string) (*FileJournal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	var size int64
+	torn := false
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			torn = len(line) > 0
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("journal %s line %d: %v", path, len(events)+1, err)
+		}
+		events = append(events, event)
+		size += int64(len(line))
+	}
+	if torn {
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return &FileJournal{file: file, events: events}, nil
+}
+
+// Appends the event to the file and waits for it to be written to disk
+func (j *FileJournal) Append(event Event) (Event, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	event.Seq = uint64(len(j.events)) + 1
+	line, err := json.Marshal(event)
+	if err != nil {
+		return Event{}, err
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return Event{}, err
+	}
+	if err := j.file.Sync(); err != nil {
+		return Event{}, err
+	}
+	j.events = append(j.events, event)
+	return event, nil
+}
+
+func (j *FileJournal) Events() ([]Event, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	events := make([]Event, len(j.events))
+	copy(events, j.events)
+	return events, nil
+}
+
+func (j *FileJournal) Close() error {
+	return j.file.Close()
+}
+
+// Replay applies the events of a journal in order to a store,
+// rebuilding the rooms, blackouts, webhooks, waitlist and reservations it recorded
+// Returns an error if an event can not be applied
+func Replay(journal Journal, store BookingStore) error {
+	events, err := journal.Events()
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := replay(event, store); err != nil {
+			return fmt.Errorf("replaying event %d (%s %s): %v", event.Seq, event.Type, event.Reservation.Id, err)
+		}
+	}
+	return nil
+}
+
+func replay(event Event, store BookingStore) error {
+	reservation := event.Reservation
+	switch event.Type {
+	case EventBooked, EventHeld:
+		reserved, err := store.Reserve(reservation)
+		if err == nil && !reserved {
+			return ErrRoomNotAvailable()
+		}
+		return err
+	case EventConfirmed:
+		_, err := store.Confirm(reservation.Id, 0, event.Time)
+		return err
+	case EventCancelled, EventExpired:
+		return store.Release(reservation.Id, 0)
+	case EventMoved:
+		_, moved, err := store.Move(reservation.Id, 0, reservation.Room, reservation.From, reservation.To)
+		if err == nil && !moved {
+			return ErrRoomNotAvailable()
+		}
+		return err
+	case EventRoomAdded, EventRoomUpdated, EventRoomRemoved:
+		if event.Room == nil {
+			return fmt.Errorf("%s event without room", event.Type)
+		}
+		switch event.Type {
+		case EventRoomAdded:
+			_, err := store.AddRoom(*event.Room)
+			return err
+		case EventRoomUpdated:
+			return store.UpdateRoom(*event.Room)
+		}
+		// The room had no bookings left from the day it was removed
+		return store.RemoveRoom(event.Room.Id, event.Time)
+	case EventBlackoutAdded, EventBlackoutRemoved:
+		if event.Blackout == nil {
+			return fmt.Errorf("%s event without blackout", event.Type)
+		}
+		if event.Type == EventBlackoutAdded {
+			return store.AddBlackout(*event.Blackout)
+		}
+		return store.RemoveBlackout(event.Blackout.Id)
+	case EventWebhookAdded, EventWebhookRemoved:
+		if event.Webhook == nil {
+			return fmt.Errorf("%s event without webhook", event.Type)
+		}
+		if event.Type == EventWebhookAdded {
+			return store.AddWebhook(*event.Webhook)
+		}
+		return store.RemoveWebhook(event.Webhook.Id)
+	case EventDeadLettered:
+		if event.DeadLetter == nil {
+			return fmt.Errorf("%s event without dead letter", event.Type)
+		}
+		return store.AddDeadLetter(*event.DeadLetter)
+	case EventWaitlistEntryStored, EventWaitlistEntryRemoved:
+		if event.WaitlistEntry == nil {
+			return fmt.Errorf("%s event without waitlist entry", event.Type)
+		}
+		if event.Type == EventWaitlistEntryStored {
+			return store.PutWaitlistEntry(*event.WaitlistEntry)
+		}
+		return store.RemoveWaitlistEntry(event.WaitlistEntry.Id)
+	}
+	return fmt.Errorf("unknown event type %q", event.Type)
+}
+
+// WithJournal records every change to the rooms, blackouts, webhooks, waitlist and reservations of the store in a journal
+// Changes are applied one at a time so the journal keeps their order
+func WithJournal(journal Journal) Option {
+	return func(r *roomsService) {
+		r.journal = journal
+		r.journaled().journal = journal
+	}
+}
+
+// Returns the journaled store the service changes reservations through,
+// wrapping the store in one the first time
+func (r *roomsService) journaled() *journaledStore {
+	if store, ok := r.store.(*journaledStore); ok {
+		return store
+	}
+	store := &journaledStore{BookingStore: r.store, now: func() time.Time { return r.now() }}
+	r.store = store
+	return store
+}
+
+// journaledStore appends an event to the journal, if any, for every change to a room,
+// a blackout, a webhook, the waitlist or a reservation and publishes the changes to reservations to the webhooks, if any
+// An event is lost if the journal fails after the change was stored
+type journaledStore struct {
+	BookingStore
+	journal  Journal
+	webhooks *WebhookDispatcher
+	now      func() time.Time
+	mux      sync.Mutex
+}
+
+func (s *journaledStore) record(eventType string, reservation, previous Reservation) error {
+	return s.append(Event{Type: eventType, Reservation: reservation, Previous: previous})
+}
+
+func (s *journaledStore) append(event Event) error {
+	event.Time = s.now().UTC().Truncate(time.Second)
+	if s.journal != nil {
+		appended, err := s.journal.Append(event)
+		if err != nil {
+			return err
+		}
+		event = appended
+	}
+	if s.webhooks != nil && event.Reservation.Id != "" {
+		s.webhooks.Publish(event)
+	}
+	return nil
+}
+
+func (s *journaledStore) AddRoom(room RoomInfo) (RoomInfo, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	added, err := s.BookingStore.AddRoom(room)
+	if err != nil {
+		return added, err
+	}
+	return added, s.append(Event{Type: EventRoomAdded, Room: &added})
+}
+
+func (s *journaledStore) UpdateRoom(room RoomInfo) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.UpdateRoom(room); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventRoomUpdated, Room: &room})
+}
+
+func (s *journaledStore) RemoveRoom(room int, from time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.RemoveRoom(room, from); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventRoomRemoved, Room: &RoomInfo{Id: room}})
+}
+
+func (s *journaledStore) AddBlackout(blackout Blackout) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.AddBlackout(blackout); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventBlackoutAdded, Blackout: &blackout})
+}
+
+func (s *journaledStore) RemoveBlackout(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.RemoveBlackout(id); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventBlackoutRemoved, Blackout: &Blackout{Id: id}})
+}
+
+func (s *journaledStore) AddWebhook(webhook Webhook) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.AddWebhook(webhook); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventWebhookAdded, Webhook: &webhook})
+}
+
+func (s *journaledStore) RemoveWebhook(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.RemoveWebhook(id); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventWebhookRemoved, Webhook: &Webhook{Id: id}})
+}
+
+func (s *journaledStore) AddDeadLetter(deadLetter DeadLetter) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.AddDeadLetter(deadLetter); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventDeadLettered, DeadLetter: &deadLetter})
+}
+
+func (s *journaledStore) PutWaitlistEntry(entry WaitlistEntry) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.PutWaitlistEntry(entry); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventWaitlistEntryStored, WaitlistEntry: &entry})
+}
+
+func (s *journaledStore) RemoveWaitlistEntry(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.BookingStore.RemoveWaitlistEntry(id); err != nil {
+		return err
+	}
+	return s.append(Event{Type: EventWaitlistEntryRemoved, WaitlistEntry: &WaitlistEntry{Id: id}})
+}
+
+func (s *journaledStore) Reserve(reservations ...Reservation) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	reserved, err := s.BookingStore.Reserve(reservations...)
+	if err != nil || !reserved {
+		return reserved, err
+	}
+	for _, reservation := range reservations {
+		eventType := EventBooked
+		if reservation.Held() {
+			eventType = EventHeld
+		}
+		if err := s.record(eventType, reservation, Reservation{}); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (s *journaledStore) Release(id string, version int) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	reservation, err := s.BookingStore.Reservation(id)
+	if err != nil {
+		return err
+	}
+	if err := s.BookingStore.Release(id, version); err != nil {
+		return err
+	}
+	return s.record(EventCancelled, reservation, Reservation{})
+}
+
+func (s *journaledStore) Move(id string, version, room int, from, to time.Time) (Reservation, bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	previous, err := s.BookingStore.Reservation(id)
+	if err != nil {
+		return Reservation{}, false, err
+	}
+	moved, ok, err := s.BookingStore.Move(id, version, room, from, to)
+	if err != nil || !ok {
+		return moved, ok, err
+	}
+	return moved, true, s.record(EventMoved, moved, previous)
+}
+
+func (s *journaledStore) Confirm(id string, version int, now time.Time) (Reservation, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	confirmed, err := s.BookingStore.Confirm(id, version, now)
+	if err != nil {
+		return confirmed, err
+	}
+	return confirmed, s.record(EventConfirmed, confirmed, Reservation{})
+}
+
+func (s *journaledStore) ReleaseExpired(now time.Time) ([]Reservation, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	released, err := s.BookingStore.ReleaseExpired(now)
+	if err != nil {
+		return released, err
+	}
+	for _, reservation := range released {
+		if err := s.record(EventExpired, reservation, Reservation{}); err != nil {
+			return released, err
+		}
+	}
+	return released, nil
+}
+
+// Returns the changes to a booking, identified by its reservation id or
+// confirmation code, or to every booking of a room if the id is empty (read/non-blocking)
+// Cancelled bookings keep their history
+// Returns an error if the token is invalid, the booking belongs to another user,
+// the room history is not requested by an administrator or no journal is kept
+func (r roomsService) History(ctx context.Context, token, ref string, room int) ([]Event, error) {
+	if r.journal == nil {
+		return nil, ErrHistoryNotRecorded()
+	}
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" && !r.admins[user] {
+		return nil, ErrNotAdmin()
+	}
+
+	events, err := r.journal.Events()
+	if err != nil {
+		return nil, err
+	}
+	history := []Event{}
+	for _, event := range events {
+		if ref == "" && event.Touches(room) {
+			history = append(history, event)
+		}
+		if ref != "" && (event.Reservation.Id == ref || event.Reservation.Code == strings.ToUpper(ref)) {
+			history = append(history, event)
+		}
+	}
+	if ref == "" {
+		return history, nil
+	}
+	if len(history) == 0 {
+		return nil, ErrBookingNotFound()
+	}
+	if history[0].Reservation.User != user && !r.admins[user] {
+		return nil, ErrNotBookingOwner()
+	}
+	return history, nil
+}
@@ -0,0 +1,261 @@
+package rooms
+
+import (
+	"context"
+	"go-booking-service/pb"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var testEvent = Event{
+	Seq:         1,
+	Type:        EventBooked,
+	Time:        time.Date(2020, 6, 12, 21, 30, 0, 0, time.UTC),
+	Reservation: testReservation,
+}
+
+var testPBEvent = &pb.Event{
+	Seq:         1,
+	Type:        EventBooked,
+	Time:        time.Date(2020, 6, 12, 21, 30, 0, 0, time.UTC).Unix(),
+	Reservation: testPBReservation,
+}
+
+func TestFileJournal(t *testing.T) {
+	t.Log("FileJournal")
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rooms.journal")
+
+	journal, err := NewFileJournal(path)
+	assert.NilError(t, err)
+	t.Logf("should number the appended events")
+	first, err := journal.Append(Event{Type: EventBooked, Time: testEvent.Time, Reservation: testReservation})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, first, testEvent)
+	second, err := journal.Append(Event{Type: EventCancelled, Time: testEvent.Time, Reservation: testReservation})
+	assert.NilError(t, err)
+	assert.Equal(t, second.Seq, uint64(2))
+	assert.NilError(t, journal.Close())
+
+	t.Logf("should read the events back when reopened")
+	journal, err = NewFileJournal(path)
+	assert.NilError(t, err)
+	events, err := journal.Events()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []Event{first, second})
+	third, err := journal.Append(Event{Type: EventBooked, Time: testEvent.Time, Reservation: testReservation})
+	assert.NilError(t, err)
+	assert.Equal(t, third.Seq, uint64(3))
+	assert.NilError(t, journal.Close())
+
+	t.Logf("should cut off a line torn while it was appended")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NilError(t, err)
+	_, err = file.WriteString(`{"seq":4,"type":"boo`)
+	assert.NilError(t, err)
+	assert.NilError(t, file.Close())
+	journal, err = NewFileJournal(path)
+	assert.NilError(t, err)
+	events, err = journal.Events()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []Event{first, second, third})
+	fourth, err := journal.Append(Event{Type: EventCancelled, Time: testEvent.Time, Reservation: testReservation})
+	assert.NilError(t, err)
+	assert.Equal(t, fourth.Seq, uint64(4))
+	assert.NilError(t, journal.Close())
+	journal, err = NewFileJournal(path)
+	assert.NilError(t, err)
+	events, err = journal.Events()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, events, []Event{first, second, third, fourth})
+	assert.NilError(t, journal.Close())
+
+	t.Logf("should return an error if the file is corrupted")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("{\"seq\":1}\nnot json\n"), 0600))
+	_, err = NewFileJournal(path)
+	assert.Assert(t, err != nil)
+	assert.NilError(t, ioutil.WriteFile(path, []byte("{\"seq\":1}\nnot json\n{\"seq\":3}"), 0600))
+	_, err = NewFileJournal(path)
+	assert.Assert(t, err != nil)
+}
+
+func journalTestClock() time.Time {
+	return testEvent.Time
+}
+
+// Books, holds, moves, confirms and cancels stays on a journaled service
+// Returns the service, its journal and the bookings left
+func journaledTestService(t *testing.T) (RoomsService, Journal, []Reservation) {
+	ctx := context.Background()
+	journal := NewMemoryJournal()
+	rs := NewRoomsServer(NewMemoryStore(testRooms(3)), validatorUser{}, WithAdmins("Admin"), WithClock(journalTestClock), WithJournal(journal))
+	from := testEvent.Time.Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+	booked, err := rs.Book(ctx, "John", "", 1, from, from.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	cancelled, err := rs.Book(ctx, "John", "", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	held, err := rs.Hold(ctx, "Anna", from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	_, err = rs.ModifyBooking(ctx, "John", booked[0].Id, time.Time{}, time.Time{}, 2, 0)
+	assert.Assert(t, err != nil)
+	assert.NilError(t, rs.Cancel(ctx, "John", cancelled[0].Code, 0))
+	moved, err := rs.ModifyBooking(ctx, "John", booked[0].Id, time.Time{}, time.Time{}, 2, 0)
+	assert.NilError(t, err)
+	confirmed, err := rs.Confirm(ctx, "Anna", held.Id, 0)
+	assert.NilError(t, err)
+	return rs, journal, []Reservation{moved, confirmed}
+}
+
+func TestJournaledStore(t *testing.T) {
+	t.Log("JournaledStore")
+	_, journal, bookings := journaledTestService(t)
+	moved, confirmed := bookings[0], bookings[1]
+
+	events, err := journal.Events()
+	assert.NilError(t, err)
+	types := make([]string, len(events))
+	for i, event := range events {
+		assert.Equal(t, event.Seq, uint64(i+1))
+		assert.Equal(t, event.Time, testEvent.Time)
+		types[i] = event.Type
+	}
+
+	t.Logf("should record every change applied to the reservations in order")
+	assert.DeepEqual(t, types, []string{EventBooked, EventBooked, EventHeld, EventCancelled, EventMoved, EventConfirmed})
+	t.Logf("should record the reservation before it was moved")
+	assert.Equal(t, events[4].Previous.Room, 1)
+	assert.DeepEqual(t, events[4].Reservation, moved)
+	assert.DeepEqual(t, events[5].Reservation, confirmed)
+
+	t.Logf("should record expired holds")
+	journal = NewMemoryJournal()
+	rs := NewRoomsServer(NewMemoryStore(testRooms(1)), validatorUser{}, WithClock(journalTestClock), WithJournal(journal)).(roomsService)
+	held, err := rs.Hold(context.Background(), "Anna", testHold.From, testHold.To, RoomFilter{})
+	assert.NilError(t, err)
+	assert.NilError(t, rs.releaseExpiredHolds(held.Expires.Add(time.Second)))
+	events, err = journal.Events()
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[1].Type, EventExpired)
+	assert.Equal(t, events[1].Reservation.Id, held.Id)
+}
+
+func TestReplay(t *testing.T) {
+	t.Log("Replay")
+	_, journal, bookings := journaledTestService(t)
+
+	t.Logf("should rebuild the reservations of the journal")
+	store := NewMemoryStore(testRooms(3))
+	assert.NilError(t, Replay(journal, store))
+	for _, booking := range bookings {
+		reservation, err := store.Reservation(booking.Id)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, reservation, booking)
+	}
+	free, err := store.CountFree(bookings[0].From, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, free, 1)
+
+	t.Logf("should return an error if an event can not be applied")
+	assert.Assert(t, Replay(journal, store) != nil)
+}
+
+func TestReplayInventory(t *testing.T) {
+	t.Log("ReplayInventory")
+	ctx := context.Background()
+	journal := NewMemoryJournal()
+	rs := NewRoomsServer(NewMemoryStore(nil), validatorUser{}, WithAdmins("Admin"), WithClock(journalTestClock), WithJournal(journal))
+	from := testEvent.Time.Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+	suite, err := rs.CreateRoom(ctx, "Admin", RoomInfo{Name: "201", Type: SingleRoom, Capacity: 1})
+	assert.NilError(t, err)
+	suite.Type, suite.Capacity = SuiteRoom, 4
+	suite, err = rs.UpdateRoom(ctx, "Admin", suite)
+	assert.NilError(t, err)
+	removed, err := rs.CreateRoom(ctx, "Admin", RoomInfo{Name: "202", Type: DoubleRoom, Capacity: 2})
+	assert.NilError(t, err)
+	assert.NilError(t, rs.DecommissionRoom(ctx, "Admin", removed.Id))
+	lifted, _, err := rs.AddBlackout(ctx, "Admin", Blackout{Room: suite.Id, From: from, To: from.AddDate(0, 0, 1)})
+	assert.NilError(t, err)
+	assert.NilError(t, rs.RemoveBlackout(ctx, "Admin", lifted.Id))
+	blackout, _, err := rs.AddBlackout(ctx, "Admin", Blackout{From: from.AddDate(0, 0, 7), To: from.AddDate(0, 0, 8)})
+	assert.NilError(t, err)
+	booked, err := rs.Book(ctx, "John", "", 1, from, from.AddDate(0, 0, 2), RoomFilter{Type: SuiteRoom})
+	assert.NilError(t, err)
+
+	t.Logf("should rebuild the rooms and blackouts the reservations depend on")
+	store := NewMemoryStore(nil)
+	assert.NilError(t, Replay(journal, store))
+	rooms, err := store.Rooms()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, rooms, []RoomInfo{suite})
+	blackouts, err := store.Blackouts()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, blackouts, []Blackout{blackout})
+	reservation, err := store.Reservation(booked[0].Id)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reservation, booked[0])
+
+	t.Logf("should keep inventory changes out of the room history")
+	history, err := rs.History(ctx, "Admin", "", 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(history), 0)
+}
+
+func TestServiceHistory(t *testing.T) {
+	t.Log("ServiceHistory")
+	ctx := context.Background()
+	rs, _, bookings := journaledTestService(t)
+	moved := bookings[0]
+
+	t.Logf("should return the changes to a booking to its owner")
+	events, err := rs.History(ctx, "John", moved.Code, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[0].Type, EventBooked)
+	assert.Equal(t, events[1].Type, EventMoved)
+
+	t.Logf("should keep the history of cancelled bookings")
+	room, err := rs.History(ctx, "Admin", "", 2)
+	assert.NilError(t, err)
+	events, err = rs.History(ctx, "John", room[0].Reservation.Id, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[1].Type, EventCancelled)
+
+	t.Logf("should return an error if the booking was never recorded")
+	_, err = rs.History(ctx, "John", "CODE1", 0)
+	assert.DeepEqual(t, err, ErrBookingNotFound())
+
+	t.Logf("should return an error if the booking belongs to another user")
+	_, err = rs.History(ctx, "Anna", moved.Id, 0)
+	assert.DeepEqual(t, err, ErrNotBookingOwner())
+	_, err = rs.History(ctx, "Admin", moved.Id, 0)
+	assert.NilError(t, err)
+
+	t.Logf("should return the changes to a room, moves away included, to administrators")
+	types := make([]string, len(room))
+	for i, event := range room {
+		types[i] = event.Type
+	}
+	assert.DeepEqual(t, types, []string{EventBooked, EventCancelled, EventMoved})
+	room, err = rs.History(ctx, "Admin", "", 1)
+	assert.NilError(t, err)
+	assert.Equal(t, len(room), 2)
+	assert.Equal(t, room[1].Type, EventMoved)
+	_, err = rs.History(ctx, "John", "", 1)
+	assert.DeepEqual(t, err, ErrNotAdmin())
+
+	t.Logf("should return an error if no journal is kept")
+	rs = NewRoomsServer(NewMemoryStore(testRooms(1)), validatorUser{})
+	_, err = rs.History(ctx, "John", moved.Id, 0)
+	assert.DeepEqual(t, err, ErrHistoryNotRecorded())
+}
@@ -0,0 +1,79 @@
+package rooms
+
+import (
+	"sync"
+	"time"
+)
+
+// Quota limits what every user can book on this service, 0 for no limit.
+// A sharded Server does not add up the bookings of its shards, so the
+// limits apply per shard
+type Quota struct {
+	// Reservations, holds included, not checked out yet
+	MaxActiveBookings int
+	// Rooms booked for the same night
+	MaxRoomsPerDate int
+}
+
+// WithQuota limits the bookings of every user, unlimited if not configured
+func WithQuota(quota Quota) Option {
+	return func(r *roomsService) {
+		r.quota = &quotaLimiter{quota: quota, users: map[string]*sync.Mutex{}}
+	}
+}
+
+// quotaLimiter serializes the bookings of each user so concurrent requests
+// can not exceed the quota together
+type quotaLimiter struct {
+	quota Quota
+	users map[string]*sync.Mutex
+	mux   sync.Mutex
+}
+
+// Locks the bookings of a user until the returned function is called
+func (q *quotaLimiter) lock(user string) func() {
+	q.mux.Lock()
+	userMux, ok := q.users[user]
+	if !ok {
+		userMux = &sync.Mutex{}
+		q.users[user] = userMux
+	}
+	q.mux.Unlock()
+	userMux.Lock()
+	return userMux.Unlock
+}
+
+// Returns an error if booking count more rooms for the stay of a user,
+// not counting the reservation except (if any), exceeds the quota
+// Must be called with the bookings of the user locked
+func (r roomsService) checkQuota(stay Reservation, count int, except string) error {
+	reservations, err := r.store.Reservations(stay.User)
+	if err != nil {
+		return err
+	}
+	today := today(r.now())
+	active := count
+	booked := map[time.Time]int{}
+	for _, reservation := range reservations {
+		if reservation.Id == except || !reservation.To.After(today) {
+			continue
+		}
+		active++
+		for _, night := range reservation.Nights() {
+			booked[night]++
+		}
+	}
+
+	quota := r.quota.quota
+	if quota.MaxActiveBookings > 0 && active > quota.MaxActiveBookings {
+		return ErrQuotaExceeded()
+	}
+	if quota.MaxRoomsPerDate > 0 {
+		for _, night := range stay.Nights() {
+			if booked[night]+count > quota.MaxRoomsPerDate {
+				return ErrQuotaExceeded()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package rooms
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var quotaTestDate = time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+
+func quotaTestClock() time.Time {
+	return quotaTestDate
+}
+
+var serviceBookQuotaTest = []struct {
+	name         string
+	quota        Quota
+	reservations []Reservation
+	count        int
+	err          error
+}{
+	{
+		name:  "should book within the quota",
+		quota: Quota{MaxActiveBookings: 2, MaxRoomsPerDate: 2},
+		reservations: []Reservation{
+			stay("1", "John", 1, quotaTestDate, 1),
+		},
+		count: 1,
+	},
+	{
+		name:  "should return an error if the user has too many active bookings",
+		quota: Quota{MaxActiveBookings: 2},
+		reservations: []Reservation{
+			stay("1", "John", 1, quotaTestDate.AddDate(0, 0, 3), 1),
+			stay("2", "John", 1, quotaTestDate.AddDate(0, 0, 5), 1),
+		},
+		count: 1,
+		err:   ErrQuotaExceeded(),
+	},
+	{
+		name:  "should not count checked out bookings nor those of other users",
+		quota: Quota{MaxActiveBookings: 1},
+		reservations: []Reservation{
+			stay("1", "John", 1, quotaTestDate.AddDate(0, 0, -3), 3),
+			stay("2", "Anna", 1, quotaTestDate.AddDate(0, 0, 3), 1),
+		},
+		count: 1,
+	},
+	{
+		name:  "should return an error if the user would book too many rooms for a night",
+		quota: Quota{MaxRoomsPerDate: 2},
+		reservations: []Reservation{
+			stay("1", "John", 1, quotaTestDate.AddDate(0, 0, 1), 2),
+		},
+		count: 2,
+		err:   ErrQuotaExceeded(),
+	},
+	{
+		name:  "should return an error if a group is larger than the quota",
+		quota: Quota{MaxActiveBookings: 3},
+		count: 4,
+		err:   ErrQuotaExceeded(),
+	},
+}
+
+func TestServiceBookQuota(t *testing.T) {
+	t.Log("ServiceBookQuota")
+
+	for _, testcase := range serviceBookQuotaTest {
+		t.Logf(testcase.name)
+
+		store := NewMemoryStore(testRooms(5))
+		store.Reserve(testcase.reservations...)
+		rs := NewRoomsServer(store, validatorCorrect{}, WithQuota(testcase.quota), WithClock(quotaTestClock))
+
+		reservations, err := rs.Book(context.Background(), "jjj.www.ttt", "", testcase.count, quotaTestDate.AddDate(0, 0, 2), quotaTestDate.AddDate(0, 0, 3), RoomFilter{})
+		assert.DeepEqual(t, err, testcase.err)
+		if err == nil {
+			assert.Equal(t, len(reservations), testcase.count)
+		}
+	}
+}
+
+func TestServiceQuota(t *testing.T) {
+	t.Log("ServiceQuota")
+	ctx := context.Background()
+	rs := NewRoomsServer(NewMemoryStore(testRooms(5)), validatorUser{}, WithQuota(Quota{MaxRoomsPerDate: 1}), WithClock(quotaTestClock))
+	first, err := rs.Book(ctx, "John", "", 1, quotaTestDate, quotaTestDate.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	second, err := rs.Book(ctx, "John", "", 1, quotaTestDate.AddDate(0, 0, 1), quotaTestDate.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should count holds in the quota")
+	_, err = rs.Hold(ctx, "John", quotaTestDate, quotaTestDate.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrQuotaExceeded())
+
+	t.Logf("should not move bookings beyond the quota")
+	_, err = rs.ModifyBooking(ctx, "John", second[0].Id, quotaTestDate, quotaTestDate.AddDate(0, 0, 1), 0, 0)
+	assert.DeepEqual(t, err, ErrQuotaExceeded())
+
+	t.Logf("should not count the moved booking itself")
+	_, err = rs.ModifyBooking(ctx, "John", first[0].Id, quotaTestDate, quotaTestDate.AddDate(0, 0, 1), 3, 0)
+	assert.NilError(t, err)
+
+	t.Logf("should not let concurrent bookings of a user exceed the quota together")
+	var wg sync.WaitGroup
+	booked := make(chan struct{}, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := rs.Book(ctx, "Anna", "", 1, quotaTestDate, quotaTestDate.AddDate(0, 0, 1), RoomFilter{}); err == nil {
+				booked <- struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, len(booked), 1)
+}
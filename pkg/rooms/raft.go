@@ -0,0 +1,381 @@
+package rooms
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// Changes to the store replicated through the raft log
+const (
+	raftAddRoom             = "add_room"
+	raftUpdateRoom          = "update_room"
+	raftRemoveRoom          = "remove_room"
+	raftReserve             = "reserve"
+	raftRelease             = "release"
+	raftMove                = "move"
+	raftConfirm             = "confirm"
+	raftReleaseExpired      = "release_expired"
+	raftAddBlackout         = "add_blackout"
+	raftRemoveBlackout      = "remove_blackout"
+	raftAddWebhook          = "add_webhook"
+	raftRemoveWebhook       = "remove_webhook"
+	raftAddDeadLetter       = "add_dead_letter"
+	raftPutWaitlistEntry    = "put_waitlist_entry"
+	raftRemoveWaitlistEntry = "remove_waitlist_entry"
+)
+
+// raftCommand is a change to the store as written to the raft log
+// Ids, codes and times are chosen before the change is logged
+// so every node applies it the same way
+type raftCommand struct {
+	Op           string        `json:"op"`
+	Id           string        `json:"id,omitempty"`
+	Version      int           `json:"version,omitempty"`
+	Room         RoomInfo      `json:"room"`
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	Time         time.Time     `json:"time"`
+	Reservations []Reservation `json:"reservations,omitempty"`
+	Blackout     Blackout      `json:"blackout"`
+	Webhook      Webhook       `json:"webhook"`
+	DeadLetter   DeadLetter    `json:"dead_letter"`
+	Entry        WaitlistEntry `json:"entry"`
+}
+
+// raftResult is what applying a change to the store returned
+type raftResult struct {
+	Room         RoomInfo
+	Reservation  Reservation
+	Reservations []Reservation
+	Ok           bool
+	Err          error
+}
+
+// StoreFSM applies the changes committed to the raft log to a memory store
+// and wakes the availability watchers of the node, if any, on every node
+type StoreFSM struct {
+	store    BookingStore
+	watchers *availabilityWatchers
+	mux      sync.RWMutex
+}
+
+// NewStoreFSM returns a StoreFSM starting from the rooms given,
+// which must be the same on every node of the cluster
+func NewStoreFSM(rooms []Room) *StoreFSM {
+	return &StoreFSM{store: NewMemoryStore(rooms)}
+}
+
+// The store is replaced when a snapshot is restored
+func (f *StoreFSM) current() BookingStore {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+	return f.store
+}
+
+// Wakes the watchers on every change applied from now on,
+// whether it was made on this node or on another one
+func (f *StoreFSM) watch(watchers *availabilityWatchers) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.watchers = watchers
+}
+
+// Returns the store changes are applied to
+func (f *StoreFSM) watched() BookingStore {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+	if f.watchers == nil {
+		return f.store
+	}
+	return watchedStore{BookingStore: f.store, watchers: f.watchers}
+}
+
+func (f *StoreFSM) Apply(log *raft.Log) interface{} {
+	var cmd raftCommand
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return raftResult{Err: err}
+	}
+	store := f.watched()
+	var result raftResult
+	switch cmd.Op {
+	case raftAddRoom:
+		result.Room, result.Err = store.AddRoom(cmd.Room)
+	case raftUpdateRoom:
+		result.Err = store.UpdateRoom(cmd.Room)
+	case raftRemoveRoom:
+		result.Err = store.RemoveRoom(cmd.Room.Id, cmd.From)
+	case raftReserve:
+		// a booking retried on another leader is logged again with new ids
+		if result.Err = checkKey(store, cmd.Reservations); result.Err == nil {
+			result.Ok, result.Err = store.Reserve(cmd.Reservations...)
+		}
+	case raftRelease:
+		result.Err = store.Release(cmd.Id, cmd.Version)
+	case raftMove:
+		result.Reservation, result.Ok, result.Err = store.Move(cmd.Id, cmd.Version, cmd.Room.Id, cmd.From, cmd.To)
+	case raftConfirm:
+		result.Reservation, result.Err = store.Confirm(cmd.Id, cmd.Version, cmd.Time)
+	case raftReleaseExpired:
+		result.Reservations, result.Err = store.ReleaseExpired(cmd.Time)
+	case raftAddBlackout:
+		result.Err = store.AddBlackout(cmd.Blackout)
+	case raftRemoveBlackout:
+		result.Err = store.RemoveBlackout(cmd.Id)
+	case raftAddWebhook:
+		result.Err = store.AddWebhook(cmd.Webhook)
+	case raftRemoveWebhook:
+		result.Err = store.RemoveWebhook(cmd.Id)
+	case raftAddDeadLetter:
+		result.Err = store.AddDeadLetter(cmd.DeadLetter)
+	case raftPutWaitlistEntry:
+		result.Err = store.PutWaitlistEntry(cmd.Entry)
+	case raftRemoveWaitlistEntry:
+		result.Err = store.RemoveWaitlistEntry(cmd.Id)
+	default:
+		result.Err = ErrInvalidRequestStructure()
+	}
+	return result
+}
+
+// Returns ErrIdempotencyKeyUsed if the user of the reservations already booked with their key
+func checkKey(store BookingStore, reservations []Reservation) error {
+	if len(reservations) == 0 || reservations[0].Key == "" {
+		return nil
+	}
+	booked, err := keyedReservations(store, reservations[0].User, reservations[0].Key)
+	if err == nil && len(booked) > 0 {
+		return ErrIdempotencyKeyUsed()
+	}
+	return err
+}
+
+func (f *StoreFSM) Snapshot() (raft.FSMSnapshot, error) {
+	snapshot, err := f.current().Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Version = SnapshotVersion
+	snapshot.Taken = time.Now().UTC().Truncate(time.Second)
+	return fsmSnapshot{snapshot}, nil
+}
+
+func (f *StoreFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	snapshot, err := ReadSnapshot(rc)
+	if err != nil {
+		return err
+	}
+	store := NewMemoryStore(nil)
+	if err := Restore(snapshot, store); err != nil {
+		return err
+	}
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.store = store
+	if f.watchers != nil {
+		f.watchers.notify(time.Time{}, time.Time{})
+	}
+	return nil
+}
+
+// fsmSnapshot is written to the raft snapshot store in the snapshot file format
+type fsmSnapshot struct {
+	snapshot Snapshot
+}
+
+func (s fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := WriteSnapshot(sink, s.snapshot); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s fsmSnapshot) Release() {}
+
+// RaftStore is a BookingStore replicated to every node of a cluster through a raft log.
+// Only the leader accepts changes, which return once committed and applied to its store.
+// Reads are answered by the store of the node and may miss the latest changes on followers.
+// Availability watchers are woken as the changes are applied to the store of their node.
+// Reservations keep the idempotency key they were booked with so a booking retried
+// on another leader is not applied twice, but the idempotency cache of the service
+// is not replicated and is lost on failover
+// Webhooks, dead letters and the waitlist are replicated, the events not delivered yet are not
+type RaftStore struct {
+	raft    *raft.Raft
+	fsm     *StoreFSM
+	timeout time.Duration
+}
+
+// NewRaftStore returns a RaftStore logging changes to a raft node whose FSM is given,
+// waiting up to a timeout for them to be committed
+func NewRaftStore(node *raft.Raft, fsm *StoreFSM, timeout time.Duration) *RaftStore {
+	return &RaftStore{raft: node, fsm: fsm, timeout: timeout}
+}
+
+// Returns the id of the leader, empty if there is none, and whether it is this node
+// Nodes are identified by the address of their gRPC server
+func (s *RaftStore) Leader() (string, bool) {
+	_, id := s.raft.LeaderWithID()
+	return string(id), s.raft.State() == raft.Leader
+}
+
+// Returns ErrNotLeader if the node does not accept changes and
+// ErrChangeNotCommitted if leadership was lost before the change was known to be committed
+func (s *RaftStore) apply(cmd raftCommand) (raftResult, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return raftResult{}, err
+	}
+	future := s.raft.Apply(data, s.timeout)
+	switch err := future.Error(); err {
+	case nil:
+	case raft.ErrNotLeader, raft.ErrLeadershipTransferInProgress, raft.ErrRaftShutdown:
+		return raftResult{}, ErrNotLeader()
+	case raft.ErrLeadershipLost, raft.ErrEnqueueTimeout:
+		return raftResult{}, ErrChangeNotCommitted()
+	default:
+		return raftResult{}, err
+	}
+	result := future.Response().(raftResult)
+	return result, result.Err
+}
+
+func (s *RaftStore) watch(watchers *availabilityWatchers) {
+	s.fsm.watch(watchers)
+}
+
+func (s *RaftStore) AddRoom(room RoomInfo) (RoomInfo, error) {
+	result, err := s.apply(raftCommand{Op: raftAddRoom, Room: room})
+	return result.Room, err
+}
+
+func (s *RaftStore) UpdateRoom(room RoomInfo) error {
+	_, err := s.apply(raftCommand{Op: raftUpdateRoom, Room: room})
+	return err
+}
+
+func (s *RaftStore) RemoveRoom(room int, from time.Time) error {
+	_, err := s.apply(raftCommand{Op: raftRemoveRoom, Room: RoomInfo{Id: room}, From: from})
+	return err
+}
+
+func (s *RaftStore) Reserve(reservations ...Reservation) (bool, error) {
+	result, err := s.apply(raftCommand{Op: raftReserve, Reservations: reservations})
+	return result.Ok, err
+}
+
+func (s *RaftStore) Release(id string, version int) error {
+	_, err := s.apply(raftCommand{Op: raftRelease, Id: id, Version: version})
+	return err
+}
+
+func (s *RaftStore) Move(id string, version, room int, from, to time.Time) (Reservation, bool, error) {
+	result, err := s.apply(raftCommand{Op: raftMove, Id: id, Version: version, Room: RoomInfo{Id: room}, From: from, To: to})
+	return result.Reservation, result.Ok, err
+}
+
+func (s *RaftStore) Confirm(id string, version int, now time.Time) (Reservation, error) {
+	result, err := s.apply(raftCommand{Op: raftConfirm, Id: id, Version: version, Time: now})
+	return result.Reservation, err
+}
+
+func (s *RaftStore) ReleaseExpired(now time.Time) ([]Reservation, error) {
+	result, err := s.apply(raftCommand{Op: raftReleaseExpired, Time: now})
+	return result.Reservations, err
+}
+
+func (s *RaftStore) AddBlackout(blackout Blackout) error {
+	_, err := s.apply(raftCommand{Op: raftAddBlackout, Blackout: blackout})
+	return err
+}
+
+func (s *RaftStore) RemoveBlackout(id string) error {
+	_, err := s.apply(raftCommand{Op: raftRemoveBlackout, Id: id})
+	return err
+}
+
+func (s *RaftStore) AddWebhook(webhook Webhook) error {
+	_, err := s.apply(raftCommand{Op: raftAddWebhook, Webhook: webhook})
+	return err
+}
+
+func (s *RaftStore) RemoveWebhook(id string) error {
+	_, err := s.apply(raftCommand{Op: raftRemoveWebhook, Id: id})
+	return err
+}
+
+func (s *RaftStore) AddDeadLetter(deadLetter DeadLetter) error {
+	_, err := s.apply(raftCommand{Op: raftAddDeadLetter, DeadLetter: deadLetter})
+	return err
+}
+
+func (s *RaftStore) PutWaitlistEntry(entry WaitlistEntry) error {
+	_, err := s.apply(raftCommand{Op: raftPutWaitlistEntry, Entry: entry})
+	return err
+}
+
+func (s *RaftStore) RemoveWaitlistEntry(id string) error {
+	_, err := s.apply(raftCommand{Op: raftRemoveWaitlistEntry, Id: id})
+	return err
+}
+
+func (s *RaftStore) Rooms() ([]RoomInfo, error) {
+	return s.fsm.current().Rooms()
+}
+
+func (s *RaftStore) Reservation(id string) (Reservation, error) {
+	return s.fsm.current().Reservation(id)
+}
+
+func (s *RaftStore) ReservationByCode(code string) (Reservation, error) {
+	return s.fsm.current().ReservationByCode(code)
+}
+
+func (s *RaftStore) Reservations(user string) ([]Reservation, error) {
+	return s.fsm.current().Reservations(user)
+}
+
+func (s *RaftStore) Query(date time.Time) (map[int]string, error) {
+	return s.fsm.current().Query(date)
+}
+
+func (s *RaftStore) CountFree(date time.Time, rooms RoomSet) (int, error) {
+	return s.fsm.current().CountFree(date, rooms)
+}
+
+func (s *RaftStore) FreeRooms(from, to time.Time, rooms RoomSet) (RoomSet, error) {
+	return s.fsm.current().FreeRooms(from, to, rooms)
+}
+
+func (s *RaftStore) Bookings(room int) (map[time.Time]string, error) {
+	return s.fsm.current().Bookings(room)
+}
+
+func (s *RaftStore) ReservationsBetween(from, to time.Time) ([]Reservation, error) {
+	return s.fsm.current().ReservationsBetween(from, to)
+}
+
+func (s *RaftStore) Blackouts() ([]Blackout, error) {
+	return s.fsm.current().Blackouts()
+}
+
+func (s *RaftStore) Webhooks() ([]Webhook, error) {
+	return s.fsm.current().Webhooks()
+}
+
+func (s *RaftStore) DeadLetters() ([]DeadLetter, error) {
+	return s.fsm.current().DeadLetters()
+}
+
+func (s *RaftStore) Waitlist() ([]WaitlistEntry, error) {
+	return s.fsm.current().Waitlist()
+}
+
+func (s *RaftStore) Snapshot() (Snapshot, error) {
+	return s.fsm.current().Snapshot()
+}
@@ -0,0 +1,408 @@
+package rooms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-booking-service/pb"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"gotest.tools/assert"
+)
+
+type testSnapshotSink struct {
+	bytes.Buffer
+	cancelled bool
+}
+
+func (s *testSnapshotSink) ID() string {
+	return "test"
+}
+
+func (s *testSnapshotSink) Cancel() error {
+	s.cancelled = true
+	return nil
+}
+
+func (s *testSnapshotSink) Close() error {
+	return nil
+}
+
+func applyTestCommand(t *testing.T, fsm *StoreFSM, cmd raftCommand) raftResult {
+	data, err := json.Marshal(cmd)
+	assert.NilError(t, err)
+	return fsm.Apply(&raft.Log{Data: data}).(raftResult)
+}
+
+func TestStoreFSM(t *testing.T) {
+	t.Log("StoreFSM")
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	fsm := NewStoreFSM(testRooms(2))
+
+	t.Logf("should apply the changes of the log")
+	result := applyTestCommand(t, fsm, raftCommand{Op: raftReserve, Reservations: []Reservation{stay("1", "John", 1, date, 2)}})
+	assert.DeepEqual(t, result, raftResult{Ok: true})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftReserve, Reservations: []Reservation{stay("2", "Anna", 1, date, 1)}})
+	assert.DeepEqual(t, result, raftResult{})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftMove, Id: "1", Room: RoomInfo{Id: 2}, From: date, To: date.AddDate(0, 0, 2)})
+	moved := stay("1", "John", 2, date, 2)
+	moved.Version = 1
+	assert.DeepEqual(t, result, raftResult{Reservation: moved, Ok: true})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftAddBlackout, Blackout: testBlackout})
+	assert.DeepEqual(t, result, raftResult{})
+	users, err := fsm.current().Query(date)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, users, map[int]string{2: "John"})
+
+	t.Logf("should replicate the webhooks, dead letters and waitlist")
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftPutWaitlistEntry, Entry: snapshotTestWaitlistEntry})
+	assert.DeepEqual(t, result, raftResult{})
+	entries, err := fsm.current().Waitlist()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, entries, []WaitlistEntry{snapshotTestWaitlistEntry})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftAddWebhook, Webhook: testWebhook})
+	assert.DeepEqual(t, result, raftResult{})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftAddDeadLetter, DeadLetter: testDeadLetter})
+	assert.DeepEqual(t, result, raftResult{})
+	webhooks, err := fsm.current().Webhooks()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, webhooks, []Webhook{testWebhook})
+	deadLetters, err := fsm.current().DeadLetters()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, deadLetters, []DeadLetter{testDeadLetter})
+
+	t.Logf("should not book an idempotency key twice")
+	keyed := stay("3", "Anna", 1, date.AddDate(0, 0, 5), 1)
+	keyed.Key = "retry-1"
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftReserve, Reservations: []Reservation{keyed}})
+	assert.DeepEqual(t, result, raftResult{Ok: true})
+	keyed.Id, keyed.Room = "4", 2
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftReserve, Reservations: []Reservation{keyed}})
+	assert.DeepEqual(t, result, raftResult{Err: ErrIdempotencyKeyUsed()})
+	assert.NilError(t, fsm.current().Release("3", 0))
+
+	t.Logf("should return the errors of the store")
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftRelease, Id: "2"})
+	assert.DeepEqual(t, result, raftResult{Err: ErrBookingNotFound()})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftRemoveWebhook, Id: "unknown"})
+	assert.DeepEqual(t, result, raftResult{Err: ErrWebhookNotFound()})
+	result = applyTestCommand(t, fsm, raftCommand{Op: raftRemoveWaitlistEntry, Id: "unknown"})
+	assert.DeepEqual(t, result, raftResult{Err: ErrWaitlistEntryNotFound()})
+	result = applyTestCommand(t, fsm, raftCommand{Op: "unknown"})
+	assert.DeepEqual(t, result, raftResult{Err: ErrInvalidRequestStructure()})
+	assert.Assert(t, fsm.Apply(&raft.Log{Data: []byte("not json")}).(raftResult).Err != nil)
+
+	t.Logf("should restore the store from a snapshot")
+	snapshot, err := fsm.Snapshot()
+	assert.NilError(t, err)
+	var sink testSnapshotSink
+	assert.NilError(t, snapshot.Persist(&sink))
+	assert.Assert(t, !sink.cancelled)
+	restored := NewStoreFSM(testRooms(3))
+	assert.NilError(t, restored.Restore(ioutil.NopCloser(&sink)))
+	want, err := fsm.current().Snapshot()
+	assert.NilError(t, err)
+	got, err := restored.current().Snapshot()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, want)
+}
+
+type testNode struct {
+	id        string
+	raft      *raft.Raft
+	transport *raft.InmemTransport
+	store     *RaftStore
+	service   RoomsService
+	endpoints Endpoints
+	server    *grpc.Server
+	listener  *bufconn.Listener
+}
+
+// Starts a cluster of nodes with a number of rooms, connected in memory
+// Nodes are served over gRPC and forward changes to the leader
+func newTestCluster(t *testing.T, nodes, rooms int) []*testNode {
+	cluster := make([]*testNode, nodes)
+	listeners := map[string]*bufconn.Listener{}
+	var configuration raft.Configuration
+	for i := range cluster {
+		id := fmt.Sprintf("node%d", i+1)
+		addr, transport := raft.NewInmemTransport(raft.ServerAddress(id))
+		cluster[i] = &testNode{id: id, transport: transport, listener: bufconn.Listen(1024 * 1024)}
+		listeners[id] = cluster[i].listener
+		configuration.Servers = append(configuration.Servers, raft.Server{ID: raft.ServerID(id), Address: addr})
+	}
+	for _, node := range cluster {
+		for _, peer := range cluster {
+			node.transport.Connect(raft.ServerAddress(peer.id), peer.transport)
+		}
+	}
+	dialer := grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+		return listeners[addr].Dial()
+	})
+
+	for _, node := range cluster {
+		config := raft.DefaultConfig()
+		config.LocalID = raft.ServerID(node.id)
+		config.HeartbeatTimeout = 50 * time.Millisecond
+		config.ElectionTimeout = 50 * time.Millisecond
+		config.LeaderLeaseTimeout = 50 * time.Millisecond
+		config.CommitTimeout = 5 * time.Millisecond
+		config.LogOutput = ioutil.Discard
+		logs := raft.NewInmemStore()
+		fsm := NewStoreFSM(testRooms(rooms))
+		r, err := raft.NewRaft(config, fsm, logs, logs, raft.NewInmemSnapshotStore(), node.transport)
+		assert.NilError(t, err)
+		assert.NilError(t, r.BootstrapCluster(configuration).Error())
+		node.raft = r
+		node.store = NewRaftStore(r, fsm, 5*time.Second)
+
+		node.service = NewRoomsServer(node.store, validatorUser{})
+		node.endpoints = ForwardToLeader(MakeEndpoints(node.service), node.store.Leader, 5*time.Second, dialer, grpc.WithInsecure())
+		node.server = grpc.NewServer()
+		pb.RegisterRoomsServer(node.server, NewGRPCServer(node.endpoints))
+		go node.server.Serve(node.listener)
+	}
+	return cluster
+}
+
+func stopTestNode(node *testNode, cluster []*testNode) {
+	node.server.Stop()
+	node.listener.Close()
+	node.raft.Shutdown().Error()
+	for _, peer := range cluster {
+		peer.transport.Disconnect(raft.ServerAddress(node.id))
+	}
+}
+
+func stopTestCluster(cluster []*testNode) {
+	for _, node := range cluster {
+		stopTestNode(node, cluster)
+	}
+}
+
+// Returns the leader elected among the nodes, failing if none is elected in time
+func waitTestLeader(t *testing.T, nodes []*testNode) *testNode {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, node := range nodes {
+			if node.raft.State() == raft.Leader {
+				return node
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no leader elected among %d nodes", len(nodes))
+	return nil
+}
+
+// Fails unless the snapshots of the nodes become equal in time
+func waitTestReplicated(t *testing.T, nodes []*testNode) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		want, err := nodes[0].store.Snapshot()
+		assert.NilError(t, err)
+		replicated := true
+		for _, node := range nodes[1:] {
+			got, err := node.store.Snapshot()
+			assert.NilError(t, err)
+			replicated = replicated && reflect.DeepEqual(got, want)
+		}
+		if replicated {
+			return want
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stores of %d nodes not replicated", len(nodes))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRaftCluster(t *testing.T) {
+	t.Log("RaftCluster")
+	ctx := context.Background()
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	cluster := newTestCluster(t, 3, 2)
+	defer stopTestCluster(cluster)
+	leader := waitTestLeader(t, cluster)
+	var follower *testNode
+	for _, node := range cluster {
+		if node != leader {
+			follower = node
+		}
+	}
+
+	t.Logf("should only accept changes on the leader")
+	_, err := follower.store.Reserve(stay("1", "John", 1, date, 1))
+	assert.DeepEqual(t, err, ErrNotLeader())
+	addr, self := follower.store.Leader()
+	assert.Equal(t, addr, leader.id)
+	assert.Assert(t, !self)
+
+	t.Logf("should forward bookings made on followers to the leader and replicate them")
+	booked, err := follower.endpoints.Book(ctx, "John", "", 2, date, date.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	reservation, err := leader.store.Reservation(booked[1].Id)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reservation, booked[1])
+	snapshot := waitTestReplicated(t, cluster)
+	sortReservations(booked)
+	assert.DeepEqual(t, snapshot.Reservations, booked)
+
+	t.Logf("should return the errors of the leader")
+	_, err = follower.endpoints.Book(ctx, "Anna", "", 1, date, date.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrNoRoomAvailable())
+
+	t.Logf("should stream the availability changed by the leader to the watchers of followers")
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	from := date.AddDate(0, 0, 2)
+	updates, err := follower.service.WatchAvailability(watchCtx, from, from, RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, <-updates, []DayAvailability{{Date: from, Available: 2}})
+	_, err = leader.endpoints.Book(ctx, "Anna", "", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	select {
+	case changed := <-updates:
+		assert.DeepEqual(t, changed, []DayAvailability{{Date: from, Available: 1}})
+	case <-time.After(5 * time.Second):
+		t.Fatal("no availability update on the follower")
+	}
+
+	t.Logf("should return the booking of an idempotency key retried on a new leader")
+	keyed, err := leader.endpoints.Book(ctx, "Bob", "retry-1", 1, date.AddDate(0, 0, 5), date.AddDate(0, 0, 6), RoomFilter{})
+	assert.NilError(t, err)
+	waitTestReplicated(t, cluster)
+	var alive []*testNode
+	for _, node := range cluster {
+		if node != leader {
+			alive = append(alive, node)
+		}
+	}
+	stopTestNode(leader, cluster)
+	elected := waitTestLeader(t, alive)
+	retried, err := elected.endpoints.Book(ctx, "Bob", "retry-1", 1, date.AddDate(0, 0, 5), date.AddDate(0, 0, 6), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, retried[0].Id, keyed[0].Id)
+	reservations, err := elected.store.Reservations("Bob")
+	assert.NilError(t, err)
+	assert.Equal(t, len(reservations), 1)
+}
+
+func TestRaftClusterLeaderFailure(t *testing.T) {
+	t.Log("RaftClusterLeaderFailure")
+	ctx := context.Background()
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	const rooms, nights, users = 2, 10, 6
+	cluster := newTestCluster(t, 3, rooms)
+	defer stopTestCluster(cluster)
+	leader := waitTestLeader(t, cluster)
+
+	var (
+		mux       sync.Mutex
+		alive     = append([]*testNode{}, cluster...)
+		confirmed []Reservation
+		wg        sync.WaitGroup
+	)
+	// Books every night for a user, retrying through the nodes alive
+	// until booked or every room is taken
+	book := func(user string) {
+		defer wg.Done()
+		attempt := 0
+		for night := 0; night < nights; night++ {
+			from := date.AddDate(0, 0, night)
+			for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); attempt++ {
+				mux.Lock()
+				node := alive[attempt%len(alive)]
+				mux.Unlock()
+				callCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+				booked, err := node.endpoints.Book(callCtx, user, "", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+				cancel()
+				if err == nil {
+					mux.Lock()
+					confirmed = append(confirmed, booked...)
+					mux.Unlock()
+					break
+				}
+				if err == ErrNoRoomAvailable() {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		}
+	}
+	for user := 0; user < users; user++ {
+		wg.Add(1)
+		go book(fmt.Sprintf("User%d", user))
+	}
+
+	t.Logf("should elect a new leader when the leader is killed mid-booking")
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		mux.Lock()
+		booked := len(confirmed)
+		mux.Unlock()
+		if booked >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bookings confirmed before the leader failure, want 3", booked)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mux.Lock()
+	for i, node := range alive {
+		if node == leader {
+			alive = append(alive[:i], alive[i+1:]...)
+			break
+		}
+	}
+	mux.Unlock()
+	stopTestNode(leader, cluster)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatalf("bookings still retried a minute after the leader failure")
+	}
+	elected := waitTestLeader(t, alive)
+	assert.Assert(t, elected != leader)
+	assert.NilError(t, elected.raft.Barrier(5*time.Second).Error())
+
+	t.Logf("should keep every confirmed booking")
+	snapshot := waitTestReplicated(t, alive)
+	stored := map[string]Reservation{}
+	for _, reservation := range snapshot.Reservations {
+		stored[reservation.Id] = reservation
+	}
+	for _, booking := range confirmed {
+		assert.DeepEqual(t, stored[booking.Id], booking)
+	}
+
+	t.Logf("should never book a room twice for the same night")
+	booked := map[int]map[time.Time]string{}
+	for _, reservation := range snapshot.Reservations {
+		if booked[reservation.Room] == nil {
+			booked[reservation.Room] = map[time.Time]string{}
+		}
+		for night := reservation.From; night.Before(reservation.To); night = night.AddDate(0, 0, 1) {
+			other, taken := booked[reservation.Room][night]
+			assert.Assert(t, !taken, "room %d booked by %s and %s on %s", reservation.Room, other, reservation.Id, night)
+			booked[reservation.Room][night] = reservation.Id
+		}
+	}
+	assert.Assert(t, len(snapshot.Reservations) <= rooms*nights)
+}
@@ -0,0 +1,91 @@
+package rooms
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// Reservation is a stay booked by a user in a room,
+// from check-in (included) to check-out (excluded)
+// A hold is a tentative reservation released when it expires unless confirmed,
+// Expires is zero for confirmed reservations
+type Reservation struct {
+	Id      string    `json:"id"`
+	Code    string    `json:"code"`
+	User    string    `json:"user"`
+	Room    int       `json:"room"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Expires time.Time `json:"expires"`
+	// Starts at 1 and goes up every time the reservation is moved or confirmed
+	Version int `json:"version"`
+	// Idempotency key of the request that booked it, if any
+	Key string `json:"key,omitempty"`
+}
+
+// Returns every night of the reservation
+func (r Reservation) Nights() []time.Time {
+	var dates []time.Time
+	for date := r.From; date.Before(r.To); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// Returns true if the reservation is a hold waiting for confirmation
+func (r Reservation) Held() bool {
+	return !r.Expires.IsZero()
+}
+
+// Returns true if the reservation is a hold expired at a time
+func (r Reservation) Expired(now time.Time) bool {
+	return r.Held() && !now.Before(r.Expires)
+}
+
+// Returns ErrVersionConflict unless the version expected is 0 (any) or the version of the reservation
+func (r Reservation) checkVersion(version int) error {
+	if version != 0 && version != r.Version {
+		return ErrVersionConflict()
+	}
+	return nil
+}
+
+// Confirmation codes leave out characters that are easily mistaken (0/O, 1/I)
+// The alphabet has 32 characters so every random byte maps to it without bias
+const (
+	codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codeLength   = 6
+)
+
+// How many times a reservation is retried with new identifiers
+// if they collide with an existing one
+const maxReservationAttempts = 3
+
+// Returns a random reservation id
+func newReservationID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Returns a random, short and human readable confirmation code
+func newConfirmationCode() string {
+	b := make([]byte, codeLength)
+	rand.Read(b)
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b)
+}
+
+// Sorts reservations by check-in, then by id
+func sortReservations(reservations []Reservation) {
+	sort.Slice(reservations, func(i, j int) bool {
+		if !reservations[i].From.Equal(reservations[j].From) {
+			return reservations[i].From.Before(reservations[j].From)
+		}
+		return reservations[i].Id < reservations[j].Id
+	})
+}
@@ -0,0 +1,93 @@
+package rooms
+
+import (
+	"go-booking-service/pb"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var testReservation = Reservation{
+	Id:      "6b1f0f0ee8d5e0b4b0a7f3c2a9d8c1e2",
+	Code:    "K7QX9M",
+	User:    "John",
+	Room:    1,
+	From:    time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:      time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+	Version: 1,
+}
+
+var testPBReservation = &pb.Reservation{
+	Id:      "6b1f0f0ee8d5e0b4b0a7f3c2a9d8c1e2",
+	Code:    "K7QX9M",
+	User:    "John",
+	Room:    1,
+	From:    time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(),
+	To:      time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC).Unix(),
+	Version: 1,
+}
+
+var testHold = Reservation{
+	Id:      "9d2c4e6f8a0b1c3d5e7f9a1b3c5d7e9f",
+	Code:    "H4LD2P",
+	User:    "John",
+	Room:    1,
+	From:    time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:      time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+	Expires: time.Date(2020, 6, 1, 12, 15, 0, 0, time.UTC),
+}
+
+var testPBHold = &pb.Reservation{
+	Id:      "9d2c4e6f8a0b1c3d5e7f9a1b3c5d7e9f",
+	Code:    "H4LD2P",
+	User:    "John",
+	Room:    1,
+	From:    time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(),
+	To:      time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC).Unix(),
+	Expires: time.Date(2020, 6, 1, 12, 15, 0, 0, time.UTC).Unix(),
+}
+
+func TestReservationExpired(t *testing.T) {
+	t.Log("ReservationExpired")
+
+	assert.Assert(t, !testReservation.Held())
+	assert.Assert(t, !testReservation.Expired(testHold.Expires))
+	assert.Assert(t, testHold.Held())
+	assert.Assert(t, !testHold.Expired(testHold.Expires.Add(-time.Second)))
+	assert.Assert(t, testHold.Expired(testHold.Expires))
+}
+
+func TestReservationNights(t *testing.T) {
+	t.Log("ReservationNights")
+
+	assert.DeepEqual(t, testReservation.Nights(), []time.Time{
+		time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+	})
+}
+
+func TestNewConfirmationCode(t *testing.T) {
+	t.Log("NewConfirmationCode")
+
+	for i := 0; i < 100; i++ {
+		code := newConfirmationCode()
+		assert.Equal(t, len(code), codeLength)
+		for _, c := range code {
+			assert.Assert(t, strings.ContainsRune(codeAlphabet, c), code)
+		}
+	}
+}
+
+func TestNewReservationID(t *testing.T) {
+	t.Log("NewReservationID")
+
+	ids := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := newReservationID()
+		assert.Equal(t, len(id), 32)
+		assert.Assert(t, !ids[id], id)
+		ids[id] = true
+	}
+}
@@ -0,0 +1,91 @@
+package rooms
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Room types
+const (
+	SingleRoom = "single"
+	DoubleRoom = "double"
+	SuiteRoom  = "suite"
+)
+
+// RoomInfo describes a room
+type RoomInfo struct {
+	Id        int      `json:"id"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Capacity  int      `json:"capacity"`
+	Amenities []string `json:"amenities"`
+}
+
+// Highest room id, rooms are indexed by id
+const maxRoomID = 1 << 16
+
+// Room is a room kept in memory along with its bookings
+type Room struct {
+	RoomInfo
+	Book map[time.Time]string
+	Mux  *sync.Mutex
+}
+
+// RoomFilter selects rooms by their attributes
+// Zero values match every room
+type RoomFilter struct {
+	Type        string   `json:"type"`
+	MinCapacity int      `json:"min_capacity"`
+	Amenities   []string `json:"amenities"`
+}
+
+// Returns an error if the filter asks for an unknown room type
+func (f RoomFilter) Validate() error {
+	if f.Type != "" && !validRoomType(f.Type) {
+		return ErrInvalidRoomType()
+	}
+	return nil
+}
+
+// Returns true if the filter matches every room
+func (f RoomFilter) Empty() bool {
+	return f.Type == "" && f.MinCapacity <= 0 && len(f.Amenities) == 0
+}
+
+// Returns true if the room satisfies every criteria of the filter
+func (f RoomFilter) Match(room RoomInfo) bool {
+	if f.Type != "" && f.Type != room.Type {
+		return false
+	}
+	if room.Capacity < f.MinCapacity {
+		return false
+	}
+	for _, required := range f.Amenities {
+		if !hasAmenity(room, required) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAmenity(room RoomInfo, amenity string) bool {
+	for _, a := range room.Amenities {
+		if a == amenity {
+			return true
+		}
+	}
+	return false
+}
+
+func validRoomType(t string) bool {
+	switch t {
+	case SingleRoom, DoubleRoom, SuiteRoom:
+		return true
+	}
+	return false
+}
+
+func sortRooms(rooms []RoomInfo) {
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Id < rooms[j].Id })
+}
@@ -0,0 +1,104 @@
+package rooms
+
+import "math/bits"
+
+// RoomSet is a set of room ids kept as a bitmap, bit i of word i/64 is room i
+// Sets are small even for thousands of rooms and are combined a word at a time
+type RoomSet []uint64
+
+// Returns the set of rooms with the ids
+func NewRoomSet(ids ...int) RoomSet {
+	s := RoomSet{}
+	for _, id := range ids {
+		s = s.Add(id)
+	}
+	return s
+}
+
+// Adds a room to the set, growing it if needed
+// Returns the set, which must be used instead of the original
+func (s RoomSet) Add(id int) RoomSet {
+	word := id / 64
+	if word >= len(s) {
+		grown := make(RoomSet, word+1)
+		copy(grown, s)
+		s = grown
+	}
+	s[word] |= 1 << uint(id%64)
+	return s
+}
+
+// Removes a room from the set
+func (s RoomSet) Remove(id int) {
+	if word := id / 64; word < len(s) {
+		s[word] &^= 1 << uint(id%64)
+	}
+}
+
+// Returns true if the room is in the set
+func (s RoomSet) Has(id int) bool {
+	word := id / 64
+	return word < len(s) && s[word]&(1<<uint(id%64)) != 0
+}
+
+// Returns the number of rooms in the set
+func (s RoomSet) Len() int {
+	var n int
+	for _, word := range s {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Returns a copy of the set
+func (s RoomSet) Clone() RoomSet {
+	clone := make(RoomSet, len(s))
+	copy(clone, s)
+	return clone
+}
+
+// Returns a new set with the rooms that are in both sets
+func (s RoomSet) And(other RoomSet) RoomSet {
+	if len(other) < len(s) {
+		s = s[:len(other)]
+	}
+	result := s.Clone()
+	for i := range result {
+		result[i] &= other[i]
+	}
+	return result
+}
+
+// Returns a new set with the rooms of the set that are not in another
+func (s RoomSet) AndNot(other RoomSet) RoomSet {
+	result := s.Clone()
+	for i := 0; i < len(result) && i < len(other); i++ {
+		result[i] &^= other[i]
+	}
+	return result
+}
+
+// Returns the number of rooms of the set that are not in another
+func (s RoomSet) CountAndNot(other RoomSet) int {
+	var n int
+	for i, word := range s {
+		if i < len(other) {
+			word &^= other[i]
+		}
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Returns the ids of the rooms in the set in ascending order
+func (s RoomSet) Ids() []int {
+	ids := make([]int, 0, s.Len())
+	for i, word := range s {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			ids = append(ids, i*64+bit)
+			word &^= 1 << uint(bit)
+		}
+	}
+	return ids
+}
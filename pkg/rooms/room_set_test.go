@@ -0,0 +1,34 @@
+package rooms
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRoomSet(t *testing.T) {
+	t.Log("RoomSet")
+
+	s := NewRoomSet(1, 3, 64, 130)
+	assert.DeepEqual(t, s.Ids(), []int{1, 3, 64, 130})
+	assert.Equal(t, s.Len(), 4)
+	assert.Assert(t, s.Has(64))
+	assert.Assert(t, !s.Has(2))
+	assert.Assert(t, !s.Has(1000))
+
+	t.Logf("should remove rooms without changing the copies")
+	c := s.Clone()
+	s.Remove(64)
+	s.Remove(1000)
+	assert.DeepEqual(t, s.Ids(), []int{1, 3, 130})
+	assert.DeepEqual(t, c.Ids(), []int{1, 3, 64, 130})
+
+	t.Logf("should return the rooms not in another set")
+	other := NewRoomSet(3, 64)
+	assert.DeepEqual(t, c.AndNot(other).Ids(), []int{1, 130})
+	assert.Equal(t, c.CountAndNot(other), 2)
+	assert.DeepEqual(t, c.And(other).Ids(), []int{3, 64})
+	assert.DeepEqual(t, other.And(c).Ids(), []int{3, 64})
+	assert.DeepEqual(t, other.AndNot(c).Ids(), []int{})
+	assert.Equal(t, NewRoomSet().Len(), 0)
+}
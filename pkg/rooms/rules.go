@@ -0,0 +1,201 @@
+package rooms
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Machine-readable reasons of the stays rejected by booking rules
+const (
+	ReasonPastCheckIn   = "past_check_in"
+	ReasonMinStay       = "min_stay"
+	ReasonMaxStay       = "max_stay"
+	ReasonMaxLeadTime   = "max_lead_time"
+	ReasonSameDayCutoff = "same_day_cutoff"
+)
+
+// RuleViolation is returned when a stay breaks a booking rule
+type RuleViolation struct {
+	Reason string `json:"reason"`
+	Msg    string `json:"message"`
+}
+
+func (e RuleViolation) Error() string {
+	return e.Msg
+}
+
+// Rule restricts the stays that can be booked
+type Rule interface {
+	// Returns a RuleViolation if the stay can not be booked at a time
+	Check(stay Reservation, now time.Time) error
+}
+
+// RuleFunc adapts a function to a Rule
+type RuleFunc func(stay Reservation, now time.Time) error
+
+func (f RuleFunc) Check(stay Reservation, now time.Time) error {
+	return f(stay, now)
+}
+
+// WithRules restricts the stays bookable in rooms of a type, every room if the type is empty
+// Rules are checked in order and the first one broken rejects the stay
+func WithRules(roomType string, rules ...Rule) Option {
+	return func(r *roomsService) {
+		r.rules[roomType] = append(r.rules[roomType], rules...)
+	}
+}
+
+// WithClock sets the time rules, quotas and holds are checked against, time.Now if not configured
+func WithClock(now func() time.Time) Option {
+	return func(r *roomsService) {
+		r.now = now
+	}
+}
+
+// NoPastCheckIn rejects stays starting before today
+func NoPastCheckIn() Rule {
+	return RuleFunc(func(stay Reservation, now time.Time) error {
+		if stay.From.Before(today(now)) {
+			return RuleViolation{ReasonPastCheckIn, "Check-in is in the past"}
+		}
+		return nil
+	})
+}
+
+// MinStay rejects stays shorter than a number of nights
+// If weekdays are given, only stays including the night of one of them are restricted
+func MinStay(nights int, weekdays ...time.Weekday) Rule {
+	msg := fmt.Sprintf("Stays must be at least %d nights", nights)
+	if len(weekdays) > 0 {
+		names := make([]string, len(weekdays))
+		for i, weekday := range weekdays {
+			names[i] = weekday.String()
+		}
+		msg = fmt.Sprintf("Stays including a %s night must be at least %d nights", strings.Join(names, " or "), nights)
+	}
+	return RuleFunc(func(stay Reservation, now time.Time) error {
+		stayNights := stay.Nights()
+		if len(stayNights) >= nights || !includesWeekday(stayNights, weekdays) {
+			return nil
+		}
+		return RuleViolation{ReasonMinStay, msg}
+	})
+}
+
+// MaxStay rejects stays longer than a number of nights
+func MaxStay(nights int) Rule {
+	msg := fmt.Sprintf("Stays must be at most %d nights", nights)
+	return RuleFunc(func(stay Reservation, now time.Time) error {
+		if len(stay.Nights()) > nights {
+			return RuleViolation{ReasonMaxStay, msg}
+		}
+		return nil
+	})
+}
+
+// MaxLeadTime rejects stays starting more than a number of days after today
+func MaxLeadTime(days int) Rule {
+	msg := fmt.Sprintf("Check-in must be at most %d days ahead", days)
+	return RuleFunc(func(stay Reservation, now time.Time) error {
+		if stay.From.After(today(now).AddDate(0, 0, days)) {
+			return RuleViolation{ReasonMaxLeadTime, msg}
+		}
+		return nil
+	})
+}
+
+// SameDayCutoff rejects stays starting today once an hour (UTC) is reached
+func SameDayCutoff(hour int) Rule {
+	msg := fmt.Sprintf("Same-day check-in closes at %02d:00", hour)
+	return RuleFunc(func(stay Reservation, now time.Time) error {
+		start := today(now)
+		if stay.From.Equal(start) && now.Sub(start) >= time.Duration(hour)*time.Hour {
+			return RuleViolation{ReasonSameDayCutoff, msg}
+		}
+		return nil
+	})
+}
+
+func today(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// Returns whether any night is on one of the weekdays, true if there are none
+func includesWeekday(nights []time.Time, weekdays []time.Weekday) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, night := range nights {
+		for _, weekday := range weekdays {
+			if night.Weekday() == weekday {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the first rule for every room and for rooms of a type broken by the stay
+func (r roomsService) checkRules(stay Reservation, roomType string) error {
+	if len(r.rules) == 0 {
+		return nil
+	}
+	now := r.now().UTC()
+	for _, rule := range r.rules[""] {
+		if err := rule.Check(stay, now); err != nil {
+			return err
+		}
+	}
+	if roomType == "" {
+		return nil
+	}
+	for _, rule := range r.rules[roomType] {
+		if err := rule.Check(stay, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the rooms whose type rules reject the stay
+// and the rejection of the first of those types by name
+func (r roomsService) ruledOut(stay Reservation) (rooms RoomSet, violation error, err error) {
+	if len(r.rules) == 0 {
+		return RoomSet{}, nil, nil
+	}
+	now := r.now().UTC()
+	rejected := map[string]error{}
+	for roomType, rules := range r.rules {
+		if roomType == "" {
+			continue
+		}
+		for _, rule := range rules {
+			if err := rule.Check(stay, now); err != nil {
+				rejected[roomType] = err
+				break
+			}
+		}
+	}
+	rooms = RoomSet{}
+	if len(rejected) == 0 {
+		return rooms, nil, nil
+	}
+
+	types := make([]string, 0, len(rejected))
+	for roomType := range rejected {
+		types = append(types, roomType)
+	}
+	sort.Strings(types)
+	infos, err := r.store.Rooms()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, info := range infos {
+		if _, ok := rejected[info.Type]; ok {
+			rooms = rooms.Add(info.Id)
+		}
+	}
+	return rooms, rejected[types[0]], nil
+}
@@ -0,0 +1,154 @@
+package rooms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// Friday 2020-06-12 at 21:30
+var rulesTestNow = time.Date(2020, 6, 12, 21, 30, 0, 0, time.UTC)
+
+func rulesTestClock() time.Time {
+	return rulesTestNow
+}
+
+var ruleTest = []struct {
+	name string
+	rule Rule
+	stay Reservation
+	want error
+}{
+	{
+		name: "no-past-check-in should accept stays starting today",
+		rule: NoPastCheckIn(),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC), 1),
+	},
+	{
+		name: "no-past-check-in should reject stays starting yesterday",
+		rule: NoPastCheckIn(),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 11, 0, 0, 0, 0, time.UTC), 2),
+		want: RuleViolation{ReasonPastCheckIn, "Check-in is in the past"},
+	},
+	{
+		name: "min-stay should reject shorter stays",
+		rule: MinStay(3),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 2),
+		want: RuleViolation{ReasonMinStay, "Stays must be at least 3 nights"},
+	},
+	{
+		name: "min-stay on saturdays should reject short stays including a saturday night",
+		rule: MinStay(2, time.Saturday),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 1),
+		want: RuleViolation{ReasonMinStay, "Stays including a Saturday night must be at least 2 nights"},
+	},
+	{
+		name: "min-stay on saturdays should accept short stays on other nights",
+		rule: MinStay(2, time.Saturday),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC), 1),
+	},
+	{
+		name: "min-stay on saturdays should accept long enough stays including a saturday night",
+		rule: MinStay(2, time.Saturday),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC), 2),
+	},
+	{
+		name: "max-stay should reject longer stays",
+		rule: MaxStay(14),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 15),
+		want: RuleViolation{ReasonMaxStay, "Stays must be at most 14 nights"},
+	},
+	{
+		name: "max-lead-time should accept stays starting on the last day",
+		rule: MaxLeadTime(365),
+		stay: stay("1", "John", 0, time.Date(2021, 6, 12, 0, 0, 0, 0, time.UTC), 1),
+	},
+	{
+		name: "max-lead-time should reject stays starting later",
+		rule: MaxLeadTime(365),
+		stay: stay("1", "John", 0, time.Date(2021, 6, 13, 0, 0, 0, 0, time.UTC), 1),
+		want: RuleViolation{ReasonMaxLeadTime, "Check-in must be at most 365 days ahead"},
+	},
+	{
+		name: "same-day-cutoff should reject stays starting today after the cutoff",
+		rule: SameDayCutoff(21),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC), 1),
+		want: RuleViolation{ReasonSameDayCutoff, "Same-day check-in closes at 21:00"},
+	},
+	{
+		name: "same-day-cutoff should accept stays starting today before the cutoff",
+		rule: SameDayCutoff(22),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC), 1),
+	},
+	{
+		name: "same-day-cutoff should accept stays starting tomorrow",
+		rule: SameDayCutoff(21),
+		stay: stay("1", "John", 0, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 1),
+	},
+}
+
+func TestRule(t *testing.T) {
+	t.Log("Rule")
+
+	for _, testcase := range ruleTest {
+		t.Logf(testcase.name)
+
+		assert.DeepEqual(t, testcase.rule.Check(testcase.stay, rulesTestNow), testcase.want)
+	}
+}
+
+func newRulesTestService(rules ...Option) RoomsService {
+	store := NewMemoryStore(nil)
+	store.AddRoom(RoomInfo{Id: 1, Name: "101", Type: SingleRoom, Capacity: 1})
+	store.AddRoom(RoomInfo{Id: 2, Name: "201", Type: SuiteRoom, Capacity: 4})
+	options := append([]Option{WithClock(rulesTestClock)}, rules...)
+	return NewRoomsServer(store, validatorCorrect{}, options...)
+}
+
+func TestServiceBookRules(t *testing.T) {
+	t.Log("ServiceBookRules")
+	ctx := context.Background()
+	saturday := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	rs := newRulesTestService(
+		WithRules("", NoPastCheckIn(), MaxLeadTime(365)),
+		WithRules(SuiteRoom, MinStay(2, time.Saturday)),
+	)
+
+	t.Logf("should reject stays breaking the rules for every room")
+	_, err := rs.Book(ctx, "jjj.www.ttt", "", 1, saturday.AddDate(0, 0, -2), saturday, RoomFilter{})
+	assert.DeepEqual(t, err, RuleViolation{ReasonPastCheckIn, "Check-in is in the past"})
+	_, err = rs.Hold(ctx, "jjj.www.ttt", saturday.AddDate(2, 0, 0), saturday.AddDate(2, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, RuleViolation{ReasonMaxLeadTime, "Check-in must be at most 365 days ahead"})
+	_, err = rs.JoinWaitlist(ctx, "jjj.www.ttt", saturday.AddDate(0, 0, -2), saturday, RoomFilter{})
+	assert.DeepEqual(t, err, RuleViolation{ReasonPastCheckIn, "Check-in is in the past"})
+
+	t.Logf("should reject stays breaking the rules of the requested room type")
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", 1, saturday, saturday.AddDate(0, 0, 1), RoomFilter{Type: SuiteRoom})
+	assert.DeepEqual(t, err, RuleViolation{ReasonMinStay, "Stays including a Saturday night must be at least 2 nights"})
+
+	t.Logf("should book rooms of other types instead")
+	reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 1, saturday, saturday.AddDate(0, 0, 1), RoomFilter{Type: SingleRoom})
+	assert.NilError(t, err)
+	assert.Equal(t, reservations[0].Room, 1)
+
+	t.Logf("should check bookings moved to another room against the rules of its type")
+	_, err = rs.ModifyBooking(ctx, "jjj.www.ttt", reservations[0].Id, time.Time{}, time.Time{}, 2, 0)
+	assert.DeepEqual(t, err, RuleViolation{ReasonMinStay, "Stays including a Saturday night must be at least 2 nights"})
+
+	t.Logf("should return the rule violation if only rooms of rejected types are free")
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", 1, saturday, saturday.AddDate(0, 0, 1), RoomFilter{})
+	assert.DeepEqual(t, err, RuleViolation{ReasonMinStay, "Stays including a Saturday night must be at least 2 nights"})
+
+	t.Logf("should book stays following the rules of the room type")
+	reservations, err = rs.Book(ctx, "jjj.www.ttt", "", 1, saturday, saturday.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, reservations[0].Room, 2)
+
+	t.Logf("should check new dates of modified bookings against the rules of the room")
+	_, err = rs.ModifyBooking(ctx, "jjj.www.ttt", reservations[0].Id, saturday, saturday.AddDate(0, 0, 1), 0, 0)
+	assert.DeepEqual(t, err, RuleViolation{ReasonMinStay, "Stays including a Saturday night must be at least 2 nights"})
+	_, err = rs.ModifyBooking(ctx, "jjj.www.ttt", reservations[0].Id, saturday.AddDate(0, 0, 1), saturday.AddDate(0, 0, 2), 0, 0)
+	assert.NilError(t, err)
+}
@@ -2,69 +2,498 @@ package rooms
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type RoomsService interface {
-	Book(context.Context, string, time.Time) (int, error)
-	Check(context.Context, time.Time) (int, error)
+	Book(context.Context, string, string, int, time.Time, time.Time, RoomFilter) ([]Reservation, error)
+	Check(context.Context, time.Time, RoomFilter) (int, error)
+	Cancel(context.Context, string, string, int) error
+	ModifyBooking(context.Context, string, string, time.Time, time.Time, int, int) (Reservation, error)
+	Availability(context.Context, time.Time, time.Time, RoomFilter) ([]DayAvailability, error)
+	WatchAvailability(context.Context, time.Time, time.Time, RoomFilter) (<-chan []DayAvailability, error)
+	ListRooms(context.Context) ([]RoomInfo, error)
+	CreateRoom(context.Context, string, RoomInfo) (RoomInfo, error)
+	UpdateRoom(context.Context, string, RoomInfo) (RoomInfo, error)
+	DecommissionRoom(context.Context, string, int) error
+	ListBookings(context.Context, string, time.Time, time.Time) ([]Booking, error)
+	GetReservation(context.Context, string, string) (Reservation, error)
+	Hold(context.Context, string, time.Time, time.Time, RoomFilter) (Reservation, error)
+	Confirm(context.Context, string, string, int) (Reservation, error)
+	JoinWaitlist(context.Context, string, time.Time, time.Time, RoomFilter) (WaitlistEntry, error)
+	WaitlistPosition(context.Context, string, string) (WaitlistEntry, error)
+	AddBlackout(context.Context, string, Blackout) (Blackout, []Reservation, error)
+	ListBlackouts(context.Context, string) ([]Blackout, error)
+	RemoveBlackout(context.Context, string, string) error
+	History(context.Context, string, string, int) ([]Event, error)
+	Snapshot(context.Context, string) (Snapshot, error)
+	AddWebhook(context.Context, string, Webhook) (Webhook, error)
+	ListWebhooks(context.Context, string) ([]Webhook, error)
+	RemoveWebhook(context.Context, string, string) error
+	DeadLetters(context.Context, string) ([]DeadLetter, error)
 }
 
 type Validator interface {
 	Validate(context.Context, string) (string, error)
 }
 
-func NewRoomsServer(rooms []Room, validator Validator) RoomsService {
-	return roomsService{rooms, validator}
+func NewRoomsServer(store BookingStore, validator Validator, options ...Option) RoomsService {
+	r := roomsService{
+		store:       store,
+		validator:   validator,
+		admins:      map[string]bool{},
+		waitlistMux: &sync.Mutex{},
+		holdTTL:     DefaultHoldTTL,
+		idempotency: newIdempotencyCache(DefaultIdempotencyRetention),
+		allocator:   NewFirstFitAllocator(),
+		rules:       map[string][]Rule{},
+		now:         time.Now,
+		watchers:    newAvailabilityWatchers(),
+	}
+	if watchable, ok := store.(watchableStore); ok {
+		watchable.watch(r.watchers)
+	} else {
+		r.store = watchedStore{BookingStore: store, watchers: r.watchers}
+	}
+	for _, option := range options {
+		option(&r)
+	}
+	if r.reaper.interval > 0 {
+		go r.reapHolds(r.reaper.ctx, r.reaper.interval)
+	}
+	return r
+}
+
+// Option configures the rooms service
+type Option func(*roomsService)
+
+// WithAdmins allows the users to manage the room inventory
+func WithAdmins(users ...string) Option {
+	return func(r *roomsService) {
+		for _, user := range users {
+			r.admins[user] = true
+		}
+	}
 }
 
-type Room struct {
-	Book map[time.Time]string
-	Mux  *sync.Mutex
+// DayAvailability is the number of rooms available for a date
+type DayAvailability struct {
+	Date      time.Time `json:"date"`
+	Available int       `json:"available"`
 }
 
+// Booking is a night of a reservation
+type Booking struct {
+	Id   string    `json:"id"`
+	Code string    `json:"code"`
+	Room int       `json:"room"`
+	Date time.Time `json:"date"`
+}
+
+// Longest date range accepted by Availability and ListBookings
+const maxAvailabilityDays = 366
+
+// Longest stay, so every night of a stay fits in the range Availability accepts
+const maxStayNights = maxAvailabilityDays
+
+// Most rooms booked by a single request
+const maxGroupRooms = 50
+
 type roomsService struct {
-	rooms     []Room
-	validator Validator
+	store       BookingStore
+	validator   Validator
+	admins      map[string]bool
+	waitlistMux *sync.Mutex
+	holdTTL     time.Duration
+	reaper      reaper
+	idempotency *idempotencyCache
+	allocator   Allocator
+	rules       map[string][]Rule
+	now         func() time.Time
+	quota       *quotaLimiter
+	journal     Journal
+	watchers    *availabilityWatchers
+	webhooks    *WebhookDispatcher
 }
 
-// Books an availabe room for a date (write/blocking)
-// Retruns an error if authentication token is invalid
-// or there are no rooms available
-func (r roomsService) Book(ctx context.Context, token string, date time.Time) (int, error) {
+// Books count rooms (1 if 0) matching the filter available for every night
+// from check-in to check-out (write/blocking)
+// Every room is booked for the whole stay or none of them is
+// Requests of a user with the same idempotency key (optional) return the result
+// of the first one while it is retained, unless it failed for a reason a retry may not meet
+// Returns a reservation with its id and confirmation code for every room
+// Retruns an error if authentication token is invalid, the idempotency key is too long,
+// the count is invalid, the stay breaks a booking rule, the user would exceed their quota
+// or there are not enough rooms available
+func (r roomsService) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter RoomFilter) ([]Reservation, error) {
 
 	// validate token
 	user, err := r.validator.Validate(ctx, token)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-
-	var booked bool
-	for id, room := range r.rooms {
-		if room.Book[date] == "" {
-			room.Mux.Lock()
-			if room.Book[date] == "" {
-				room.Book[date] = user
-				booked = true
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 || count > maxGroupRooms {
+		return nil, ErrInvalidRoomCount()
+	}
+	stay := Reservation{User: user, From: from, To: to}
+	if key == "" {
+		return r.bookRooms(stay, count, filter)
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey()
+	}
+	return r.idempotency.do(user, key, r.now(), func() ([]Reservation, error) {
+		// the request may have booked before the cache was lost,
+		// with a restart or on the leader before a failover
+		if booked, err := keyedReservations(r.store, user, key); err != nil || len(booked) > 0 {
+			return booked, err
+		}
+		stay.Key = key
+		reservations, err := r.bookRooms(stay, count, filter)
+		if err == ErrIdempotencyKeyUsed() {
+			if booked, err := keyedReservations(r.store, user, key); err != nil || len(booked) > 0 {
+				return booked, err
 			}
-			room.Mux.Unlock()
-			if booked {
-				return id + 1, nil
+		}
+		return reservations, err
+	})
+}
+
+// Books a stay in the name of its user in an available room
+func (r roomsService) book(stay Reservation, filter RoomFilter) (Reservation, error) {
+	reservations, err := r.bookRooms(stay, 1, filter)
+	if err != nil {
+		return Reservation{}, err
+	}
+	return reservations[0], nil
+}
+
+// Books a stay in the name of its user in count available rooms
+// picked by the allocator, all of them or none
+func (r roomsService) bookRooms(stay Reservation, count int, filter RoomFilter) ([]Reservation, error) {
+	if _, err := nights(stay.From, stay.To); err != nil {
+		return nil, err
+	}
+	if err := r.checkRules(stay, filter.Type); err != nil {
+		return nil, err
+	}
+	ruled, violation, err := r.ruledOut(stay)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.quota != nil {
+		defer r.quota.lock(stay.User)()
+		if err := r.checkQuota(stay, count, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	candidates, err := r.candidates(filter)
+	if err != nil {
+		return nil, err
+	}
+	blocked, all, err := r.blackedOut(stay.From, stay.To)
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return nil, ErrNoRoomAvailable()
+	}
+
+	// free rooms may be taken by concurrent bookings before they are reserved,
+	// then they are looked up again
+	collisions := 0
+	for {
+		free, err := r.store.FreeRooms(stay.From, stay.To, candidates)
+		if err != nil {
+			return nil, err
+		}
+		rooms := r.allocator.Allocate(stay, free.AndNot(blocked).AndNot(ruled), count)
+		if rooms == nil && violation != nil {
+			return nil, violation
+		}
+		if rooms == nil {
+			return nil, ErrNoRoomAvailable()
+		}
+
+		reservations := make([]Reservation, count)
+		for i, room := range rooms {
+			reservations[i] = stay
+			reservations[i].Id = newReservationID()
+			reservations[i].Code = newConfirmationCode()
+			reservations[i].Room = room
+			reservations[i].Version = 1
+		}
+		reserved, err := r.store.Reserve(reservations...)
+		if err == ErrReservationExists() {
+			// retry with new ids and confirmation codes
+			if collisions++; collisions < maxReservationAttempts {
+				continue
 			}
 		}
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return reservations, nil
+		}
+	}
+}
+
+// Returns the number of rooms matching the filter available for a date (read/non-blocking)
+// Held and blacked out rooms are not available
+func (r roomsService) Check(ctx context.Context, date time.Time, filter RoomFilter) (int, error) {
+	candidates, err := r.candidates(filter)
+	if err != nil {
+		return 0, err
+	}
+	counter, err := r.freeCounter(date, date.AddDate(0, 0, 1), candidates)
+	if err != nil {
+		return 0, err
+	}
+	return counter.count(date)
+}
+
+// Returns the number of rooms matching the filter available for every date
+// from one date to another (both included)
+// Returns an error if the range is reversed or too long
+func (r roomsService) Availability(ctx context.Context, from, to time.Time, filter RoomFilter) ([]DayAvailability, error) {
+	if to.Before(from) || to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		return nil, ErrInvalidDateRange()
+	}
+
+	candidates, err := r.candidates(filter)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := r.freeCounter(from, to.AddDate(0, 0, 1), candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []DayAvailability
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		available, err := counter.count(date)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, DayAvailability{date, available})
 	}
-	return 0, ErrNoRoomAvailable()
+	return days, nil
 }
 
-// Returns the number of available rooms for a date (read/non-blocking)
-func (r roomsService) Check(ctx context.Context, date time.Time) (int, error) {
+// Returns the rooms matching the filter
+func (r roomsService) rooms(filter RoomFilter) ([]RoomInfo, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	rooms, err := r.store.Rooms()
+	if err != nil {
+		return nil, err
+	}
 
-	var count int
-	for _, room := range r.rooms {
-		if room.Book[date] == "" {
-			count++
+	var matching []RoomInfo
+	for _, room := range rooms {
+		if filter.Match(room) {
+			matching = append(matching, room)
 		}
 	}
-	return count, nil
+	return matching, nil
+}
+
+// Returns the set of rooms matching the filter, nil if it matches every room
+func (r roomsService) candidates(filter RoomFilter) (RoomSet, error) {
+	if filter.Empty() {
+		return nil, nil
+	}
+	rooms, err := r.rooms(filter)
+	if err != nil {
+		return nil, err
+	}
+	set := RoomSet{}
+	for _, room := range rooms {
+		set = set.Add(room.Id)
+	}
+	return set, nil
+}
+
+// Returns the type of a room, empty if it does not exist
+func (r roomsService) roomType(id int) (string, error) {
+	rooms, err := r.store.Rooms()
+	if err != nil {
+		return "", err
+	}
+	i := sort.Search(len(rooms), func(i int) bool { return rooms[i].Id >= id })
+	if i == len(rooms) || rooms[i].Id != id {
+		return "", nil
+	}
+	return rooms[i].Type, nil
+}
+
+// Releases every night of a reservation (write/blocking)
+// The reservation is identified by its id or confirmation code
+// Returns an error if authentication token is invalid, the reservation does not exist,
+// belongs to another user or has another version than the one expected (0 for any)
+func (r roomsService) Cancel(ctx context.Context, token, ref string, version int) error {
+	reservation, err := r.GetReservation(ctx, token, ref)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Release(reservation.Id, version); err != nil {
+		return err
+	}
+	r.promoteWaitlist()
+	return nil
+}
+
+// Moves a reservation to other dates or another room (write/blocking)
+// The reservation is identified by its id or confirmation code, which it keeps
+// Zero dates keep the current stay and room 0 keeps the current room
+// The reservation is left untouched unless the room is free for every new night
+// New dates and new rooms are checked against the booking rules of the room
+// Returns an error if authentication token is invalid, the reservation does not exist,
+// belongs to another user, has another version than the one expected (0 for any),
+// the range is invalid, breaks a booking rule, exceeds the quota
+// of the user or the room is not available
+func (r roomsService) ModifyBooking(ctx context.Context, token, ref string, from, to time.Time, room, version int) (Reservation, error) {
+	reservation, err := r.GetReservation(ctx, token, ref)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if err := reservation.checkVersion(version); err != nil {
+		return Reservation{}, err
+	}
+	if from.IsZero() && to.IsZero() {
+		from, to = reservation.From, reservation.To
+	}
+	if room == 0 {
+		room = reservation.Room
+	}
+	if _, err := nights(from, to); err != nil {
+		return Reservation{}, err
+	}
+	if !from.Equal(reservation.From) || !to.Equal(reservation.To) || room != reservation.Room {
+		roomType, err := r.roomType(room)
+		if err != nil {
+			return Reservation{}, err
+		}
+		stay := Reservation{User: reservation.User, Room: room, From: from, To: to}
+		if err := r.checkRules(stay, roomType); err != nil {
+			return Reservation{}, err
+		}
+	}
+	blocked, all, err := r.blackedOut(from, to)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if all || blocked.Has(room) {
+		return Reservation{}, ErrRoomNotAvailable()
+	}
+
+	moved, err := r.move(reservation, version, room, from, to)
+	if err != nil {
+		return Reservation{}, err
+	}
+	r.promoteWaitlist()
+	return moved, nil
+}
+
+// Moves a reservation within the quota of its user
+// The waitlist is promoted after the bookings of the user are unlocked,
+// as waiting stays of the user lock them again
+func (r roomsService) move(reservation Reservation, version, room int, from, to time.Time) (Reservation, error) {
+	if r.quota != nil {
+		defer r.quota.lock(reservation.User)()
+		stay := Reservation{User: reservation.User, From: from, To: to}
+		if err := r.checkQuota(stay, 1, reservation.Id); err != nil {
+			return Reservation{}, err
+		}
+	}
+	moved, ok, err := r.store.Move(reservation.Id, version, room, from, to)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if !ok {
+		return Reservation{}, ErrRoomNotAvailable()
+	}
+	return moved, nil
+}
+
+// Returns a reservation by its id or confirmation code (read/non-blocking)
+// Returns an error if authentication token is invalid,
+// the reservation does not exist or belongs to another user
+func (r roomsService) GetReservation(ctx context.Context, token, ref string) (Reservation, error) {
+
+	// validate token
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	reservation, err := r.store.Reservation(ref)
+	if err == ErrBookingNotFound() {
+		reservation, err = r.store.ReservationByCode(strings.ToUpper(ref))
+	}
+	if err != nil {
+		return Reservation{}, err
+	}
+	if reservation.User != user {
+		return Reservation{}, ErrNotBookingOwner()
+	}
+	return reservation, nil
+}
+
+// Returns the bookings of the user from one date to another (both included)
+// ordered by date and room (read/non-blocking)
+// Returns an error if authentication token is invalid,
+// the range is reversed or too long
+func (r roomsService) ListBookings(ctx context.Context, token string, from, to time.Time) ([]Booking, error) {
+
+	// validate token
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if to.Before(from) || to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		return nil, ErrInvalidDateRange()
+	}
+
+	reservations, err := r.store.Reservations(user)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings := []Booking{}
+	for _, reservation := range reservations {
+		for _, date := range reservation.Nights() {
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			bookings = append(bookings, Booking{reservation.Id, reservation.Code, reservation.Room, date})
+		}
+	}
+	sort.Slice(bookings, func(i, j int) bool {
+		if !bookings[i].Date.Equal(bookings[j].Date) {
+			return bookings[i].Date.Before(bookings[j].Date)
+		}
+		return bookings[i].Room < bookings[j].Room
+	})
+	return bookings, nil
+}
+
+// Returns every night of a stay, from check-in (included) to check-out (excluded)
+// Returns an error if the stay is empty, reversed or longer than maxStayNights
+func nights(from, to time.Time) ([]time.Time, error) {
+	if !to.After(from) || to.Sub(from) > maxStayNights*24*time.Hour {
+		return nil, ErrInvalidDateRange()
+	}
+	return Reservation{From: from, To: to}.Nights(), nil
 }
@@ -25,7 +25,9 @@ func (v validatorIncorrect) Validate(_ context.Context, token string) (string, e
 var serviceBookTest = []struct {
 	name      string
 	token     string
-	date      time.Time
+	from      time.Time
+	to        time.Time
+	filter    RoomFilter
 	rooms     []Room
 	validator Validator
 	want      int
@@ -34,9 +36,11 @@ var serviceBookTest = []struct {
 	{
 		name:  "should return booked room id",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		rooms: []Room{
 			{
+				RoomInfo{Id: 1},
 				map[time.Time]string{},
 				&sync.Mutex{},
 			},
@@ -47,7 +51,8 @@ var serviceBookTest = []struct {
 	{
 		name:      "should return en error if there are no rooms available",
 		token:     "jjj.www.ttt",
-		date:      time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:      time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:        time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		rooms:     []Room{},
 		validator: validatorCorrect{},
 		err:       ErrNoRoomAvailable(),
@@ -55,9 +60,11 @@ var serviceBookTest = []struct {
 	{
 		name:  "should return en error if the token is invalid",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		rooms: []Room{
 			{
+				RoomInfo{Id: 1},
 				map[time.Time]string{},
 				&sync.Mutex{},
 			},
@@ -65,6 +72,142 @@ var serviceBookTest = []struct {
 		validator: validatorIncorrect{},
 		err:       jwt.ErrInvalidToken(),
 	},
+	{
+		name:  "should book every night of the stay in the same room",
+		token: "jjj.www.ttt",
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 16, 12, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{
+					time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC): "Charles",
+				},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 2},
+				map[time.Time]string{
+					time.Date(2020, 6, 16, 12, 0, 0, 0, time.UTC): "Charles",
+				},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		want:      2,
+	},
+	{
+		name:  "should return an error if no room is available for the whole stay",
+		token: "jjj.www.ttt",
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{
+					time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC): "Charles",
+				},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 2},
+				map[time.Time]string{
+					time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC): "Charles",
+				},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		err:       ErrNoRoomAvailable(),
+	},
+	{
+		name:  "should return an error if check-out is not after check-in",
+		token: "jjj.www.ttt",
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		err:       ErrInvalidDateRange(),
+	},
+	{
+		name:  "should return an error if the stay is longer than a year",
+		token: "jjj.www.ttt",
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		err:       ErrInvalidDateRange(),
+	},
+	{
+		name:   "should book a room matching the filter",
+		token:  "jjj.www.ttt",
+		from:   time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:     time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
+		filter: RoomFilter{Type: DoubleRoom, Amenities: []string{"balcony"}},
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1, Type: DoubleRoom, Capacity: 2},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 2, Type: SuiteRoom, Capacity: 4, Amenities: []string{"balcony"}},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 3, Type: DoubleRoom, Capacity: 2, Amenities: []string{"tv", "balcony"}},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		want:      3,
+	},
+	{
+		name:   "should return an error if no room matches the filter",
+		token:  "jjj.www.ttt",
+		from:   time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:     time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
+		filter: RoomFilter{MinCapacity: 3},
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1, Type: DoubleRoom, Capacity: 2},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		err:       ErrNoRoomAvailable(),
+	},
+	{
+		name:   "should return an error if the filter has an unknown room type",
+		token:  "jjj.www.ttt",
+		from:   time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:     time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
+		filter: RoomFilter{Type: "penthouse"},
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1, Type: DoubleRoom, Capacity: 2},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		err:       ErrInvalidRoomType(),
+	},
 }
 
 func TestServiceBook(t *testing.T) {
@@ -73,18 +216,98 @@ func TestServiceBook(t *testing.T) {
 	for _, testcase := range serviceBookTest {
 		t.Logf(testcase.name)
 
-		rs := roomsService{testcase.rooms, testcase.validator}
-		result, err := rs.Book(context.Background(), testcase.token, testcase.date)
+		rs := roomsService{store: NewMemoryStore(testcase.rooms), validator: testcase.validator, allocator: NewFirstFitAllocator()}
+		reservations, err := rs.Book(context.Background(), testcase.token, "", 0, testcase.from, testcase.to, testcase.filter)
 
-		assert.Equal(t, result, testcase.want)
 		assert.DeepEqual(t, err, testcase.err)
+		if err != nil {
+			continue
+		}
+		assert.Equal(t, len(reservations), 1)
+		result := reservations[0]
+		assert.Equal(t, result.Room, testcase.want)
+		assert.Equal(t, len(result.Id), 32)
+		assert.Equal(t, len(result.Code), codeLength)
+		assert.Equal(t, result.User, "John")
+		stored, err := rs.store.ReservationByCode(result.Code)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, stored, result)
 	}
 }
 
+func TestServiceBookGroup(t *testing.T) {
+	t.Log("ServiceBookGroup")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+	store := NewMemoryStore(testRooms(4))
+	store.Reserve(stay("1", "Charles", 2, from.AddDate(0, 0, 1), 1))
+	rs := NewRoomsServer(store, validatorCorrect{})
+
+	t.Logf("should book the first free rooms")
+	reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 3, from, to, RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(reservations), 3)
+	for i, room := range []int{1, 3, 4} {
+		assert.Equal(t, reservations[i].Room, room)
+		assert.Equal(t, reservations[i].User, "John")
+		stored, err := store.Reservation(reservations[i].Id)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, stored, reservations[i])
+	}
+	for _, reservation := range reservations {
+		assert.NilError(t, rs.Cancel(ctx, "jjj.www.ttt", reservation.Id, 0))
+	}
+
+	t.Logf("should book no room if there are not enough free")
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", 4, from, to, RoomFilter{})
+	assert.DeepEqual(t, err, ErrNoRoomAvailable())
+	available, _ := rs.Check(ctx, from, RoomFilter{})
+	assert.Equal(t, available, 4)
+	booked, _ := store.Reservations("John")
+	assert.DeepEqual(t, booked, []Reservation{})
+
+	t.Logf("should return an error if the count is invalid")
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", -1, from, to, RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidRoomCount())
+	_, err = rs.Book(ctx, "jjj.www.ttt", "", maxGroupRooms+1, from, to, RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidRoomCount())
+}
+
+func TestServiceBookGroupConcurrent(t *testing.T) {
+	t.Log("ServiceBookGroupConcurrent")
+	ctx := context.Background()
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(testRooms(10))
+	rs := NewRoomsServer(store, validatorCorrect{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservations, err := rs.Book(ctx, "jjj.www.ttt", "", 3, date, date.AddDate(0, 0, 1), RoomFilter{})
+			if err != nil {
+				assert.Check(t, err == ErrNoRoomAvailable())
+				return
+			}
+			assert.Check(t, len(reservations) == 3)
+		}()
+	}
+	wg.Wait()
+
+	// groups never book part of their rooms
+	users, _ := store.Query(date)
+	assert.Equal(t, len(users), 9)
+	booked, _ := store.Reservations("John")
+	assert.Equal(t, len(booked), 9)
+}
+
 var serviceCheckTest = []struct {
 	name      string
 	token     string
 	date      time.Time
+	filter    RoomFilter
 	rooms     []Room
 	validator Validator
 	want      int
@@ -96,14 +319,17 @@ var serviceCheckTest = []struct {
 		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
 		rooms: []Room{
 			{
+				RoomInfo{Id: 1},
 				map[time.Time]string{},
 				&sync.Mutex{},
 			},
 			{
+				RoomInfo{Id: 2},
 				map[time.Time]string{},
 				&sync.Mutex{},
 			},
 			{
+				RoomInfo{Id: 3},
 				map[time.Time]string{},
 				&sync.Mutex{},
 			},
@@ -117,6 +343,7 @@ var serviceCheckTest = []struct {
 		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
 		rooms: []Room{
 			{
+				RoomInfo{Id: 1},
 				map[time.Time]string{
 					time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC): "John",
 				},
@@ -126,6 +353,33 @@ var serviceCheckTest = []struct {
 		validator: validatorCorrect{},
 		want:      0,
 	},
+	{
+		name:   "should only count the rooms matching the filter",
+		token:  "jjj.www.ttt",
+		date:   time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		filter: RoomFilter{Type: DoubleRoom, MinCapacity: 2},
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1, Type: DoubleRoom, Capacity: 2},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 2, Type: DoubleRoom, Capacity: 2},
+				map[time.Time]string{
+					time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC): "John",
+				},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 3, Type: SingleRoom, Capacity: 1},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		validator: validatorCorrect{},
+		want:      1,
+	},
 }
 
 func TestServiceCheck(t *testing.T) {
@@ -134,10 +388,359 @@ func TestServiceCheck(t *testing.T) {
 	for _, testcase := range serviceCheckTest {
 		t.Logf(testcase.name)
 
-		rs := roomsService{testcase.rooms, testcase.validator}
-		result, err := rs.Check(context.Background(), testcase.date)
+		rs := roomsService{store: NewMemoryStore(testcase.rooms), validator: testcase.validator}
+		result, err := rs.Check(context.Background(), testcase.date, testcase.filter)
 
 		assert.Equal(t, result, testcase.want)
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
+
+// Returns a memory store with a room and the reservations
+// Returns n available rooms with ids from 1
+func testRooms(n int) []Room {
+	rooms := make([]Room, n)
+	for i := range rooms {
+		rooms[i] = Room{RoomInfo{Id: i + 1}, map[time.Time]string{}, &sync.Mutex{}}
+	}
+	return rooms
+}
+
+func newReservationsTestStore(reservations ...Reservation) BookingStore {
+	store := NewMemoryStore([]Room{{RoomInfo{Id: 1}, map[time.Time]string{}, &sync.Mutex{}}})
+	for _, reservation := range reservations {
+		store.Reserve(reservation)
+	}
+	return store
+}
+
+var serviceCancelTest = []struct {
+	name         string
+	id           string
+	version      int
+	reservations []Reservation
+	validator    Validator
+	want         map[int]string
+	err          error
+}{
+	{
+		name:         "should release every night of the reservation",
+		id:           testReservation.Id,
+		reservations: []Reservation{testReservation},
+		validator:    validatorCorrect{},
+		want:         map[int]string{},
+	},
+	{
+		name:         "should release the reservation by its confirmation code",
+		id:           "k7qx9m",
+		reservations: []Reservation{testReservation},
+		validator:    validatorCorrect{},
+		want:         map[int]string{},
+	},
+	{
+		name:         "should release the reservation with the version expected",
+		id:           testReservation.Id,
+		version:      1,
+		reservations: []Reservation{testReservation},
+		validator:    validatorCorrect{},
+		want:         map[int]string{},
+	},
+	{
+		name:         "should return an error if the reservation has another version than expected",
+		id:           testReservation.Id,
+		version:      2,
+		reservations: []Reservation{testReservation},
+		validator:    validatorCorrect{},
+		want:         map[int]string{1: "John"},
+		err:          ErrVersionConflict(),
+	},
+	{
+		name:         "should return an error if the reservation belongs to another user",
+		id:           testReservation.Id,
+		reservations: []Reservation{stay(testReservation.Id, "Charles", 1, testReservation.From, 2)},
+		validator:    validatorCorrect{},
+		want:         map[int]string{1: "Charles"},
+		err:          ErrNotBookingOwner(),
+	},
+	{
+		name:      "should return an error if the reservation does not exist",
+		id:        testReservation.Id,
+		validator: validatorCorrect{},
+		want:      map[int]string{},
+		err:       ErrBookingNotFound(),
+	},
+	{
+		name:         "should return an error if the token is invalid",
+		id:           testReservation.Id,
+		reservations: []Reservation{testReservation},
+		validator:    validatorIncorrect{},
+		want:         map[int]string{1: "John"},
+		err:          jwt.ErrInvalidToken(),
+	},
+}
+
+func TestServiceCancel(t *testing.T) {
+	t.Log("ServiceCancel")
+
+	for _, testcase := range serviceCancelTest {
+		t.Logf(testcase.name)
+
+		rs := roomsService{store: newReservationsTestStore(testcase.reservations...), validator: testcase.validator, waitlistMux: &sync.Mutex{}, now: time.Now}
+		err := rs.Cancel(context.Background(), "jjj.www.ttt", testcase.id, testcase.version)
+		users, _ := rs.store.Query(time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC))
+
+		assert.DeepEqual(t, users, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+func TestServiceModifyBooking(t *testing.T) {
+	t.Log("ServiceModifyBooking")
+	ctx := context.Background()
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(testRooms(2))
+	store.Reserve(stay("1", "Anna", 2, date.AddDate(0, 0, 2), 1))
+	rs := NewRoomsServer(store, validatorUser{})
+	booked, err := rs.Book(ctx, "John", "", 1, date, date.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	reservation := booked[0]
+
+	t.Logf("should move the reservation to overlapping dates")
+	moved, err := rs.ModifyBooking(ctx, "John", reservation.Code, date.AddDate(0, 0, 1), date.AddDate(0, 0, 3), 0, 0)
+	assert.NilError(t, err)
+	want := reservation
+	want.From, want.To = date.AddDate(0, 0, 1), date.AddDate(0, 0, 3)
+	want.Version = 2
+	assert.DeepEqual(t, moved, want)
+	available, _ := rs.Check(ctx, date, RoomFilter{})
+	assert.Equal(t, available, 2)
+
+	t.Logf("should leave the reservation untouched if the room is taken")
+	_, err = rs.ModifyBooking(ctx, "John", reservation.Id, time.Time{}, time.Time{}, 2, 0)
+	assert.DeepEqual(t, err, ErrRoomNotAvailable())
+	stored, _ := store.Reservation(reservation.Id)
+	assert.DeepEqual(t, stored, want)
+	users, _ := store.Query(date.AddDate(0, 0, 1))
+	assert.DeepEqual(t, users, map[int]string{1: "John"})
+
+	t.Logf("should move the reservation to another room")
+	moved, err = rs.ModifyBooking(ctx, "John", reservation.Id, date, date.AddDate(0, 0, 2), 2, 0)
+	assert.NilError(t, err)
+	want.Room, want.From, want.To = 2, date, date.AddDate(0, 0, 2)
+	want.Version = 3
+	assert.DeepEqual(t, moved, want)
+	users, _ = store.Query(date.AddDate(0, 0, 1))
+	assert.DeepEqual(t, users, map[int]string{2: "John"})
+	users, _ = store.Query(date.AddDate(0, 0, 2))
+	assert.DeepEqual(t, users, map[int]string{2: "Anna"})
+
+	t.Logf("should return an error if the reservation belongs to another user")
+	_, err = rs.ModifyBooking(ctx, "Anna", reservation.Id, time.Time{}, time.Time{}, 1, 0)
+	assert.DeepEqual(t, err, ErrNotBookingOwner())
+
+	t.Logf("should return an error if the range or room are invalid")
+	_, err = rs.ModifyBooking(ctx, "John", reservation.Id, date, date, 0, 0)
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+	_, err = rs.ModifyBooking(ctx, "John", reservation.Id, time.Time{}, time.Time{}, 3, 0)
+	assert.DeepEqual(t, err, ErrRoomNotFound())
+
+	t.Logf("should return an error if the reservation has another version than expected")
+	_, err = rs.ModifyBooking(ctx, "John", reservation.Id, date.AddDate(0, 0, 4), date.AddDate(0, 0, 5), 0, 2)
+	assert.DeepEqual(t, err, ErrVersionConflict())
+	stored, _ = store.Reservation(reservation.Id)
+	assert.DeepEqual(t, stored, want)
+}
+
+var serviceGetReservationTest = []struct {
+	name         string
+	id           string
+	reservations []Reservation
+	want         Reservation
+	err          error
+}{
+	{
+		name:         "should return the reservation by its id",
+		id:           testReservation.Id,
+		reservations: []Reservation{testReservation},
+		want:         testReservation,
+	},
+	{
+		name:         "should return the reservation by its confirmation code",
+		id:           testReservation.Code,
+		reservations: []Reservation{testReservation},
+		want:         testReservation,
+	},
+	{
+		name:         "should return an error if the reservation belongs to another user",
+		id:           "CODE1",
+		reservations: []Reservation{stay("1", "Charles", 1, testReservation.From, 1)},
+		err:          ErrNotBookingOwner(),
+	},
+	{
+		name: "should return an error if the reservation does not exist",
+		id:   testReservation.Code,
+		err:  ErrBookingNotFound(),
+	},
+}
+
+func TestServiceGetReservation(t *testing.T) {
+	t.Log("ServiceGetReservation")
+
+	for _, testcase := range serviceGetReservationTest {
+		t.Logf(testcase.name)
+
+		rs := roomsService{store: newReservationsTestStore(testcase.reservations...), validator: validatorCorrect{}}
+		result, err := rs.GetReservation(context.Background(), "jjj.www.ttt", testcase.id)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var serviceAvailabilityTest = []struct {
+	name  string
+	from  time.Time
+	to    time.Time
+	rooms []Room
+	want  []DayAvailability
+	err   error
+}{
+	{
+		name: "should return the number of available rooms for every day",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{
+					time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC): "John",
+				},
+				&sync.Mutex{},
+			},
+			{
+				RoomInfo{Id: 2},
+				map[time.Time]string{
+					time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC): "Charles",
+					time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC): "Charles",
+				},
+				&sync.Mutex{},
+			},
+		},
+		want: []DayAvailability{
+			{time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 2},
+			{time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC), 0},
+			{time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 1},
+		},
+	},
+	{
+		name: "should return an error if the range is reversed",
+		from: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		err: ErrInvalidDateRange(),
+	},
+	{
+		name: "should return an error if the range is too long",
+		from: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		rooms: []Room{
+			{
+				RoomInfo{Id: 1},
+				map[time.Time]string{},
+				&sync.Mutex{},
+			},
+		},
+		err: ErrInvalidDateRange(),
+	},
+}
+
+func TestServiceAvailability(t *testing.T) {
+	t.Log("ServiceAvailability")
+
+	for _, testcase := range serviceAvailabilityTest {
+		t.Logf(testcase.name)
+
+		rs := roomsService{store: NewMemoryStore(testcase.rooms), validator: validatorCorrect{}}
+		result, err := rs.Availability(context.Background(), testcase.from, testcase.to, RoomFilter{})
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var serviceListBookingsTest = []struct {
+	name         string
+	from         time.Time
+	to           time.Time
+	reservations []Reservation
+	validator    Validator
+	want         []Booking
+	err          error
+}{
+	{
+		name: "should return the nights of the user in the range ordered by date and room",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		reservations: []Reservation{
+			stay("1", "John", 1, time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC), 2),
+			stay("2", "John", 2, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 1),
+			stay("3", "Charles", 2, time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC), 1),
+		},
+		validator: validatorCorrect{},
+		want: []Booking{
+			{"2", "CODE2", 2, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)},
+			{"1", "CODE1", 1, time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+		},
+	},
+	{
+		name: "should return no bookings if the user has none",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		reservations: []Reservation{
+			stay("1", "Charles", 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 1),
+		},
+		validator: validatorCorrect{},
+		want:      []Booking{},
+	},
+	{
+		name:      "should return an error if the range is reversed",
+		from:      time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		to:        time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		validator: validatorCorrect{},
+		err:       ErrInvalidDateRange(),
+	},
+	{
+		name:      "should return an error if the token is invalid",
+		from:      time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:        time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		validator: validatorIncorrect{},
+		err:       jwt.ErrInvalidToken(),
+	},
+}
+
+func TestServiceListBookings(t *testing.T) {
+	t.Log("ServiceListBookings")
+
+	for _, testcase := range serviceListBookingsTest {
+		t.Logf(testcase.name)
+
+		store := NewMemoryStore([]Room{
+			{RoomInfo{Id: 1}, map[time.Time]string{}, &sync.Mutex{}},
+			{RoomInfo{Id: 2}, map[time.Time]string{}, &sync.Mutex{}},
+		})
+		for _, reservation := range testcase.reservations {
+			store.Reserve(reservation)
+		}
+		rs := roomsService{store: store, validator: testcase.validator}
+		result, err := rs.ListBookings(context.Background(), "jjj.www.ttt", testcase.from, testcase.to)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
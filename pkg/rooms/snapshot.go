@@ -0,0 +1,150 @@
+package rooms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version of the snapshot format written by WriteSnapshot
+const SnapshotVersion = 1
+
+// Snapshot is a point-in-time copy of the state of a store
+// Booked nights are rebuilt from the reservations
+type Snapshot struct {
+	Version      int           `json:"version"`
+	Taken        time.Time     `json:"taken"`
+	Rooms        []RoomInfo    `json:"rooms"`
+	Reservations []Reservation `json:"reservations"`
+	Blackouts    []Blackout    `json:"blackouts"`
+	// Webhooks with their secrets
+	Webhooks    []Webhook    `json:"webhooks"`
+	DeadLetters []DeadLetter `json:"dead_letters"`
+	// Waitlist entries in the order they joined
+	Waitlist []WaitlistEntry `json:"waitlist"`
+}
+
+// WriteSnapshot encodes a snapshot as JSON
+func WriteSnapshot(w io.Writer, snapshot Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// ReadSnapshot decodes a snapshot written by WriteSnapshot
+// Returns an error if the snapshot has another version
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	if snapshot.Version != SnapshotVersion {
+		return Snapshot{}, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// Restore loads a snapshot into a store without reservations, blackouts, webhooks or waitlist
+// Rooms missing from the snapshot are removed and the rest added or updated
+// Reservations of rooms decommissioned since are restored without their room
+// Returns an error if the store is not empty or a reservation can not be stored
+func Restore(snapshot Snapshot, store BookingStore) error {
+	current, err := store.Snapshot()
+	if err != nil {
+		return err
+	}
+	if len(current.Reservations) > 0 || len(current.Blackouts) > 0 || len(current.Webhooks) > 0 || len(current.Waitlist) > 0 {
+		return errors.New("store already has reservations, blackouts, webhooks or waitlist")
+	}
+	existing := map[int]bool{}
+	for _, room := range current.Rooms {
+		existing[room.Id] = true
+	}
+	for _, room := range snapshot.Rooms {
+		if existing[room.Id] {
+			err = store.UpdateRoom(room)
+		} else {
+			_, err = store.AddRoom(room)
+		}
+		if err != nil {
+			return err
+		}
+		delete(existing, room.Id)
+	}
+	for id := range existing {
+		if err := store.RemoveRoom(id, time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	// Rooms decommissioned after their stays keep their past reservations,
+	// which are stored in the room added back until they are all restored
+	decommissioned := map[int]time.Time{}
+	for _, reservation := range snapshot.Reservations {
+		if reservation.To.After(decommissioned[reservation.Room]) {
+			decommissioned[reservation.Room] = reservation.To
+		}
+	}
+	for _, room := range snapshot.Rooms {
+		delete(decommissioned, room.Id)
+	}
+	for id := range decommissioned {
+		if _, err := store.AddRoom(RoomInfo{Id: id}); err != nil {
+			return err
+		}
+	}
+	if len(snapshot.Reservations) > 0 {
+		reserved, err := store.Reserve(snapshot.Reservations...)
+		if err != nil {
+			return err
+		}
+		if !reserved {
+			return ErrRoomNotAvailable()
+		}
+	}
+	for id, checkOut := range decommissioned {
+		if err := store.RemoveRoom(id, checkOut); err != nil {
+			return err
+		}
+	}
+	for _, blackout := range snapshot.Blackouts {
+		if err := store.AddBlackout(blackout); err != nil {
+			return err
+		}
+	}
+	for _, webhook := range snapshot.Webhooks {
+		if err := store.AddWebhook(webhook); err != nil {
+			return err
+		}
+	}
+	for _, deadLetter := range snapshot.DeadLetters {
+		if err := store.AddDeadLetter(deadLetter); err != nil {
+			return err
+		}
+	}
+	for _, entry := range snapshot.Waitlist {
+		if err := store.PutWaitlistEntry(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a consistent copy of the rooms, reservations, blackouts, webhooks and waitlist (read/non-blocking)
+// Bookings are only held back while the state is copied
+// Returns an error if the token is invalid or the user is not an administrator
+func (r roomsService) Snapshot(ctx context.Context, token string) (Snapshot, error) {
+	if err := r.authorizeAdmin(ctx, token); err != nil {
+		return Snapshot{}, err
+	}
+	snapshot, err := r.store.Snapshot()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot.Version = SnapshotVersion
+	snapshot.Taken = r.now().UTC().Truncate(time.Second)
+	return snapshot, nil
+}
@@ -0,0 +1,207 @@
+package rooms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-booking-service/pb"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var snapshotTestHold = Reservation{
+	Id:      testHold.Id,
+	Code:    testHold.Code,
+	User:    "Anna",
+	Room:    2,
+	From:    testHold.From,
+	To:      testHold.To,
+	Expires: testHold.Expires,
+}
+
+var testSnapshot = Snapshot{
+	Version:      SnapshotVersion,
+	Taken:        time.Date(2020, 6, 12, 21, 30, 0, 0, time.UTC),
+	Rooms:        storeTestRooms,
+	Reservations: []Reservation{testReservation, snapshotTestHold},
+	Blackouts:    []Blackout{{Id: "B4CK0T", Room: 2, From: testBlackout.From, To: testBlackout.To, Reason: "painting"}},
+	Webhooks:     []Webhook{testWebhook},
+	DeadLetters:  []DeadLetter{testDeadLetter},
+	Waitlist:     []WaitlistEntry{snapshotTestWaitlistEntry},
+}
+
+var snapshotTestWaitlistEntry = WaitlistEntry{
+	Id:     testWaitlistEntry.Id,
+	User:   "John",
+	From:   testWaitlistEntry.From,
+	To:     testWaitlistEntry.To,
+	Filter: testWaitlistEntry.Filter,
+}
+
+func TestReadSnapshot(t *testing.T) {
+	t.Log("ReadSnapshot")
+
+	t.Logf("should read back a written snapshot")
+	var file bytes.Buffer
+	assert.NilError(t, WriteSnapshot(&file, testSnapshot))
+	snapshot, err := ReadSnapshot(&file)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, snapshot, testSnapshot)
+
+	t.Logf("should return an error if the version is not supported")
+	_, err = ReadSnapshot(strings.NewReader(`{"version":2,"rooms":[]}`))
+	assert.Error(t, err, "unsupported snapshot version 2")
+
+	t.Logf("should return an error if the file is corrupted")
+	_, err = ReadSnapshot(strings.NewReader(`{"version":1,"rooms":`))
+	assert.Assert(t, err != nil)
+}
+
+func TestRestore(t *testing.T) {
+	t.Log("Restore")
+
+	for _, impl := range storeImplementations {
+		t.Logf("%s: should restore the snapshot replacing the rooms", impl.name)
+		store, cleanup := impl.store(t, []RoomInfo{{Id: 2, Name: "old"}, {Id: 3, Name: "303"}})
+		assert.NilError(t, Restore(testSnapshot, store))
+		snapshot, err := store.Snapshot()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, snapshot, Snapshot{
+			Rooms:        testSnapshot.Rooms,
+			Reservations: testSnapshot.Reservations,
+			Blackouts:    testSnapshot.Blackouts,
+			Webhooks:     testSnapshot.Webhooks,
+			DeadLetters:  testSnapshot.DeadLetters,
+			Waitlist:     testSnapshot.Waitlist,
+		})
+		users, err := store.Query(testReservation.From)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, users, map[int]string{1: "John", 2: "Anna"})
+
+		t.Logf("%s: should return an error if the store is not empty", impl.name)
+		assert.Error(t, Restore(testSnapshot, store), "store already has reservations, blackouts, webhooks or waitlist")
+
+		t.Logf("%s: should return an error if the reservations can not be stored", impl.name)
+		conflicting := testSnapshot
+		conflicting.Blackouts = nil
+		conflicting.Reservations = []Reservation{testReservation, stay("1", "Anna", 1, testReservation.From, 1)}
+		empty, emptyCleanup := impl.store(t, nil)
+		assert.DeepEqual(t, Restore(conflicting, empty), ErrRoomNotAvailable())
+		emptyCleanup()
+		cleanup()
+	}
+}
+
+func TestRestoreDecommissioned(t *testing.T) {
+	t.Log("RestoreDecommissioned")
+	date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+
+	for _, impl := range storeImplementations {
+		t.Logf("%s: should restore a snapshot taken after a booked room was decommissioned", impl.name)
+		store, cleanup := impl.store(t, storeTestRooms)
+		reserved, err := store.Reserve(stay("1", "John", 1, date, 1), stay("2", "Anna", 2, date, 1))
+		assert.NilError(t, err)
+		assert.Assert(t, reserved)
+		assert.NilError(t, store.RemoveRoom(1, date.AddDate(0, 0, 1)))
+		snapshot, err := store.Snapshot()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, snapshot.Reservations, []Reservation{stay("1", "John", 1, date, 1), stay("2", "Anna", 2, date, 1)})
+
+		restored, restoredCleanup := impl.store(t, nil)
+		assert.NilError(t, Restore(snapshot, restored))
+		rooms, _ := restored.Rooms()
+		assert.DeepEqual(t, rooms, storeTestRooms[1:])
+
+		t.Logf("%s: should keep the reservations of the decommissioned room", impl.name)
+		kept, err := restored.Reservation("1")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, kept, stay("1", "John", 1, date, 1))
+		users, _ := restored.Query(date)
+		assert.DeepEqual(t, users, map[int]string{2: "Anna"})
+
+		t.Logf("%s: should install the snapshot on a raft node", impl.name)
+		snapshot.Version = SnapshotVersion
+		var file bytes.Buffer
+		assert.NilError(t, WriteSnapshot(&file, snapshot))
+		fsm := NewStoreFSM(nil)
+		assert.NilError(t, fsm.Restore(ioutil.NopCloser(&file)))
+		users, _ = fsm.current().Query(date)
+		assert.DeepEqual(t, users, map[int]string{2: "Anna"})
+		_, err = fsm.current().Reservation("1")
+		assert.NilError(t, err)
+		restoredCleanup()
+		cleanup()
+	}
+}
+
+func TestServiceSnapshot(t *testing.T) {
+	t.Log("ServiceSnapshot")
+	ctx := context.Background()
+	rs := NewRoomsServer(NewMemoryStore(testRooms(3)), validatorUser{}, WithAdmins("Admin"), WithClock(journalTestClock))
+
+	t.Logf("should return an error if the user is not an administrator")
+	_, err := rs.Snapshot(ctx, "John")
+	assert.DeepEqual(t, err, ErrNotAdmin())
+
+	t.Logf("should return a versioned snapshot to administrators")
+	booked, err := rs.Book(ctx, "John", "", 1, testReservation.From, testReservation.To, RoomFilter{})
+	assert.NilError(t, err)
+	snapshot, err := rs.Snapshot(ctx, "Admin")
+	assert.NilError(t, err)
+	assert.Equal(t, snapshot.Version, SnapshotVersion)
+	assert.Equal(t, snapshot.Taken, testEvent.Time)
+	assert.Equal(t, len(snapshot.Rooms), 3)
+	assert.DeepEqual(t, snapshot.Reservations, booked)
+
+	t.Logf("should take consistent snapshots while bookings go on")
+	var wg sync.WaitGroup
+	for user := 0; user < 4; user++ {
+		wg.Add(1)
+		go func(user int) {
+			defer wg.Done()
+			token := fmt.Sprintf("User%d", user)
+			for night := 0; night < 20; night++ {
+				from := testReservation.From.AddDate(0, 0, night)
+				booked, err := rs.Book(ctx, token, "", 1, from, from.AddDate(0, 0, 1), RoomFilter{})
+				if err == nil && night%2 == 0 {
+					rs.Cancel(ctx, token, booked[0].Id, 0)
+				}
+			}
+		}(user)
+	}
+	for i := 0; i < 10; i++ {
+		snapshot, err := rs.Snapshot(ctx, "Admin")
+		assert.NilError(t, err)
+		assert.NilError(t, Restore(snapshot, NewMemoryStore(nil)))
+	}
+	wg.Wait()
+}
+
+func TestGRPCSnapshotCodec(t *testing.T) {
+	t.Log("GRPCSnapshotCodec")
+	ctx := context.Background()
+
+	t.Logf("should send the snapshot in the file format")
+	reply, err := encodeGRPCSnapshotResponse(ctx, &SnapshotResponse{Snapshot: testSnapshot})
+	assert.NilError(t, err)
+	resp, err := decodeGRPCSnapshotResponse(ctx, reply)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resp, &SnapshotResponse{Snapshot: testSnapshot})
+
+	t.Logf("should send the error instead of the snapshot")
+	reply, err = encodeGRPCSnapshotResponse(ctx, &SnapshotResponse{Err: ErrNotAdmin()})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reply, &pb.SnapshotResponse{Error: NotAdmin})
+	resp, err = decodeGRPCSnapshotResponse(ctx, reply)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resp, &SnapshotResponse{Err: ErrNotAdmin()})
+
+	t.Logf("should return an error if the snapshot can not be read")
+	_, err = decodeGRPCSnapshotResponse(ctx, &pb.SnapshotResponse{Snapshot: []byte(`{"version":2}`)})
+	assert.Error(t, err, "unsupported snapshot version 2")
+}
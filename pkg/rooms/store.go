@@ -0,0 +1,622 @@
+package rooms
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BookingStore keeps track of which rooms are booked for which dates.
+// Rooms are identified by their id
+type BookingStore interface {
+	// Returns the description of every room in the store, ordered by id
+	Rooms() ([]RoomInfo, error)
+	// Adds a room to the store, a room without id gets the next free one
+	// Returns the stored room
+	AddRoom(room RoomInfo) (RoomInfo, error)
+	// Replaces the description of a room
+	UpdateRoom(room RoomInfo) error
+	// Removes a room from the store unless it is booked on or after a date
+	RemoveRoom(room int, from time.Time) error
+	// Books the room of every reservation for every night in the name of its user
+	// and stores the reservations (all or nothing)
+	// Returns false if a room was already booked for any of the nights
+	// and an error if a reservation id or code are already taken
+	Reserve(reservations ...Reservation) (bool, error)
+	// Frees the room for every night of a reservation and removes it
+	// Versions are checked as by Move
+	Release(id string, version int) error
+	// Moves a reservation to a room and stay, keeping its id and code (all or nothing)
+	// Nights the reservation already holds in the room count as free
+	// Returns the moved reservation, false if the room was booked by another
+	// reservation for any of the new nights
+	// and ErrVersionConflict if a version (0 for any) is expected and the reservation has another one
+	Move(id string, version, room int, from, to time.Time) (Reservation, bool, error)
+	// Turns a hold that has not expired at a time into a confirmed reservation
+	// Versions are checked as by Move
+	// Returns the confirmed reservation
+	Confirm(id string, version int, now time.Time) (Reservation, error)
+	// Releases every hold expired at a time
+	// Returns the released holds
+	ReleaseExpired(now time.Time) ([]Reservation, error)
+	// Returns the reservation with an id
+	Reservation(id string) (Reservation, error)
+	// Returns the reservation with a confirmation code
+	ReservationByCode(code string) (Reservation, error)
+	// Returns every reservation of a user ordered by check-in
+	Reservations(user string) ([]Reservation, error)
+	// Returns the user that booked each room for a date, by room id
+	// Available rooms are not included
+	Query(date time.Time) (map[int]string, error)
+	// Returns how many rooms of a set are free on a date, nil stands for every room
+	CountFree(date time.Time, rooms RoomSet) (int, error)
+	// Returns the rooms of a set free for every night from check-in to check-out,
+	// nil stands for every room
+	FreeRooms(from, to time.Time, rooms RoomSet) (RoomSet, error)
+	// Returns the user that booked a room for every date it is booked
+	Bookings(room int) (map[time.Time]string, error)
+	// Returns every reservation with a night from check-in to check-out ordered by check-in
+	ReservationsBetween(from, to time.Time) ([]Reservation, error)
+	// Stores a blackout
+	AddBlackout(blackout Blackout) error
+	// Removes a blackout
+	RemoveBlackout(id string) error
+	// Returns every blackout ordered by start
+	Blackouts() ([]Blackout, error)
+	// Stores a webhook with its secret
+	AddWebhook(webhook Webhook) error
+	// Removes a webhook
+	RemoveWebhook(id string) error
+	// Returns every webhook with its secret ordered by url
+	Webhooks() ([]Webhook, error)
+	// Stores a dead letter, dropping the oldest ones beyond the most kept
+	AddDeadLetter(deadLetter DeadLetter) error
+	// Returns the dead letters, oldest first
+	DeadLetters() ([]DeadLetter, error)
+	// Stores a waitlist entry, replacing the one with the same id in its place
+	PutWaitlistEntry(entry WaitlistEntry) error
+	// Removes a waitlist entry
+	RemoveWaitlistEntry(id string) error
+	// Returns every waitlist entry in the order they were first stored
+	Waitlist() ([]WaitlistEntry, error)
+	// Returns the rooms, reservations, blackouts, webhooks and waitlist as of the same point in time
+	Snapshot() (Snapshot, error)
+}
+
+// NewMemoryStore returns a BookingStore backed by the in-memory maps of rooms.
+// Availability is answered by an index of the rooms booked on every date
+// that is read without locking.
+// All bookings are lost when the process exits
+func NewMemoryStore(rooms []Room) BookingStore {
+	m := &memoryStore{
+		rooms:        map[int]Room{},
+		mux:          &sync.RWMutex{},
+		reservations: map[string]Reservation{},
+		codes:        map[string]string{},
+		resMux:       &sync.Mutex{},
+		index:        newAvailabilityIndex(),
+		blackoutMux:  &sync.Mutex{},
+		webhooks:     map[string]Webhook{},
+		webhookMux:   &sync.Mutex{},
+		waitlistMux:  &sync.Mutex{},
+	}
+	m.blackouts.Store([]Blackout{})
+	for _, room := range rooms {
+		m.rooms[room.Id] = room
+		m.index.addRoom(room.Id)
+		for date := range room.Book {
+			m.index.set(date, room.Id, true)
+		}
+	}
+	return m
+}
+
+// Locks are taken in order: mux, resMux and then the room lock
+// The index is updated holding the lock of the room
+// Blackouts are read without locking from a copy replaced on every change
+// Webhooks and dead letters have a lock of their own, and so does the waitlist
+type memoryStore struct {
+	rooms        map[int]Room
+	mux          *sync.RWMutex
+	reservations map[string]Reservation
+	codes        map[string]string
+	resMux       *sync.Mutex
+	index        *availabilityIndex
+	blackouts    atomic.Value
+	blackoutMux  *sync.Mutex
+	webhooks     map[string]Webhook
+	deadLetters  []DeadLetter
+	webhookMux   *sync.Mutex
+	waitlist     []WaitlistEntry
+	waitlistMux  *sync.Mutex
+}
+
+// Must be called holding the store lock
+func (m *memoryStore) room(id int) (Room, error) {
+	room, ok := m.rooms[id]
+	if !ok {
+		return Room{}, ErrRoomNotFound()
+	}
+	return room, nil
+}
+
+func (m *memoryStore) Rooms() ([]RoomInfo, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	infos := make([]RoomInfo, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		infos = append(infos, room.RoomInfo)
+	}
+	sortRooms(infos)
+	return infos, nil
+}
+
+func (m *memoryStore) AddRoom(room RoomInfo) (RoomInfo, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if room.Id == 0 {
+		for id := range m.rooms {
+			if id > room.Id {
+				room.Id = id
+			}
+		}
+		room.Id++
+	}
+	if _, ok := m.rooms[room.Id]; ok {
+		return RoomInfo{}, ErrRoomExists()
+	}
+	m.rooms[room.Id] = Room{room, map[time.Time]string{}, &sync.Mutex{}}
+	m.index.addRoom(room.Id)
+	return room, nil
+}
+
+func (m *memoryStore) UpdateRoom(room RoomInfo) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	r, ok := m.rooms[room.Id]
+	if !ok {
+		return ErrRoomNotFound()
+	}
+	r.RoomInfo = room
+	m.rooms[room.Id] = r
+	return nil
+}
+
+func (m *memoryStore) RemoveRoom(room int, from time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	r, ok := m.rooms[room]
+	if !ok {
+		return ErrRoomNotFound()
+	}
+	r.Mux.Lock()
+	defer r.Mux.Unlock()
+	for date := range r.Book {
+		if !date.Before(from) {
+			return ErrRoomHasBookings()
+		}
+	}
+	for date := range r.Book {
+		m.index.set(date, room, false)
+	}
+	delete(m.rooms, room)
+	m.index.removeRoom(room)
+	return nil
+}
+
+func (m *memoryStore) Reserve(reservations ...Reservation) (bool, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	rooms := map[int]Room{}
+	for _, reservation := range reservations {
+		r, err := m.room(reservation.Room)
+		if err != nil {
+			return false, err
+		}
+		rooms[r.Id] = r
+	}
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	ids, codes := map[string]bool{}, map[string]bool{}
+	for _, reservation := range reservations {
+		if _, ok := m.reservations[reservation.Id]; ok || m.codes[reservation.Code] != "" || ids[reservation.Id] || codes[reservation.Code] {
+			return false, ErrReservationExists()
+		}
+		ids[reservation.Id], codes[reservation.Code] = true, true
+	}
+
+	// rooms are locked in order of id
+	order := make([]int, 0, len(rooms))
+	for id := range rooms {
+		order = append(order, id)
+	}
+	sort.Ints(order)
+	for _, id := range order {
+		rooms[id].Mux.Lock()
+		defer rooms[id].Mux.Unlock()
+	}
+
+	// nights claimed by the reservations, by room
+	claimed := map[int]map[time.Time]bool{}
+	for _, reservation := range reservations {
+		r := rooms[reservation.Room]
+		if claimed[r.Id] == nil {
+			claimed[r.Id] = map[time.Time]bool{}
+		}
+		for _, date := range reservation.Nights() {
+			if r.Book[date] != "" || claimed[r.Id][date] {
+				return false, nil
+			}
+			claimed[r.Id][date] = true
+		}
+	}
+	for _, reservation := range reservations {
+		r := rooms[reservation.Room]
+		for _, date := range reservation.Nights() {
+			r.Book[date] = reservation.User
+			m.index.set(date, r.Id, true)
+		}
+		m.reservations[reservation.Id] = reservation
+		m.codes[reservation.Code] = reservation.Id
+	}
+	return true, nil
+}
+
+func (m *memoryStore) Release(id string, version int) error {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	reservation, ok := m.reservations[id]
+	if !ok {
+		return ErrBookingNotFound()
+	}
+	if err := reservation.checkVersion(version); err != nil {
+		return err
+	}
+	m.release(reservation)
+	return nil
+}
+
+// Must be called holding the store and reservations locks
+func (m *memoryStore) release(reservation Reservation) {
+	delete(m.reservations, reservation.Id)
+	delete(m.codes, reservation.Code)
+
+	// the room may have been decommissioned since
+	r, ok := m.rooms[reservation.Room]
+	if !ok {
+		return
+	}
+	r.Mux.Lock()
+	defer r.Mux.Unlock()
+	for _, date := range reservation.Nights() {
+		if r.Book[date] == reservation.User {
+			delete(r.Book, date)
+			m.index.set(date, r.Id, false)
+		}
+	}
+}
+
+func (m *memoryStore) Move(id string, version, room int, from, to time.Time) (Reservation, bool, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	reservation, ok := m.reservations[id]
+	if !ok {
+		return Reservation{}, false, ErrBookingNotFound()
+	}
+	if err := reservation.checkVersion(version); err != nil {
+		return Reservation{}, false, err
+	}
+	r, err := m.room(room)
+	if err != nil {
+		return Reservation{}, false, err
+	}
+	moved := reservation
+	moved.Room, moved.From, moved.To = room, from, to
+	moved.Version++
+
+	// nights the reservation already holds in the new room
+	own := map[time.Time]bool{}
+	if reservation.Room == room {
+		for _, date := range reservation.Nights() {
+			own[date] = true
+		}
+	}
+	r.Mux.Lock()
+	for _, date := range moved.Nights() {
+		if r.Book[date] != "" && !own[date] {
+			r.Mux.Unlock()
+			return Reservation{}, false, nil
+		}
+	}
+	r.Mux.Unlock()
+
+	// rooms are not booked meanwhile as every reservation takes the reservations lock
+	m.release(reservation)
+	r.Mux.Lock()
+	defer r.Mux.Unlock()
+	for _, date := range moved.Nights() {
+		r.Book[date] = moved.User
+		m.index.set(date, r.Id, true)
+	}
+	m.reservations[moved.Id] = moved
+	m.codes[moved.Code] = moved.Id
+	return moved, true, nil
+}
+
+func (m *memoryStore) Confirm(id string, version int, now time.Time) (Reservation, error) {
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	reservation, ok := m.reservations[id]
+	if !ok {
+		return Reservation{}, ErrBookingNotFound()
+	}
+	if err := reservation.checkVersion(version); err != nil {
+		return Reservation{}, err
+	}
+	if err := confirm(&reservation, now); err != nil {
+		return Reservation{}, err
+	}
+	m.reservations[id] = reservation
+	return reservation, nil
+}
+
+func (m *memoryStore) ReleaseExpired(now time.Time) ([]Reservation, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	released := []Reservation{}
+	for _, reservation := range m.reservations {
+		if reservation.Expired(now) {
+			m.release(reservation)
+			released = append(released, reservation)
+		}
+	}
+	sortReservations(released)
+	return released, nil
+}
+
+// Clears the expiry of a hold that has not expired at a time, moving to the next version
+func confirm(reservation *Reservation, now time.Time) error {
+	if !reservation.Held() {
+		return ErrNotHeld()
+	}
+	if reservation.Expired(now) {
+		return ErrHoldExpired()
+	}
+	reservation.Expires = time.Time{}
+	reservation.Version++
+	return nil
+}
+
+func (m *memoryStore) Reservation(id string) (Reservation, error) {
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	reservation, ok := m.reservations[id]
+	if !ok {
+		return Reservation{}, ErrBookingNotFound()
+	}
+	return reservation, nil
+}
+
+func (m *memoryStore) ReservationByCode(code string) (Reservation, error) {
+	m.resMux.Lock()
+	id := m.codes[code]
+	m.resMux.Unlock()
+	return m.Reservation(id)
+}
+
+func (m *memoryStore) Reservations(user string) ([]Reservation, error) {
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	reservations := []Reservation{}
+	for _, reservation := range m.reservations {
+		if reservation.User == user {
+			reservations = append(reservations, reservation)
+		}
+	}
+	sortReservations(reservations)
+	return reservations, nil
+}
+
+func (m *memoryStore) Query(date time.Time) (map[int]string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	users := map[int]string{}
+	for _, room := range m.rooms {
+		room.Mux.Lock()
+		if user := room.Book[date]; user != "" {
+			users[room.Id] = user
+		}
+		room.Mux.Unlock()
+	}
+	return users, nil
+}
+
+func (m *memoryStore) Bookings(room int) (map[time.Time]string, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	r, err := m.room(room)
+	if err != nil {
+		return nil, err
+	}
+	r.Mux.Lock()
+	defer r.Mux.Unlock()
+	bookings := make(map[time.Time]string, len(r.Book))
+	for date, user := range r.Book {
+		bookings[date] = user
+	}
+	return bookings, nil
+}
+
+func (m *memoryStore) CountFree(date time.Time, rooms RoomSet) (int, error) {
+	return m.index.countFree(date, rooms), nil
+}
+
+func (m *memoryStore) FreeRooms(from, to time.Time, rooms RoomSet) (RoomSet, error) {
+	return m.index.freeRooms(from, to, rooms), nil
+}
+
+func (m *memoryStore) ReservationsBetween(from, to time.Time) ([]Reservation, error) {
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	reservations := []Reservation{}
+	for _, reservation := range m.reservations {
+		if reservation.From.Before(to) && from.Before(reservation.To) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	sortReservations(reservations)
+	return reservations, nil
+}
+
+func (m *memoryStore) AddBlackout(blackout Blackout) error {
+	m.blackoutMux.Lock()
+	defer m.blackoutMux.Unlock()
+	current := m.blackouts.Load().([]Blackout)
+	blackouts := make([]Blackout, len(current), len(current)+1)
+	copy(blackouts, current)
+	blackouts = append(blackouts, blackout)
+	sortBlackouts(blackouts)
+	m.blackouts.Store(blackouts)
+	return nil
+}
+
+func (m *memoryStore) RemoveBlackout(id string) error {
+	m.blackoutMux.Lock()
+	defer m.blackoutMux.Unlock()
+	current := m.blackouts.Load().([]Blackout)
+	blackouts := make([]Blackout, 0, len(current))
+	for _, blackout := range current {
+		if blackout.Id != id {
+			blackouts = append(blackouts, blackout)
+		}
+	}
+	if len(blackouts) == len(current) {
+		return ErrBlackoutNotFound()
+	}
+	m.blackouts.Store(blackouts)
+	return nil
+}
+
+// The returned slice is shared and must not be modified
+func (m *memoryStore) Blackouts() ([]Blackout, error) {
+	return m.blackouts.Load().([]Blackout), nil
+}
+
+func (m *memoryStore) AddWebhook(webhook Webhook) error {
+	m.webhookMux.Lock()
+	defer m.webhookMux.Unlock()
+	m.webhooks[webhook.Id] = webhook
+	return nil
+}
+
+func (m *memoryStore) RemoveWebhook(id string) error {
+	m.webhookMux.Lock()
+	defer m.webhookMux.Unlock()
+	if _, ok := m.webhooks[id]; !ok {
+		return ErrWebhookNotFound()
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *memoryStore) Webhooks() ([]Webhook, error) {
+	m.webhookMux.Lock()
+	defer m.webhookMux.Unlock()
+	return m.webhookList(), nil
+}
+
+// Must be called holding the webhook lock
+func (m *memoryStore) webhookList() []Webhook {
+	webhooks := make([]Webhook, 0, len(m.webhooks))
+	for _, webhook := range m.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sortWebhooks(webhooks)
+	return webhooks
+}
+
+func (m *memoryStore) AddDeadLetter(deadLetter DeadLetter) error {
+	m.webhookMux.Lock()
+	defer m.webhookMux.Unlock()
+	m.deadLetters = append(m.deadLetters, deadLetter)
+	if len(m.deadLetters) > maxDeadLetters {
+		m.deadLetters = m.deadLetters[len(m.deadLetters)-maxDeadLetters:]
+	}
+	return nil
+}
+
+func (m *memoryStore) DeadLetters() ([]DeadLetter, error) {
+	m.webhookMux.Lock()
+	defer m.webhookMux.Unlock()
+	return append([]DeadLetter{}, m.deadLetters...), nil
+}
+
+func (m *memoryStore) PutWaitlistEntry(entry WaitlistEntry) error {
+	m.waitlistMux.Lock()
+	defer m.waitlistMux.Unlock()
+	for i := range m.waitlist {
+		if m.waitlist[i].Id == entry.Id {
+			m.waitlist[i] = entry
+			return nil
+		}
+	}
+	m.waitlist = append(m.waitlist, entry)
+	return nil
+}
+
+func (m *memoryStore) RemoveWaitlistEntry(id string) error {
+	m.waitlistMux.Lock()
+	defer m.waitlistMux.Unlock()
+	for i := range m.waitlist {
+		if m.waitlist[i].Id == id {
+			m.waitlist = append(m.waitlist[:i:i], m.waitlist[i+1:]...)
+			return nil
+		}
+	}
+	return ErrWaitlistEntryNotFound()
+}
+
+func (m *memoryStore) Waitlist() ([]WaitlistEntry, error) {
+	m.waitlistMux.Lock()
+	defer m.waitlistMux.Unlock()
+	return append([]WaitlistEntry{}, m.waitlist...), nil
+}
+
+// Reservations are held back while they are copied, availability reads are not
+func (m *memoryStore) Snapshot() (Snapshot, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	m.resMux.Lock()
+	defer m.resMux.Unlock()
+	m.blackoutMux.Lock()
+	defer m.blackoutMux.Unlock()
+	m.webhookMux.Lock()
+	defer m.webhookMux.Unlock()
+	m.waitlistMux.Lock()
+	defer m.waitlistMux.Unlock()
+
+	snapshot := Snapshot{
+		Rooms:        make([]RoomInfo, 0, len(m.rooms)),
+		Reservations: make([]Reservation, 0, len(m.reservations)),
+		Blackouts:    append([]Blackout{}, m.blackouts.Load().([]Blackout)...),
+		Webhooks:     m.webhookList(),
+		DeadLetters:  append([]DeadLetter{}, m.deadLetters...),
+		Waitlist:     append([]WaitlistEntry{}, m.waitlist...),
+	}
+	for _, room := range m.rooms {
+		snapshot.Rooms = append(snapshot.Rooms, room.RoomInfo)
+	}
+	for _, reservation := range m.reservations {
+		snapshot.Reservations = append(snapshot.Reservations, reservation)
+	}
+	sortRooms(snapshot.Rooms)
+	sortReservations(snapshot.Reservations)
+	return snapshot, nil
+}
@@ -0,0 +1,654 @@
+package rooms
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var storeTestRooms = []RoomInfo{
+	{Id: 1, Name: "101", Type: SingleRoom, Capacity: 1},
+	{Id: 2, Name: "102", Type: DoubleRoom, Capacity: 2, Amenities: []string{"balcony"}},
+}
+
+func newTestMemoryStore(t *testing.T, rooms []RoomInfo) (BookingStore, func()) {
+	collection := make([]Room, len(rooms))
+	for i, info := range rooms {
+		collection[i] = Room{info, map[time.Time]string{}, &sync.Mutex{}}
+	}
+	return NewMemoryStore(collection), func() {}
+}
+
+func newTestBoltStore(t *testing.T, rooms []RoomInfo) (BookingStore, func()) {
+	dir, err := ioutil.TempDir("", "rooms")
+	assert.NilError(t, err)
+	store, err := NewBoltStore(filepath.Join(dir, "rooms.db"), rooms)
+	assert.NilError(t, err)
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// every BookingStore implementation must pass the same contract
+var storeImplementations = []struct {
+	name  string
+	store func(*testing.T, []RoomInfo) (BookingStore, func())
+}{
+	{"memory", newTestMemoryStore},
+	{"bolt", newTestBoltStore},
+}
+
+// Returns a reservation of a room for a number of nights from a date
+func stay(id, user string, room int, from time.Time, nights int) Reservation {
+	return Reservation{Id: id, Code: "CODE" + id, User: user, Room: room, From: from, To: from.AddDate(0, 0, nights)}
+}
+
+var storeContractTest = []struct {
+	name string
+	run  func(*testing.T, BookingStore)
+}{
+	{
+		name: "should return the rooms ordered by id",
+		run: func(t *testing.T, s BookingStore) {
+			rooms, err := s.Rooms()
+			assert.NilError(t, err)
+			assert.DeepEqual(t, rooms, storeTestRooms)
+		},
+	},
+	{
+		name: "should reserve an available room",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			booked, err := s.Reserve(stay("1", "John", 1, date, 1))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, true)
+
+			users, err := s.Query(date)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, users, map[int]string{1: "John"})
+		},
+	},
+	{
+		name: "should not reserve a room twice for the same date",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 2, date, 1))
+			booked, err := s.Reserve(stay("2", "Charles", 2, date, 1))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, false)
+
+			users, err := s.Query(date)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, users, map[int]string{2: "John"})
+			_, err = s.Reservation("2")
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+		},
+	},
+	{
+		name: "should keep bookings of different dates apart",
+		run: func(t *testing.T, s BookingStore) {
+			s.Reserve(stay("1", "John", 1, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1))
+
+			users, err := s.Query(time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, users, map[int]string{})
+		},
+	},
+	{
+		name: "should make a released room available again",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 2))
+			assert.NilError(t, s.Release("1", 0))
+
+			users, _ := s.Query(date.AddDate(0, 0, 1))
+			assert.DeepEqual(t, users, map[int]string{})
+			booked, err := s.Reserve(stay("2", "Charles", 1, date, 1))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, true)
+			_, err = s.Reservation("1")
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+			assert.DeepEqual(t, s.Release("1", 0), ErrBookingNotFound())
+		},
+	},
+	{
+		name: "should return an error if the room does not exist",
+		run: func(t *testing.T, s BookingStore) {
+			_, err := s.Reserve(stay("1", "John", 3, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1))
+			assert.DeepEqual(t, err, ErrRoomNotFound())
+		},
+	},
+	{
+		name: "should reserve every night or none of them",
+		run: func(t *testing.T, s BookingStore) {
+			first := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			second := time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "Charles", 1, second, 1))
+
+			booked, err := s.Reserve(stay("2", "John", 1, first, 2))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, false)
+			users, _ := s.Query(first)
+			assert.DeepEqual(t, users, map[int]string{})
+
+			booked, err = s.Reserve(stay("3", "John", 2, first, 2))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, true)
+			users, _ = s.Query(second)
+			assert.DeepEqual(t, users, map[int]string{1: "Charles", 2: "John"})
+		},
+	},
+	{
+		name: "should reserve several rooms at once",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			booked, err := s.Reserve(stay("1", "John", 1, date, 2), stay("2", "John", 2, date, 2))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, true)
+
+			users, _ := s.Query(date.AddDate(0, 0, 1))
+			assert.DeepEqual(t, users, map[int]string{1: "John", 2: "John"})
+			reservations, _ := s.Reservations("John")
+			assert.Equal(t, len(reservations), 2)
+		},
+	},
+	{
+		name: "should reserve none of the rooms if any is taken",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "Charles", 2, date.AddDate(0, 0, 1), 1))
+
+			booked, err := s.Reserve(stay("2", "John", 1, date, 2), stay("3", "John", 2, date, 2))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, false)
+			users, _ := s.Query(date)
+			assert.DeepEqual(t, users, map[int]string{})
+			reservations, _ := s.Reservations("John")
+			assert.DeepEqual(t, reservations, []Reservation{})
+			free, _ := s.FreeRooms(date, date.AddDate(0, 0, 1), nil)
+			assert.DeepEqual(t, free.Ids(), []int{1, 2})
+
+			t.Logf("should not reserve a room twice in the same request")
+			booked, err = s.Reserve(stay("4", "John", 1, date, 2), stay("5", "John", 1, date.AddDate(0, 0, 1), 1))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, false)
+			users, _ = s.Query(date)
+			assert.DeepEqual(t, users, map[int]string{})
+		},
+	},
+	{
+		name: "should move a reservation to other nights and rooms",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 2))
+
+			moved, ok, err := s.Move("1", 0, 1, date.AddDate(0, 0, 1), date.AddDate(0, 0, 3))
+			assert.NilError(t, err)
+			assert.Equal(t, ok, true)
+			want := stay("1", "John", 1, date.AddDate(0, 0, 1), 2)
+			want.Version = 1
+			assert.DeepEqual(t, moved, want)
+			users, _ := s.Query(date)
+			assert.DeepEqual(t, users, map[int]string{})
+			users, _ = s.Query(date.AddDate(0, 0, 2))
+			assert.DeepEqual(t, users, map[int]string{1: "John"})
+
+			moved, ok, err = s.Move("1", 0, 2, date, date.AddDate(0, 0, 1))
+			assert.NilError(t, err)
+			assert.Equal(t, ok, true)
+			users, _ = s.Query(date.AddDate(0, 0, 1))
+			assert.DeepEqual(t, users, map[int]string{})
+			stored, _ := s.ReservationByCode("CODE1")
+			assert.DeepEqual(t, stored, moved)
+			free, _ := s.FreeRooms(date, date.AddDate(0, 0, 3), nil)
+			assert.DeepEqual(t, free.Ids(), []int{1})
+		},
+	},
+	{
+		name: "should not move a reservation to a taken room",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 1))
+			s.Reserve(stay("2", "Charles", 2, date.AddDate(0, 0, 1), 1))
+
+			_, ok, err := s.Move("1", 0, 2, date, date.AddDate(0, 0, 2))
+			assert.NilError(t, err)
+			assert.Equal(t, ok, false)
+			stored, _ := s.Reservation("1")
+			assert.DeepEqual(t, stored, stay("1", "John", 1, date, 1))
+			users, _ := s.Query(date)
+			assert.DeepEqual(t, users, map[int]string{1: "John"})
+
+			_, _, err = s.Move("1", 0, 3, date, date.AddDate(0, 0, 1))
+			assert.DeepEqual(t, err, ErrRoomNotFound())
+			_, _, err = s.Move("3", 0, 1, date, date.AddDate(0, 0, 1))
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+			users, _ = s.Query(date)
+			assert.DeepEqual(t, users, map[int]string{1: "John"})
+		},
+	},
+	{
+		name: "should not reserve with a taken id or confirmation code",
+		run: func(t *testing.T, s BookingStore) {
+			s.Reserve(stay("1", "John", 1, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1))
+
+			_, err := s.Reserve(stay("1", "Charles", 2, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1))
+			assert.DeepEqual(t, err, ErrReservationExists())
+			reservation := stay("2", "Charles", 2, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1)
+			reservation.Code = "CODE1"
+			_, err = s.Reserve(reservation)
+			assert.DeepEqual(t, err, ErrReservationExists())
+
+			users, _ := s.Query(time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC))
+			assert.DeepEqual(t, users, map[int]string{1: "John"})
+		},
+	},
+	{
+		name: "should look reservations up by id, code and user",
+		run: func(t *testing.T, s BookingStore) {
+			first := stay("1", "John", 1, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 2)
+			second := stay("2", "John", 2, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 1)
+			s.Reserve(first)
+			s.Reserve(second)
+			s.Reserve(stay("3", "Charles", 2, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), 1))
+
+			reservation, err := s.Reservation("1")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, reservation, first)
+			reservation, err = s.ReservationByCode("CODE2")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, reservation, second)
+			_, err = s.ReservationByCode("CODE4")
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+
+			reservations, err := s.Reservations("John")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, reservations, []Reservation{second, first})
+		},
+	},
+	{
+		name: "should add a room with the next free id",
+		run: func(t *testing.T, s BookingStore) {
+			room, err := s.AddRoom(RoomInfo{Name: "201", Type: SuiteRoom, Capacity: 4})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, room, RoomInfo{Id: 3, Name: "201", Type: SuiteRoom, Capacity: 4})
+
+			booked, err := s.Reserve(stay("1", "John", 3, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1))
+			assert.NilError(t, err)
+			assert.Equal(t, booked, true)
+		},
+	},
+	{
+		name: "should not add a room with a taken id",
+		run: func(t *testing.T, s BookingStore) {
+			_, err := s.AddRoom(RoomInfo{Id: 2, Name: "202", Type: SingleRoom, Capacity: 1})
+			assert.DeepEqual(t, err, ErrRoomExists())
+
+			rooms, _ := s.Rooms()
+			assert.DeepEqual(t, rooms, storeTestRooms)
+		},
+	},
+	{
+		name: "should update a room keeping its bookings",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 1))
+			updated := RoomInfo{Id: 1, Name: "101", Type: DoubleRoom, Capacity: 2}
+			assert.NilError(t, s.UpdateRoom(updated))
+
+			rooms, _ := s.Rooms()
+			assert.DeepEqual(t, rooms[0], updated)
+			bookings, err := s.Bookings(1)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, bookings, map[time.Time]string{date: "John"})
+
+			assert.DeepEqual(t, s.UpdateRoom(RoomInfo{Id: 3}), ErrRoomNotFound())
+		},
+	},
+	{
+		name: "should remove a room booked only before a date",
+		run: func(t *testing.T, s BookingStore) {
+			s.Reserve(stay("1", "John", 1, time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC), 1))
+			assert.NilError(t, s.RemoveRoom(1, time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)))
+
+			rooms, _ := s.Rooms()
+			assert.DeepEqual(t, rooms, storeTestRooms[1:])
+			_, err := s.Bookings(1)
+			assert.DeepEqual(t, err, ErrRoomNotFound())
+			assert.DeepEqual(t, s.RemoveRoom(1, time.Time{}), ErrRoomNotFound())
+			kept, err := s.ReservationByCode("CODE1")
+			assert.NilError(t, err)
+			assert.Equal(t, kept.Room, 1)
+			assert.NilError(t, s.Release("1", 0))
+		},
+	},
+	{
+		name: "should not remove a room booked on or after a date",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 2, date, 1))
+			assert.DeepEqual(t, s.RemoveRoom(2, date), ErrRoomHasBookings())
+
+			rooms, _ := s.Rooms()
+			assert.DeepEqual(t, rooms, storeTestRooms)
+		},
+	},
+	{
+		name: "should confirm a hold that has not expired",
+		run: func(t *testing.T, s BookingStore) {
+			now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+			hold := stay("1", "John", 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 2)
+			hold.Expires = now.Add(time.Minute)
+			s.Reserve(hold)
+
+			confirmed, err := s.Confirm("1", 0, now)
+			assert.NilError(t, err)
+			hold.Expires = time.Time{}
+			hold.Version = 1
+			assert.DeepEqual(t, confirmed, hold)
+			stored, _ := s.Reservation("1")
+			assert.DeepEqual(t, stored, hold)
+
+			_, err = s.Confirm("1", 0, now)
+			assert.DeepEqual(t, err, ErrNotHeld())
+			_, err = s.Confirm("2", 0, now)
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+		},
+	},
+	{
+		name: "should only change reservations with the version expected",
+		run: func(t *testing.T, s BookingStore) {
+			now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			hold := stay("1", "John", 1, date, 2)
+			hold.Version, hold.Expires = 1, now.Add(time.Minute)
+			s.Reserve(hold)
+
+			_, err := s.Confirm("1", 2, now)
+			assert.DeepEqual(t, err, ErrVersionConflict())
+			confirmed, err := s.Confirm("1", 1, now)
+			assert.NilError(t, err)
+			assert.Equal(t, confirmed.Version, 2)
+
+			_, _, err = s.Move("1", 1, 2, date, date.AddDate(0, 0, 1))
+			assert.DeepEqual(t, err, ErrVersionConflict())
+			moved, ok, err := s.Move("1", 2, 2, date, date.AddDate(0, 0, 1))
+			assert.NilError(t, err)
+			assert.Equal(t, ok, true)
+			assert.Equal(t, moved.Version, 3)
+
+			assert.DeepEqual(t, s.Release("1", 2), ErrVersionConflict())
+			stored, _ := s.Reservation("1")
+			assert.DeepEqual(t, stored, moved)
+			assert.NilError(t, s.Release("1", 3))
+			_, err = s.Reservation("1")
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+		},
+	},
+	{
+		name: "should not confirm an expired hold",
+		run: func(t *testing.T, s BookingStore) {
+			now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+			hold := stay("1", "John", 1, time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), 2)
+			hold.Expires = now
+			s.Reserve(hold)
+
+			_, err := s.Confirm("1", 0, now)
+			assert.DeepEqual(t, err, ErrHoldExpired())
+			stored, _ := s.Reservation("1")
+			assert.DeepEqual(t, stored, hold)
+		},
+	},
+	{
+		name: "should release only the expired holds",
+		run: func(t *testing.T, s BookingStore) {
+			now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			expired := stay("1", "John", 1, date, 2)
+			expired.Expires = now.Add(-time.Second)
+			held := stay("2", "Anna", 2, date, 1)
+			held.Expires = now.Add(time.Second)
+			s.Reserve(expired)
+			s.Reserve(held)
+			s.Reserve(stay("3", "Bob", 1, date.AddDate(0, 0, 2), 1))
+
+			released, err := s.ReleaseExpired(now)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, released, []Reservation{expired})
+
+			users, _ := s.Query(date)
+			assert.DeepEqual(t, users, map[int]string{2: "Anna"})
+			_, err = s.Reservation("1")
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+			_, err = s.ReservationByCode("CODE1")
+			assert.DeepEqual(t, err, ErrBookingNotFound())
+			bookings, _ := s.Bookings(1)
+			assert.DeepEqual(t, bookings, map[time.Time]string{date.AddDate(0, 0, 2): "Bob"})
+		},
+	},
+	{
+		name: "should count the free rooms of a date",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 2))
+
+			free, err := s.CountFree(date, nil)
+			assert.NilError(t, err)
+			assert.Equal(t, free, 1)
+			free, _ = s.CountFree(date, NewRoomSet(1))
+			assert.Equal(t, free, 0)
+			free, _ = s.CountFree(date.AddDate(0, 0, 2), nil)
+			assert.Equal(t, free, 2)
+
+			assert.NilError(t, s.Release("1", 0))
+			free, _ = s.CountFree(date, nil)
+			assert.Equal(t, free, 2)
+		},
+	},
+	{
+		name: "should return the rooms free for every night of a stay",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date.AddDate(0, 0, 1), 1))
+
+			free, err := s.FreeRooms(date, date.AddDate(0, 0, 3), nil)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, free.Ids(), []int{2})
+			free, _ = s.FreeRooms(date, date.AddDate(0, 0, 1), nil)
+			assert.DeepEqual(t, free.Ids(), []int{1, 2})
+			free, _ = s.FreeRooms(date, date.AddDate(0, 0, 1), NewRoomSet(1, 3))
+			assert.DeepEqual(t, free.Ids(), []int{1})
+		},
+	},
+	{
+		name: "should count added and removed rooms",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 1))
+			s.AddRoom(RoomInfo{Id: 3, Name: "103", Type: SingleRoom, Capacity: 1})
+			free, _ := s.CountFree(date, nil)
+			assert.Equal(t, free, 2)
+
+			assert.NilError(t, s.RemoveRoom(1, date.AddDate(0, 0, 1)))
+			free, _ = s.CountFree(date, nil)
+			assert.Equal(t, free, 2)
+			rooms, _ := s.FreeRooms(date, date.AddDate(0, 0, 1), nil)
+			assert.DeepEqual(t, rooms.Ids(), []int{2, 3})
+		},
+	},
+	{
+		name: "should return the reservations overlapping a range",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			s.Reserve(stay("1", "John", 1, date, 2))
+			s.Reserve(stay("2", "Anna", 2, date.AddDate(0, 0, 2), 1))
+			s.Reserve(stay("3", "Bob", 1, date.AddDate(0, 0, 4), 1))
+
+			reservations, err := s.ReservationsBetween(date.AddDate(0, 0, 1), date.AddDate(0, 0, 3))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, reservations, []Reservation{stay("1", "John", 1, date, 2), stay("2", "Anna", 2, date.AddDate(0, 0, 2), 1)})
+			reservations, _ = s.ReservationsBetween(date.AddDate(0, 0, 3), date.AddDate(0, 0, 4))
+			assert.DeepEqual(t, reservations, []Reservation{})
+		},
+	},
+	{
+		name: "should add, list and remove blackouts",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			late := Blackout{Id: "1", Room: 1, From: date.AddDate(0, 0, 2), To: date.AddDate(0, 0, 3), Reason: "painting"}
+			early := Blackout{Id: "2", From: date, To: date.AddDate(0, 0, 1), Reason: "inspection"}
+			assert.NilError(t, s.AddBlackout(late))
+			assert.NilError(t, s.AddBlackout(early))
+
+			blackouts, err := s.Blackouts()
+			assert.NilError(t, err)
+			assert.DeepEqual(t, blackouts, []Blackout{early, late})
+
+			assert.NilError(t, s.RemoveBlackout("2"))
+			assert.DeepEqual(t, s.RemoveBlackout("2"), ErrBlackoutNotFound())
+			blackouts, _ = s.Blackouts()
+			assert.DeepEqual(t, blackouts, []Blackout{late})
+		},
+	},
+	{
+		name: "should add, list and remove webhooks",
+		run: func(t *testing.T, s BookingStore) {
+			billing := Webhook{Id: "2", Url: "https://billing.local/events", Secret: "s3cr3t", Events: []string{}}
+			assert.NilError(t, s.AddWebhook(testWebhook))
+			assert.NilError(t, s.AddWebhook(billing))
+
+			webhooks, err := s.Webhooks()
+			assert.NilError(t, err)
+			assert.DeepEqual(t, webhooks, []Webhook{testWebhook, billing})
+
+			assert.NilError(t, s.RemoveWebhook("2"))
+			assert.DeepEqual(t, s.RemoveWebhook("2"), ErrWebhookNotFound())
+			webhooks, _ = s.Webhooks()
+			assert.DeepEqual(t, webhooks, []Webhook{testWebhook})
+		},
+	},
+	{
+		name: "should keep the latest dead letters",
+		run: func(t *testing.T, s BookingStore) {
+			deadLetters, err := s.DeadLetters()
+			assert.NilError(t, err)
+			assert.DeepEqual(t, deadLetters, []DeadLetter{})
+
+			for attempts := 1; attempts <= maxDeadLetters+2; attempts++ {
+				deadLetter := testDeadLetter
+				deadLetter.Attempts = attempts
+				assert.NilError(t, s.AddDeadLetter(deadLetter))
+			}
+			deadLetters, _ = s.DeadLetters()
+			assert.Equal(t, len(deadLetters), maxDeadLetters)
+			assert.Equal(t, deadLetters[0].Attempts, 3)
+			assert.Equal(t, deadLetters[maxDeadLetters-1].Attempts, maxDeadLetters+2)
+		},
+	},
+	{
+		name: "should store, list and remove waitlist entries in the order they were first stored",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			first := WaitlistEntry{Id: "2", User: "John", From: date, To: date.AddDate(0, 0, 1), Filter: RoomFilter{}}
+			second := WaitlistEntry{Id: "1", User: "Anna", From: date, To: date.AddDate(0, 0, 2), Filter: RoomFilter{Type: "double"}}
+			third := WaitlistEntry{Id: "3", User: "Bob", From: date, To: date.AddDate(0, 0, 1), Filter: RoomFilter{}}
+			assert.NilError(t, s.PutWaitlistEntry(first))
+			assert.NilError(t, s.PutWaitlistEntry(second))
+			assert.NilError(t, s.PutWaitlistEntry(third))
+			first.Reservation = "R1"
+			assert.NilError(t, s.PutWaitlistEntry(first))
+
+			entries, err := s.Waitlist()
+			assert.NilError(t, err)
+			assert.DeepEqual(t, entries, []WaitlistEntry{first, second, third})
+
+			assert.NilError(t, s.RemoveWaitlistEntry("1"))
+			assert.DeepEqual(t, s.RemoveWaitlistEntry("1"), ErrWaitlistEntryNotFound())
+			entries, _ = s.Waitlist()
+			assert.DeepEqual(t, entries, []WaitlistEntry{first, third})
+		},
+	},
+	{
+		name: "should snapshot the rooms, reservations, blackouts, webhooks and waitlist",
+		run: func(t *testing.T, s BookingStore) {
+			date := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+			blackout := Blackout{Id: "1", Room: 2, From: date, To: date.AddDate(0, 0, 1), Reason: "painting"}
+			s.Reserve(stay("2", "Anna", 1, date.AddDate(0, 0, 2), 1))
+			s.Reserve(stay("1", "John", 1, date, 2))
+			assert.NilError(t, s.AddBlackout(blackout))
+			assert.NilError(t, s.AddWebhook(testWebhook))
+			assert.NilError(t, s.AddDeadLetter(testDeadLetter))
+			assert.NilError(t, s.PutWaitlistEntry(snapshotTestWaitlistEntry))
+
+			snapshot, err := s.Snapshot()
+			assert.NilError(t, err)
+			assert.DeepEqual(t, snapshot, Snapshot{
+				Rooms:        storeTestRooms,
+				Reservations: []Reservation{stay("1", "John", 1, date, 2), stay("2", "Anna", 1, date.AddDate(0, 0, 2), 1)},
+				Blackouts:    []Blackout{blackout},
+				Webhooks:     []Webhook{testWebhook},
+				DeadLetters:  []DeadLetter{testDeadLetter},
+				Waitlist:     []WaitlistEntry{snapshotTestWaitlistEntry},
+			})
+		},
+	},
+}
+
+func TestBookingStoreContract(t *testing.T) {
+	t.Log("BookingStoreContract")
+
+	for _, impl := range storeImplementations {
+		for _, testcase := range storeContractTest {
+			t.Logf("%s: %s", impl.name, testcase.name)
+
+			store, cleanup := impl.store(t, storeTestRooms)
+			testcase.run(t, store)
+			cleanup()
+		}
+	}
+}
+
+func TestBookingStoreNoRooms(t *testing.T) {
+	t.Log("BookingStoreNoRooms")
+
+	for _, impl := range storeImplementations {
+		t.Logf("%s: should return an empty list of rooms", impl.name)
+
+		store, cleanup := impl.store(t, nil)
+		rooms, err := store.Rooms()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, rooms, []RoomInfo{})
+		cleanup()
+	}
+}
+
+func TestBoltStorePersistence(t *testing.T) {
+	t.Log("BoltStorePersistence")
+
+	dir, err := ioutil.TempDir("", "rooms")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rooms.db")
+	date := time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC)
+
+	store, err := NewBoltStore(path, storeTestRooms)
+	assert.NilError(t, err)
+	store.Reserve(stay("1", "John", 2, date, 1))
+	assert.NilError(t, store.Close())
+
+	store, err = NewBoltStore(path, storeTestRooms)
+	assert.NilError(t, err)
+	defer store.Close()
+	users, err := store.Query(date)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, users, map[int]string{2: "John"})
+	reservation, err := store.ReservationByCode("CODE1")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reservation, stay("1", "John", 2, date, 1))
+}
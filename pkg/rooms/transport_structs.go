@@ -3,20 +3,218 @@ package rooms
 import "time"
 
 type BookRequest struct {
-	Token string    `json:"token"`
-	Date  time.Time `json:"date"`
+	Token          string     `json:"token"`
+	IdempotencyKey string     `json:"idempotency_key"`
+	Count          int        `json:"count"`
+	From           time.Time  `json:"from"`
+	To             time.Time  `json:"to"`
+	Filter         RoomFilter `json:"filter"`
 }
 
 type BookResponse struct {
-	Id  int   `json:"id"`
-	Err error `json:"err"`
+	Reservation  Reservation   `json:"reservation"`
+	Reservations []Reservation `json:"reservations,omitempty"`
+	Err          error         `json:"err"`
+}
+
+type ConfirmRequest struct {
+	Token   string `json:"token"`
+	Id      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+type ModifyBookingRequest struct {
+	Token   string    `json:"token"`
+	Id      string    `json:"id"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Room    int       `json:"room"`
+	Version int       `json:"version"`
 }
 
 type CheckRequest struct {
-	Date time.Time `json:"date"`
+	Date   time.Time  `json:"date"`
+	Filter RoomFilter `json:"filter"`
 }
 
 type CheckResponse struct {
 	Available int   `json:"available"`
 	Err       error `json:"err"`
 }
+
+type CancelRequest struct {
+	Token   string `json:"token"`
+	Id      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+type CancelResponse struct {
+	Err error `json:"err"`
+}
+
+type AvailabilityRequest struct {
+	From   time.Time  `json:"from"`
+	To     time.Time  `json:"to"`
+	Filter RoomFilter `json:"filter"`
+}
+
+// Updates is closed when the watch ends
+type WatchAvailabilityResponse struct {
+	Updates <-chan []DayAvailability `json:"-"`
+	Err     error                    `json:"err"`
+}
+
+type AvailabilityResponse struct {
+	Days []DayAvailability `json:"days"`
+	Err  error             `json:"err"`
+}
+
+type ListRoomsRequest struct{}
+
+type ListRoomsResponse struct {
+	Rooms []RoomInfo `json:"rooms"`
+	Err   error      `json:"err"`
+}
+
+type RoomRequest struct {
+	Token string   `json:"token"`
+	Room  RoomInfo `json:"room"`
+}
+
+type RoomResponse struct {
+	Room RoomInfo `json:"room"`
+	Err  error    `json:"err"`
+}
+
+type DecommissionRequest struct {
+	Token string `json:"token"`
+	Id    int    `json:"id"`
+}
+
+type DecommissionResponse struct {
+	Err error `json:"err"`
+}
+
+type ListBookingsRequest struct {
+	Token string    `json:"token"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+}
+
+type ListBookingsResponse struct {
+	Bookings []Booking `json:"bookings"`
+	Err      error     `json:"err"`
+}
+
+type GetReservationRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type GetReservationResponse struct {
+	Reservation Reservation `json:"reservation"`
+	Err         error       `json:"err"`
+}
+
+type JoinWaitlistRequest struct {
+	Token  string     `json:"token"`
+	From   time.Time  `json:"from"`
+	To     time.Time  `json:"to"`
+	Filter RoomFilter `json:"filter"`
+}
+
+type WaitlistPositionRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type WaitlistResponse struct {
+	Entry WaitlistEntry `json:"entry"`
+	Err   error         `json:"err"`
+}
+
+type AddBlackoutRequest struct {
+	Token    string   `json:"token"`
+	Blackout Blackout `json:"blackout"`
+}
+
+type AddBlackoutResponse struct {
+	Blackout  Blackout      `json:"blackout"`
+	Conflicts []Reservation `json:"conflicts"`
+	Err       error         `json:"err"`
+}
+
+type ListBlackoutsRequest struct {
+	Token string `json:"token"`
+}
+
+type ListBlackoutsResponse struct {
+	Blackouts []Blackout `json:"blackouts"`
+	Err       error      `json:"err"`
+}
+
+type RemoveBlackoutRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type RemoveBlackoutResponse struct {
+	Err error `json:"err"`
+}
+
+type HistoryRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+	Room  int    `json:"room"`
+}
+
+type HistoryResponse struct {
+	Events []Event `json:"events"`
+	Err    error   `json:"err"`
+}
+
+type SnapshotRequest struct {
+	Token string `json:"token"`
+}
+
+type SnapshotResponse struct {
+	Snapshot Snapshot `json:"snapshot"`
+	Err      error    `json:"err"`
+}
+
+type AddWebhookRequest struct {
+	Token   string  `json:"token"`
+	Webhook Webhook `json:"webhook"`
+}
+
+type AddWebhookResponse struct {
+	Webhook Webhook `json:"webhook"`
+	Err     error   `json:"err"`
+}
+
+type ListWebhooksRequest struct {
+	Token string `json:"token"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+	Err      error     `json:"err"`
+}
+
+type RemoveWebhookRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type RemoveWebhookResponse struct {
+	Err error `json:"err"`
+}
+
+type DeadLettersRequest struct {
+	Token string `json:"token"`
+}
+
+type DeadLettersResponse struct {
+	DeadLetters []DeadLetter `json:"dead_letters"`
+	Err         error        `json:"err"`
+}
@@ -0,0 +1,156 @@
+package rooms
+
+import (
+	"context"
+	"time"
+)
+
+// WaitlistEntry is a stay waiting for a room to become available
+// Position is the place in the queue of the check-in date (starting at 1)
+// Once the stay is booked Position is 0 and Reservation is the id of the booking
+// Once the stay breaks a booking rule or the quota of its user Position is 0
+// and Rejected is the reason, the stay is no longer booked
+// Entries are dropped once their check-in date has passed
+type WaitlistEntry struct {
+	Id          string     `json:"id"`
+	User        string     `json:"user"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Filter      RoomFilter `json:"filter"`
+	Position    int        `json:"position"`
+	Reservation string     `json:"reservation"`
+	Rejected    string     `json:"rejected,omitempty"`
+}
+
+// Returns whether the entry is still queued for a room
+func (e WaitlistEntry) Waiting() bool {
+	return e.Reservation == "" && e.Rejected == ""
+}
+
+// Returns the entry with an id and its position from the waitlist of the store
+func waitlistEntry(store BookingStore, id string) (WaitlistEntry, error) {
+	entries, err := store.Waitlist()
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	position := map[time.Time]int{}
+	for _, entry := range entries {
+		if entry.Waiting() {
+			position[entry.From]++
+			entry.Position = position[entry.From]
+		}
+		if entry.Id == id {
+			return entry, nil
+		}
+	}
+	return WaitlistEntry{}, ErrWaitlistEntryNotFound()
+}
+
+// Returns whether a stay failed to be booked for a reason it will keep until check-in
+func rejected(err error) bool {
+	switch err := err.(type) {
+	case RuleViolation:
+		return true
+	case ErrorWithMsg:
+		return err.Msg == QuotaExceeded
+	}
+	return false
+}
+
+// Joins the waitlist for a stay when no room matching the filter is available
+// The stay is booked as soon as a room is released, in the order users joined
+// the queue of each check-in date (write/blocking)
+// Returns the entry with its position, or already booked if a room was available
+// Returns an error if authentication token is invalid, the range or filter are invalid
+// or the stay breaks a booking rule
+func (r roomsService) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter RoomFilter) (WaitlistEntry, error) {
+
+	// validate token
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	if _, err := nights(from, to); err != nil {
+		return WaitlistEntry{}, err
+	}
+	if err := filter.Validate(); err != nil {
+		return WaitlistEntry{}, err
+	}
+	if err := r.checkRules(Reservation{User: user, From: from, To: to}, filter.Type); err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	entry := WaitlistEntry{Id: newReservationID(), User: user, From: from, To: to, Filter: filter}
+	r.waitlistMux.Lock()
+	err = r.store.PutWaitlistEntry(entry)
+	r.waitlistMux.Unlock()
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	r.promoteWaitlist()
+	return r.waitlistEntry(user, entry.Id)
+}
+
+// Returns a waitlist entry with its current position (read/non-blocking)
+// Returns an error if authentication token is invalid,
+// the entry does not exist or belongs to another user
+func (r roomsService) WaitlistPosition(ctx context.Context, token, id string) (WaitlistEntry, error) {
+
+	// validate token
+	user, err := r.validator.Validate(ctx, token)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	return r.waitlistEntry(user, id)
+}
+
+func (r roomsService) waitlistEntry(user, id string) (WaitlistEntry, error) {
+	entry, err := waitlistEntry(r.store, id)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	if entry.User != user {
+		return WaitlistEntry{}, ErrNotBookingOwner()
+	}
+	return entry, nil
+}
+
+// Books every waiting stay that fits in the available rooms,
+// in the order they joined the waitlist
+// Stays breaking a booking rule or the quota of their user are rejected
+// Entries whose check-in date has passed are dropped, booked or not
+// Called after rooms are released or added
+func (r roomsService) promoteWaitlist() {
+	r.waitlistMux.Lock()
+	defer r.waitlistMux.Unlock()
+	entries, err := r.store.Waitlist()
+	if err != nil {
+		return
+	}
+	today := today(r.now())
+	for _, entry := range entries {
+		if entry.From.Before(today) {
+			if err := r.store.RemoveWaitlistEntry(entry.Id); err != nil {
+				return
+			}
+			continue
+		}
+		if !entry.Waiting() {
+			continue
+		}
+		reservation, err := r.book(Reservation{User: entry.User, From: entry.From, To: entry.To}, entry.Filter)
+		switch {
+		case err == nil:
+			entry.Reservation = reservation.Id
+		case rejected(err):
+			entry.Rejected = err.Error()
+		default:
+			continue
+		}
+		if err := r.store.PutWaitlistEntry(entry); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package rooms
+
+import (
+	"context"
+	"go-booking-service/pb"
+	jwt "go-booking-service/pkg/token"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var testWaitlistEntry = WaitlistEntry{
+	Id:       "0c8e2f6a4b1d9e7f3a5c2b8d6e4f1a9c",
+	User:     "John",
+	From:     time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:       time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+	Filter:   RoomFilter{Type: "double"},
+	Position: 2,
+}
+
+var testPBWaitlistEntry = &pb.WaitlistEntry{
+	Id:       "0c8e2f6a4b1d9e7f3a5c2b8d6e4f1a9c",
+	User:     "John",
+	From:     time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC).Unix(),
+	To:       time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC).Unix(),
+	Filter:   &pb.RoomFilter{Type: "double"},
+	Position: 2,
+}
+
+// Time the waitlists are promoted at, the day before their stays
+func waitlistTestClock() time.Time {
+	return time.Date(2020, 6, 12, 12, 0, 0, 0, time.UTC)
+}
+
+// Uses the token as the name of the user
+type validatorUser struct{}
+
+func (v validatorUser) Validate(_ context.Context, token string) (string, error) {
+	return token, nil
+}
+
+var serviceJoinWaitlistTest = []struct {
+	name         string
+	reservations []Reservation
+	validator    Validator
+	from         time.Time
+	to           time.Time
+	position     int
+	booked       bool
+	err          error
+}{
+	{
+		name:         "should queue the stay if no room is available",
+		reservations: []Reservation{testReservation},
+		validator:    validatorCorrect{},
+		from:         time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		to:           time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		position:     1,
+	},
+	{
+		name:      "should book the stay if a room is available",
+		validator: validatorCorrect{},
+		from:      time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		to:        time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		booked:    true,
+	},
+	{
+		name:      "should return an error if the date range is invalid",
+		validator: validatorCorrect{},
+		from:      time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		to:        time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		err:       ErrInvalidDateRange(),
+	},
+	{
+		name:      "should return an error if the token is invalid",
+		validator: validatorIncorrect{},
+		from:      time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		to:        time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		err:       jwt.ErrInvalidToken(),
+	},
+}
+
+func TestServiceJoinWaitlist(t *testing.T) {
+	t.Log("ServiceJoinWaitlist")
+
+	for _, testcase := range serviceJoinWaitlistTest {
+		t.Logf(testcase.name)
+
+		rs := NewRoomsServer(newReservationsTestStore(testcase.reservations...), testcase.validator, WithClock(waitlistTestClock))
+		entry, err := rs.JoinWaitlist(context.Background(), "jjj.www.ttt", testcase.from, testcase.to, RoomFilter{})
+
+		assert.DeepEqual(t, err, testcase.err)
+		assert.Equal(t, entry.Position, testcase.position)
+		assert.Equal(t, entry.Reservation != "", testcase.booked)
+	}
+}
+
+func TestServiceWaitlistPromotion(t *testing.T) {
+	t.Log("ServiceWaitlistPromotion")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	store := newReservationsTestStore(testReservation)
+	rs := NewRoomsServer(store, validatorUser{}, WithAdmins("Admin"), WithClock(waitlistTestClock))
+
+	anna, err := rs.JoinWaitlist(ctx, "Anna", from, to, RoomFilter{})
+	assert.NilError(t, err)
+	bob, err := rs.JoinWaitlist(ctx, "Bob", from, to, RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, anna.Position, 1)
+	assert.Equal(t, bob.Position, 2)
+
+	t.Logf("should return an error if the entry belongs to another user")
+	_, err = rs.WaitlistPosition(ctx, "Anna", bob.Id)
+	assert.DeepEqual(t, err, ErrNotBookingOwner())
+
+	t.Logf("should return an error if the entry does not exist")
+	_, err = rs.WaitlistPosition(ctx, "Anna", "unknown")
+	assert.DeepEqual(t, err, ErrWaitlistEntryNotFound())
+
+	t.Logf("should book the first user in the waitlist when a booking is cancelled")
+	assert.NilError(t, rs.Cancel(ctx, "John", testReservation.Id, 0))
+	anna, err = rs.WaitlistPosition(ctx, "Anna", anna.Id)
+	assert.NilError(t, err)
+	assert.Equal(t, anna.Position, 0)
+	reservation, err := store.Reservation(anna.Reservation)
+	assert.NilError(t, err)
+	assert.Equal(t, reservation.User, "Anna")
+	assert.DeepEqual(t, reservation.From, from)
+	bob, err = rs.WaitlistPosition(ctx, "Bob", bob.Id)
+	assert.NilError(t, err)
+	assert.Equal(t, bob.Position, 1)
+
+	t.Logf("should book the next user in the waitlist when a room is added")
+	_, err = rs.CreateRoom(ctx, "Admin", RoomInfo{Name: "102", Type: "single", Capacity: 1})
+	assert.NilError(t, err)
+	bob, err = rs.WaitlistPosition(ctx, "Bob", bob.Id)
+	assert.NilError(t, err)
+	assert.Equal(t, bob.Position, 0)
+	assert.Assert(t, bob.Reservation != "")
+}
+
+func TestServiceWaitlistPrune(t *testing.T) {
+	t.Log("ServiceWaitlistPrune")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	now := waitlistTestClock()
+	store := newReservationsTestStore(testReservation)
+	rs := NewRoomsServer(store, validatorUser{}, WithClock(func() time.Time { return now })).(roomsService)
+
+	anna, err := rs.JoinWaitlist(ctx, "Anna", from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	bob, err := rs.JoinWaitlist(ctx, "Bob", from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should take booked entries off the queue")
+	assert.NilError(t, rs.Cancel(ctx, "John", testReservation.Id, 0))
+	entries, err := store.Waitlist()
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 2)
+	assert.Assert(t, !entries[0].Waiting())
+	assert.Assert(t, entries[1].Waiting())
+	anna, err = rs.WaitlistPosition(ctx, "Anna", anna.Id)
+	assert.NilError(t, err)
+	assert.Assert(t, anna.Reservation != "")
+	bob, err = rs.WaitlistPosition(ctx, "Bob", bob.Id)
+	assert.NilError(t, err)
+	assert.Equal(t, bob.Position, 1)
+
+	t.Logf("should drop the entries whose check-in has passed")
+	now = from.AddDate(0, 0, 2)
+	rs.promoteWaitlist()
+	entries, err = store.Waitlist()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, entries, []WaitlistEntry{})
+	_, err = rs.WaitlistPosition(ctx, "Anna", anna.Id)
+	assert.DeepEqual(t, err, ErrWaitlistEntryNotFound())
+	_, err = rs.WaitlistPosition(ctx, "Bob", bob.Id)
+	assert.DeepEqual(t, err, ErrWaitlistEntryNotFound())
+}
+
+func TestServiceWaitlistRejected(t *testing.T) {
+	t.Log("ServiceWaitlistRejected")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	store := newReservationsTestStore(testReservation, stay("2", "Anna", 1, from.AddDate(0, 0, 5), 1))
+	rs := NewRoomsServer(store, validatorUser{}, WithClock(waitlistTestClock), WithQuota(Quota{MaxActiveBookings: 1}))
+
+	t.Logf("should reject the stays over the quota of their user")
+	anna, err := rs.JoinWaitlist(ctx, "Anna", from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, anna.Position, 0)
+	assert.Equal(t, anna.Reservation, "")
+	assert.Equal(t, anna.Rejected, QuotaExceeded)
+	bob, err := rs.JoinWaitlist(ctx, "Bob", from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, bob.Position, 1)
+
+	t.Logf("should not book the rejected stays")
+	assert.NilError(t, rs.Cancel(ctx, "John", testReservation.Id, 0))
+	anna, err = rs.WaitlistPosition(ctx, "Anna", anna.Id)
+	assert.NilError(t, err)
+	assert.Equal(t, anna.Reservation, "")
+	bob, err = rs.WaitlistPosition(ctx, "Bob", bob.Id)
+	assert.NilError(t, err)
+	assert.Assert(t, bob.Reservation != "")
+}
+
+func TestServiceWaitlistPersistence(t *testing.T) {
+	t.Log("ServiceWaitlistPersistence")
+	ctx := context.Background()
+	from := time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+	journal := NewMemoryJournal()
+	rs := NewRoomsServer(newReservationsTestStore(), validatorUser{}, WithClock(waitlistTestClock), WithJournal(journal))
+	_, err := rs.Book(ctx, "John", "", 1, from, from.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	anna, err := rs.JoinWaitlist(ctx, "Anna", from, from.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should keep the waitlist in the journal")
+	restarted := newReservationsTestStore()
+	assert.NilError(t, Replay(journal, restarted))
+	rs = NewRoomsServer(restarted, validatorUser{}, WithClock(waitlistTestClock))
+	entry, err := rs.WaitlistPosition(ctx, "Anna", anna.Id)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, entry, anna)
+
+	t.Logf("should book the restored entries")
+	booked, err := restarted.Reservations("John")
+	assert.NilError(t, err)
+	assert.NilError(t, rs.Cancel(ctx, "John", booked[0].Id, 0))
+	entry, err = rs.WaitlistPosition(ctx, "Anna", anna.Id)
+	assert.NilError(t, err)
+	assert.Assert(t, entry.Reservation != "")
+}
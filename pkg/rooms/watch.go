@@ -0,0 +1,198 @@
+package rooms
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// availabilityWatchers wakes the watchers of the dates whose availability may have changed
+type availabilityWatchers struct {
+	watchers map[*availabilityWatcher]struct{}
+	mux      sync.Mutex
+}
+
+// availabilityWatcher is woken once for any number of changes
+// to the dates from one to another (both included) until it reads them
+type availabilityWatcher struct {
+	from, to time.Time
+	changed  chan struct{}
+}
+
+func newAvailabilityWatchers() *availabilityWatchers {
+	return &availabilityWatchers{watchers: map[*availabilityWatcher]struct{}{}}
+}
+
+func (w *availabilityWatchers) add(from, to time.Time) *availabilityWatcher {
+	watcher := &availabilityWatcher{from: from, to: to, changed: make(chan struct{}, 1)}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.watchers[watcher] = struct{}{}
+	return watcher
+}
+
+func (w *availabilityWatchers) remove(watcher *availabilityWatcher) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	delete(w.watchers, watcher)
+}
+
+// Wakes the watchers of any night from one date (included) to another (excluded),
+// every watcher if both are zero
+func (w *availabilityWatchers) notify(from, to time.Time) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for watcher := range w.watchers {
+		if !from.IsZero() && (watcher.to.Before(from) || !to.After(watcher.from)) {
+			continue
+		}
+		select {
+		case watcher.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watchableStore is a store waking the availability watchers itself,
+// as its changes may not all be made through the service
+type watchableStore interface {
+	watch(watchers *availabilityWatchers)
+}
+
+// watchedStore wakes the availability watchers on every change to the rooms,
+// reservations or blackouts of the store
+type watchedStore struct {
+	BookingStore
+	watchers *availabilityWatchers
+}
+
+func (s watchedStore) AddRoom(room RoomInfo) (RoomInfo, error) {
+	added, err := s.BookingStore.AddRoom(room)
+	if err == nil {
+		s.watchers.notify(time.Time{}, time.Time{})
+	}
+	return added, err
+}
+
+func (s watchedStore) UpdateRoom(room RoomInfo) error {
+	err := s.BookingStore.UpdateRoom(room)
+	if err == nil {
+		s.watchers.notify(time.Time{}, time.Time{})
+	}
+	return err
+}
+
+func (s watchedStore) RemoveRoom(room int, from time.Time) error {
+	err := s.BookingStore.RemoveRoom(room, from)
+	if err == nil {
+		s.watchers.notify(time.Time{}, time.Time{})
+	}
+	return err
+}
+
+func (s watchedStore) Reserve(reservations ...Reservation) (bool, error) {
+	reserved, err := s.BookingStore.Reserve(reservations...)
+	if err == nil && reserved {
+		for _, reservation := range reservations {
+			s.watchers.notify(reservation.From, reservation.To)
+		}
+	}
+	return reserved, err
+}
+
+func (s watchedStore) Release(id string, version int) error {
+	reservation, err := s.BookingStore.Reservation(id)
+	if err != nil {
+		return err
+	}
+	if err := s.BookingStore.Release(id, version); err != nil {
+		return err
+	}
+	s.watchers.notify(reservation.From, reservation.To)
+	return nil
+}
+
+func (s watchedStore) Move(id string, version, room int, from, to time.Time) (Reservation, bool, error) {
+	previous, err := s.BookingStore.Reservation(id)
+	if err != nil {
+		return Reservation{}, false, err
+	}
+	moved, ok, err := s.BookingStore.Move(id, version, room, from, to)
+	if err == nil && ok {
+		s.watchers.notify(previous.From, previous.To)
+		s.watchers.notify(moved.From, moved.To)
+	}
+	return moved, ok, err
+}
+
+func (s watchedStore) ReleaseExpired(now time.Time) ([]Reservation, error) {
+	released, err := s.BookingStore.ReleaseExpired(now)
+	for _, reservation := range released {
+		s.watchers.notify(reservation.From, reservation.To)
+	}
+	return released, err
+}
+
+func (s watchedStore) AddBlackout(blackout Blackout) error {
+	err := s.BookingStore.AddBlackout(blackout)
+	if err == nil {
+		s.watchers.notify(blackout.From, blackout.To)
+	}
+	return err
+}
+
+func (s watchedStore) RemoveBlackout(id string) error {
+	err := s.BookingStore.RemoveBlackout(id)
+	if err == nil {
+		s.watchers.notify(time.Time{}, time.Time{})
+	}
+	return err
+}
+
+// Streams the number of rooms matching the filter available for every date
+// from one date to another (both included) until the context is done (read/non-blocking)
+// The availability of every date is sent first, then the dates whose availability changed
+// Changes made before an update is read are sent together
+// Returns an error if the range is reversed or too long
+func (r roomsService) WatchAvailability(ctx context.Context, from, to time.Time, filter RoomFilter) (<-chan []DayAvailability, error) {
+	watcher := r.watchers.add(from, to)
+	current, err := r.Availability(ctx, from, to, filter)
+	if err != nil {
+		r.watchers.remove(watcher)
+		return nil, err
+	}
+
+	updates := make(chan []DayAvailability, 1)
+	updates <- current
+	go func() {
+		defer close(updates)
+		defer r.watchers.remove(watcher)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-watcher.changed:
+			}
+			days, err := r.Availability(ctx, from, to, filter)
+			if err != nil {
+				return
+			}
+			var changed []DayAvailability
+			for i, day := range days {
+				if day.Available != current[i].Available {
+					changed = append(changed, day)
+				}
+			}
+			current = days
+			if len(changed) == 0 {
+				continue
+			}
+			select {
+			case updates <- changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
@@ -0,0 +1,193 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"go-booking-service/pb"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"gotest.tools/assert"
+)
+
+var watchTestDate = time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)
+
+// Returns the next update, failing if none is sent in time
+func nextUpdate(t *testing.T, updates <-chan []DayAvailability) []DayAvailability {
+	t.Helper()
+	select {
+	case update, ok := <-updates:
+		assert.Assert(t, ok, "watch ended")
+		return update
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update sent")
+		return nil
+	}
+}
+
+// Fails unless the watch ends in time
+func watchEnded(t *testing.T, updates <-chan []DayAvailability) {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("watch did not end")
+		}
+	}
+}
+
+func TestAvailabilityWatchers(t *testing.T) {
+	t.Log("AvailabilityWatchers")
+	watchers := newAvailabilityWatchers()
+	watcher := watchers.add(watchTestDate, watchTestDate.AddDate(0, 0, 2))
+	woken := func() bool {
+		select {
+		case <-watcher.changed:
+			return true
+		default:
+			return false
+		}
+	}
+
+	t.Logf("should not wake the watchers of other dates")
+	watchers.notify(watchTestDate.AddDate(0, 0, -2), watchTestDate)
+	watchers.notify(watchTestDate.AddDate(0, 0, 3), watchTestDate.AddDate(0, 0, 4))
+	assert.Assert(t, !woken())
+
+	t.Logf("should wake the watchers once for any number of changes to their dates")
+	watchers.notify(watchTestDate.AddDate(0, 0, -1), watchTestDate.AddDate(0, 0, 1))
+	watchers.notify(watchTestDate.AddDate(0, 0, 2), watchTestDate.AddDate(0, 0, 3))
+	assert.Assert(t, woken())
+	assert.Assert(t, !woken())
+
+	t.Logf("should wake every watcher if no dates are given")
+	watchers.notify(time.Time{}, time.Time{})
+	assert.Assert(t, woken())
+
+	t.Logf("should not wake removed watchers")
+	watchers.remove(watcher)
+	watchers.notify(time.Time{}, time.Time{})
+	assert.Assert(t, !woken())
+}
+
+func TestServiceWatchAvailability(t *testing.T) {
+	t.Log("ServiceWatchAvailability")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rs := NewRoomsServer(NewMemoryStore(testRooms(2)), validatorUser{}, WithAdmins("Admin"))
+	day := func(days, available int) DayAvailability {
+		return DayAvailability{watchTestDate.AddDate(0, 0, days), available}
+	}
+
+	t.Logf("should return an error if the range is invalid")
+	_, err := rs.WatchAvailability(ctx, watchTestDate, watchTestDate.AddDate(0, 0, -1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+
+	t.Logf("should send the availability of every date first")
+	updates, err := rs.WatchAvailability(ctx, watchTestDate, watchTestDate.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{day(0, 2), day(1, 2), day(2, 2)})
+
+	t.Logf("should send the dates changed by bookings")
+	booked, err := rs.Book(ctx, "John", "", 1, watchTestDate, watchTestDate.AddDate(0, 0, 2), RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{day(0, 1), day(1, 1)})
+
+	t.Logf("should send the dates changed by cancellations but not by other dates")
+	_, err = rs.Book(ctx, "John", "", 1, watchTestDate.AddDate(0, 0, 5), watchTestDate.AddDate(0, 0, 6), RoomFilter{})
+	assert.NilError(t, err)
+	assert.NilError(t, rs.Cancel(ctx, "John", booked[0].Id, 0))
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{day(0, 2), day(1, 2)})
+
+	t.Logf("should send the dates changed by blackouts")
+	_, _, err = rs.AddBlackout(ctx, "Admin", Blackout{From: watchTestDate.AddDate(0, 0, 2), To: watchTestDate.AddDate(0, 0, 3)})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{day(2, 0)})
+
+	t.Logf("should end the watch when the context is done")
+	cancel()
+	watchEnded(t, updates)
+}
+
+type testAvailabilityStream struct {
+	replies []*pb.AvailabilityResponse
+}
+
+func (s *testAvailabilityStream) Recv() (*pb.AvailabilityResponse, error) {
+	if len(s.replies) == 0 {
+		return nil, errors.New("EOF")
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply, nil
+}
+
+func TestDecodeGRPCAvailabilityStream(t *testing.T) {
+	t.Log("decodeGRPCAvailabilityStream")
+	ctx := context.Background()
+	pbDay := &pb.DayAvailability{Date: watchTestDate.Unix(), Available: 2}
+
+	t.Logf("should forward every update until the stream ends")
+	resp, err := decodeGRPCAvailabilityStream(ctx, &testAvailabilityStream{[]*pb.AvailabilityResponse{
+		{Days: []*pb.DayAvailability{pbDay}},
+		{Days: []*pb.DayAvailability{{Date: watchTestDate.Unix(), Available: 1}}},
+	}})
+	assert.NilError(t, err)
+	updates := resp.(*WatchAvailabilityResponse).Updates
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{{watchTestDate, 2}})
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{{watchTestDate, 1}})
+	watchEnded(t, updates)
+
+	t.Logf("should return the error of the first message")
+	resp, err = decodeGRPCAvailabilityStream(ctx, &testAvailabilityStream{[]*pb.AvailabilityResponse{{Error: InvalidDateRange}}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resp, &WatchAvailabilityResponse{Err: ErrInvalidDateRange()})
+
+	t.Logf("should return an error if the stream fails")
+	resp, err = decodeGRPCAvailabilityStream(ctx, &testAvailabilityStream{})
+	assert.Error(t, err, "EOF")
+	assert.DeepEqual(t, resp, &WatchAvailabilityResponse{})
+}
+
+func TestGRPCWatchAvailability(t *testing.T) {
+	t.Log("GRPCWatchAvailability")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	rs := NewRoomsServer(NewMemoryStore(testRooms(1)), validatorUser{})
+	pb.RegisterRoomsServer(server, NewGRPCServer(MakeEndpoints(rs)))
+	go server.Serve(listener)
+	defer server.Stop()
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	assert.NilError(t, err)
+	defer conn.Close()
+	client := NewGRPCClient(conn)
+
+	t.Logf("should stream the availability and its changes")
+	updates, err := client.WatchAvailability(ctx, watchTestDate, watchTestDate, RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{{watchTestDate, 1}})
+	_, err = client.Book(ctx, "John", "", 1, watchTestDate, watchTestDate.AddDate(0, 0, 1), RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, nextUpdate(t, updates), []DayAvailability{{watchTestDate, 0}})
+
+	t.Logf("should return the error of the service")
+	_, err = client.WatchAvailability(ctx, watchTestDate, watchTestDate.AddDate(0, 0, -1), RoomFilter{})
+	assert.DeepEqual(t, err, ErrInvalidDateRange())
+
+	t.Logf("should end the watch when the context is done")
+	cancel()
+	watchEnded(t, updates)
+}
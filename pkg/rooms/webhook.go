@@ -0,0 +1,389 @@
+package rooms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Headers of the requests delivering an event to a webhook
+const (
+	WebhookEventHeader     = "X-Booking-Event"
+	WebhookDeliveryHeader  = "X-Booking-Delivery"
+	WebhookSignatureHeader = "X-Booking-Signature"
+)
+
+// Events queued for a webhook while the previous ones are delivered,
+// later events are dead-lettered until the queue has room again
+const webhookQueueSize = 256
+
+// Most dead letters kept, the oldest are dropped first
+const maxDeadLetters = 1000
+
+// Webhook is an endpoint the booking events are posted to
+type Webhook struct {
+	Id  string `json:"id"`
+	Url string `json:"url"`
+	// Key the payloads are signed with, only returned when the webhook is added
+	Secret string `json:"secret,omitempty"`
+	// Types of the events posted, every type if empty
+	Events []string `json:"events"`
+}
+
+// Returns whether the webhook is subscribed to a type of event
+func (w Webhook) Wants(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, wanted := range w.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the body posted to a webhook
+type WebhookPayload struct {
+	// Id of the delivery, the same for every attempt
+	Id      string `json:"id"`
+	Webhook string `json:"webhook"`
+	Event   Event  `json:"event"`
+}
+
+// DeadLetter is an event that could not be delivered to a webhook
+type DeadLetter struct {
+	Payload  WebhookPayload `json:"payload"`
+	Url      string         `json:"url"`
+	Attempts int            `json:"attempts"`
+	Error    string         `json:"error"`
+	Failed   time.Time      `json:"failed"`
+}
+
+// SignWebhook returns the signature of a payload sent in the signature header,
+// the hex encoded HMAC-SHA256 of the body keyed with the secret of the webhook
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook returns whether a payload was signed with the secret of the webhook
+func VerifyWebhook(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, body)), []byte(signature))
+}
+
+// WebhookDispatcher posts the booking events to the webhooks subscribed to them
+// Webhooks and dead letters are kept by the store of the service the dispatcher
+// is given to, so they last as long as its bookings, and in memory until then
+// Events are delivered to each webhook in order, one at a time
+// A failed delivery is retried with exponential backoff and is dead-lettered
+// once every attempt failed
+// Events not delivered yet are lost when the process exits
+type WebhookDispatcher struct {
+	ctx     context.Context
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	store   BookingStore
+	// The webhooks being delivered to, by id
+	webhooks map[string]*subscription
+	now      func() time.Time
+	mux      sync.Mutex
+}
+
+type subscription struct {
+	webhook Webhook
+	queue   chan WebhookPayload
+	removed chan struct{}
+}
+
+// NewWebhookDispatcher returns a dispatcher delivering events until the context is done
+// A delivery is retried up to retries times, waiting backoff before the first retry
+// and twice as long before each of the next ones
+func NewWebhookDispatcher(ctx context.Context, client *http.Client, retries int, backoff time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		ctx:      ctx,
+		client:   client,
+		retries:  retries,
+		backoff:  backoff,
+		store:    NewMemoryStore(nil),
+		webhooks: map[string]*subscription{},
+		now:      time.Now,
+	}
+}
+
+// Adds a webhook with a new id, and a new secret if it has none
+// Returns an error if the url is not absolute http(s) or an event type is unknown
+func (d *WebhookDispatcher) Add(webhook Webhook) (Webhook, error) {
+	if err := validateWebhook(webhook); err != nil {
+		return Webhook{}, err
+	}
+	webhook.Id = newReservationID()
+	if webhook.Secret == "" {
+		webhook.Secret = newReservationID()
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	if err := d.store.AddWebhook(webhook); err != nil {
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// Returns every webhook, without its secret, ordered by url
+func (d *WebhookDispatcher) Webhooks() ([]Webhook, error) {
+	webhooks, err := d.store.Webhooks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// Removes a webhook, dropping the events not delivered to it yet
+// Returns an error if the webhook does not exist
+func (d *WebhookDispatcher) Remove(id string) error {
+	if err := d.store.RemoveWebhook(id); err != nil {
+		return err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if sub, ok := d.webhooks[id]; ok {
+		delete(d.webhooks, id)
+		close(sub.removed)
+	}
+	return nil
+}
+
+// Returns the events that could not be delivered, oldest first
+func (d *WebhookDispatcher) DeadLetters() ([]DeadLetter, error) {
+	return d.store.DeadLetters()
+}
+
+// Queues an event for every webhook subscribed to its type without waiting for the deliveries
+// The webhooks are read from the store, as they may have been added or removed
+// by another node of the cluster or restored from a snapshot
+func (d *WebhookDispatcher) Publish(event Event) {
+	webhooks, err := d.store.Webhooks()
+	if err != nil {
+		return
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.subscribe(webhooks)
+	for _, sub := range d.webhooks {
+		if !sub.webhook.Wants(event.Type) {
+			continue
+		}
+		payload := WebhookPayload{Id: newReservationID(), Webhook: sub.webhook.Id, Event: event}
+		select {
+		case sub.queue <- payload:
+		default:
+			// Publish is called while the change is stored, which the dead letter would wait for
+			go d.deadLetter(sub.webhook, payload, 0, fmt.Errorf("delivery queue is full"))
+		}
+	}
+}
+
+// Starts delivering to the webhooks not delivered to yet and stops delivering to
+// the ones missing, which were removed
+// Requires the lock
+func (d *WebhookDispatcher) subscribe(webhooks []Webhook) {
+	current := map[string]bool{}
+	for _, webhook := range webhooks {
+		current[webhook.Id] = true
+		if _, ok := d.webhooks[webhook.Id]; ok {
+			continue
+		}
+		sub := &subscription{
+			webhook: webhook,
+			queue:   make(chan WebhookPayload, webhookQueueSize),
+			removed: make(chan struct{}),
+		}
+		d.webhooks[webhook.Id] = sub
+		go d.deliver(sub)
+	}
+	for id, sub := range d.webhooks {
+		if !current[id] {
+			delete(d.webhooks, id)
+			close(sub.removed)
+		}
+	}
+}
+
+// Delivers the events queued for a webhook until it is removed or the context is done
+func (d *WebhookDispatcher) deliver(sub *subscription) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-sub.removed:
+			return
+		case payload := <-sub.queue:
+			attempts, err := d.send(sub, payload)
+			if err == nil {
+				continue
+			}
+			// Events of removed webhooks are dropped
+			select {
+			case <-sub.removed:
+			default:
+				d.deadLetter(sub.webhook, payload, attempts, err)
+			}
+		}
+	}
+}
+
+// Posts a payload until it is accepted or every attempt failed
+// Returns the number of attempts and the error of the last one
+func (d *WebhookDispatcher) send(sub *subscription, payload WebhookPayload) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		err = d.post(sub.webhook, payload, body)
+		if err == nil || attempt > d.retries {
+			return attempt, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			return attempt, d.ctx.Err()
+		case <-sub.removed:
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (d *WebhookDispatcher) post(webhook Webhook, payload WebhookPayload, body []byte) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, payload.Event.Type)
+	req.Header.Set(WebhookDeliveryHeader, payload.Id)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// A dead letter is lost if the store fails, as on a node no longer leading the cluster
+func (d *WebhookDispatcher) deadLetter(webhook Webhook, payload WebhookPayload, attempts int, err error) {
+	d.store.AddDeadLetter(DeadLetter{
+		Payload:  payload,
+		Url:      webhook.Url,
+		Attempts: attempts,
+		Error:    err.Error(),
+		Failed:   d.now().UTC().Truncate(time.Second),
+	})
+}
+
+func sortWebhooks(webhooks []Webhook) {
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].Url != webhooks[j].Url {
+			return webhooks[i].Url < webhooks[j].Url
+		}
+		return webhooks[i].Id < webhooks[j].Id
+	})
+}
+
+func validateWebhook(webhook Webhook) error {
+	target, err := url.Parse(webhook.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrInvalidWebhook()
+	}
+	for _, eventType := range webhook.Events {
+		switch eventType {
+		case EventBooked, EventHeld, EventConfirmed, EventCancelled, EventMoved, EventExpired:
+		default:
+			return ErrInvalidWebhook()
+		}
+	}
+	return nil
+}
+
+// WithWebhooks posts every change to the reservations of the store
+// to the webhooks of the dispatcher, which administrators manage
+// The webhooks and dead letters are kept in the store, and journaled if a journal is kept
+func WithWebhooks(webhooks *WebhookDispatcher) Option {
+	return func(r *roomsService) {
+		r.webhooks = webhooks
+		r.journaled().webhooks = webhooks
+		webhooks.store = r.journaled()
+	}
+}
+
+// Subscribes a webhook to booking events (write/blocking)
+// Returns the webhook with its id and secret
+// Returns an error if the token does not belong to an administrator,
+// the webhook is invalid or webhooks are not enabled
+func (r roomsService) AddWebhook(ctx context.Context, token string, webhook Webhook) (Webhook, error) {
+	if err := r.authorizeWebhooks(ctx, token); err != nil {
+		return Webhook{}, err
+	}
+	return r.webhooks.Add(webhook)
+}
+
+// Returns every webhook without its secret (read/non-blocking)
+// Returns an error if the token does not belong to an administrator
+// or webhooks are not enabled
+func (r roomsService) ListWebhooks(ctx context.Context, token string) ([]Webhook, error) {
+	if err := r.authorizeWebhooks(ctx, token); err != nil {
+		return nil, err
+	}
+	return r.webhooks.Webhooks()
+}
+
+// Unsubscribes a webhook (write/blocking)
+// Returns an error if the token does not belong to an administrator,
+// the webhook does not exist or webhooks are not enabled
+func (r roomsService) RemoveWebhook(ctx context.Context, token, id string) error {
+	if err := r.authorizeWebhooks(ctx, token); err != nil {
+		return err
+	}
+	return r.webhooks.Remove(id)
+}
+
+// Returns the events that could not be delivered to a webhook, oldest first (read/non-blocking)
+// Returns an error if the token does not belong to an administrator
+// or webhooks are not enabled
+func (r roomsService) DeadLetters(ctx context.Context, token string) ([]DeadLetter, error) {
+	if err := r.authorizeWebhooks(ctx, token); err != nil {
+		return nil, err
+	}
+	return r.webhooks.DeadLetters()
+}
+
+func (r roomsService) authorizeWebhooks(ctx context.Context, token string) error {
+	if r.webhooks == nil {
+		return ErrWebhooksNotEnabled()
+	}
+	return r.authorizeAdmin(ctx, token)
+}
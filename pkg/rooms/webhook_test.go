@@ -0,0 +1,369 @@
+package rooms
+
+import (
+	"context"
+	"encoding/json"
+	"go-booking-service/pb"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var testWebhook = Webhook{
+	Id:     "W3BH00K",
+	Url:    "http://housekeeping.local/bookings",
+	Secret: "s3cr3t",
+	Events: []string{EventBooked, EventCancelled},
+}
+
+var testPBWebhook = &pb.Webhook{
+	Id:     "W3BH00K",
+	Url:    "http://housekeeping.local/bookings",
+	Secret: "s3cr3t",
+	Events: []string{EventBooked, EventCancelled},
+}
+
+var testDeadLetter = DeadLetter{
+	Payload:  WebhookPayload{Id: "D3L1V3RY", Webhook: "W3BH00K", Event: testEvent},
+	Url:      "http://housekeeping.local/bookings",
+	Attempts: 3,
+	Error:    "webhook responded 500 Internal Server Error",
+	Failed:   time.Date(2020, 6, 12, 21, 31, 0, 0, time.UTC),
+}
+
+var testPBDeadLetter = &pb.DeadLetter{
+	Id:       "D3L1V3RY",
+	Webhook:  "W3BH00K",
+	Event:    testPBEvent,
+	Url:      "http://housekeeping.local/bookings",
+	Attempts: 3,
+	Error:    "webhook responded 500 Internal Server Error",
+	Failed:   time.Date(2020, 6, 12, 21, 31, 0, 0, time.UTC).Unix(),
+}
+
+// webhookRequest is a request received by a test webhook
+type webhookRequest struct {
+	header   http.Header
+	body     []byte
+	received time.Time
+}
+
+// Starts a webhook responding with the statuses in order, then with 200
+func newWebhookReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan webhookRequest) {
+	requests := make(chan webhookRequest, 100)
+	var mux sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		requests <- webhookRequest{header: r.Header, body: body, received: time.Now()}
+		mux.Lock()
+		defer mux.Unlock()
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	return server, requests
+}
+
+func receiveWebhook(t *testing.T, requests <-chan webhookRequest) (webhookRequest, WebhookPayload) {
+	t.Helper()
+	select {
+	case request := <-requests:
+		var payload WebhookPayload
+		assert.NilError(t, json.Unmarshal(request.body, &payload))
+		return request, payload
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook was not called")
+		return webhookRequest{}, WebhookPayload{}
+	}
+}
+
+func listWebhooks(t *testing.T, webhooks *WebhookDispatcher) []Webhook {
+	t.Helper()
+	listed, err := webhooks.Webhooks()
+	assert.NilError(t, err)
+	return listed
+}
+
+func listDeadLetters(t *testing.T, webhooks *WebhookDispatcher) []DeadLetter {
+	t.Helper()
+	deadLetters, err := webhooks.DeadLetters()
+	assert.NilError(t, err)
+	return deadLetters
+}
+
+func waitDeadLetters(t *testing.T, webhooks *WebhookDispatcher, count int) []DeadLetter {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deadLetters := listDeadLetters(t, webhooks)
+		if len(deadLetters) >= count {
+			return deadLetters
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d dead letters, expected %d", len(deadLetters), count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	t.Log("WebhookSignature")
+	body := []byte(`{"id":"D3L1V3RY"}`)
+	signature := SignWebhook("s3cr3t", body)
+
+	t.Logf("should sign the body with HMAC-SHA256")
+	assert.Equal(t, signature, "sha256=2e78b6e84daa33479942319fb51c8aa16a7f9a66ed6c07953a301f492c44e6aa")
+
+	t.Logf("should verify a body signed with the secret")
+	assert.Assert(t, VerifyWebhook("s3cr3t", body, signature))
+
+	t.Logf("should not verify a body signed with another secret")
+	assert.Assert(t, !VerifyWebhook("other", body, signature))
+
+	t.Logf("should not verify a changed body")
+	assert.Assert(t, !VerifyWebhook("s3cr3t", []byte(`{"id":"0TH3R"}`), signature))
+}
+
+var webhookDispatcherAddTest = []struct {
+	name    string
+	webhook Webhook
+	err     error
+}{
+	{
+		name:    "should add a webhook for every event",
+		webhook: Webhook{Url: "https://billing.local/events"},
+	},
+	{
+		name:    "should add a webhook for some events",
+		webhook: Webhook{Url: "http://housekeeping.local/bookings", Events: []string{EventBooked, EventMoved}},
+	},
+	{
+		name:    "should return an error if the url is not absolute",
+		webhook: Webhook{Url: "/bookings"},
+		err:     ErrInvalidWebhook(),
+	},
+	{
+		name:    "should return an error if the url is not http",
+		webhook: Webhook{Url: "ftp://housekeeping.local/bookings"},
+		err:     ErrInvalidWebhook(),
+	},
+	{
+		name:    "should return an error if an event type is unknown",
+		webhook: Webhook{Url: "http://housekeeping.local/bookings", Events: []string{"paid"}},
+		err:     ErrInvalidWebhook(),
+	},
+}
+
+func TestWebhookDispatcherAdd(t *testing.T) {
+	t.Log("WebhookDispatcherAdd")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, testcase := range webhookDispatcherAddTest {
+		t.Logf(testcase.name)
+
+		webhooks := NewWebhookDispatcher(ctx, http.DefaultClient, 0, 0)
+		added, err := webhooks.Add(testcase.webhook)
+
+		assert.DeepEqual(t, err, testcase.err)
+		if err != nil {
+			assert.DeepEqual(t, listWebhooks(t, webhooks), []Webhook{})
+			continue
+		}
+		assert.Assert(t, added.Id != "")
+		assert.Assert(t, added.Secret != "")
+		listed := added
+		listed.Secret = ""
+		assert.DeepEqual(t, listWebhooks(t, webhooks), []Webhook{listed})
+	}
+}
+
+func TestWebhookDispatcherRemove(t *testing.T) {
+	t.Log("WebhookDispatcherRemove")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks := NewWebhookDispatcher(ctx, http.DefaultClient, 0, 0)
+	added, err := webhooks.Add(Webhook{Url: "http://housekeeping.local/bookings"})
+	assert.NilError(t, err)
+
+	t.Logf("should remove a webhook")
+	assert.NilError(t, webhooks.Remove(added.Id))
+	assert.DeepEqual(t, listWebhooks(t, webhooks), []Webhook{})
+
+	t.Logf("should return an error if the webhook does not exist")
+	assert.DeepEqual(t, webhooks.Remove(added.Id), ErrWebhookNotFound())
+}
+
+func TestWebhookDispatcherDeliver(t *testing.T) {
+	t.Log("WebhookDispatcherDeliver")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver, requests := newWebhookReceiver(t)
+	defer receiver.Close()
+	webhooks := NewWebhookDispatcher(ctx, receiver.Client(), 3, time.Millisecond)
+	webhook, err := webhooks.Add(Webhook{Url: receiver.URL, Events: []string{EventBooked, EventCancelled}})
+	assert.NilError(t, err)
+
+	held := testEvent
+	held.Type = EventHeld
+	cancelled := testEvent
+	cancelled.Seq = 2
+	cancelled.Type = EventCancelled
+	webhooks.Publish(held)
+	webhooks.Publish(testEvent)
+	webhooks.Publish(cancelled)
+
+	t.Logf("should post the events subscribed to in order")
+	request, payload := receiveWebhook(t, requests)
+	assert.DeepEqual(t, payload.Event, testEvent)
+	assert.Equal(t, payload.Webhook, webhook.Id)
+	assert.Equal(t, request.header.Get("Content-Type"), "application/json")
+	assert.Equal(t, request.header.Get(WebhookEventHeader), EventBooked)
+	assert.Equal(t, request.header.Get(WebhookDeliveryHeader), payload.Id)
+	_, payload = receiveWebhook(t, requests)
+	assert.DeepEqual(t, payload.Event, cancelled)
+
+	t.Logf("should sign the payloads with the secret of the webhook")
+	assert.Assert(t, VerifyWebhook(webhook.Secret, request.body, request.header.Get(WebhookSignatureHeader)))
+	assert.DeepEqual(t, listDeadLetters(t, webhooks), []DeadLetter{})
+}
+
+func TestWebhookDispatcherRetry(t *testing.T) {
+	t.Log("WebhookDispatcherRetry")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver, requests := newWebhookReceiver(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+	defer receiver.Close()
+	backoff := 20 * time.Millisecond
+	webhooks := NewWebhookDispatcher(ctx, receiver.Client(), 2, backoff)
+	_, err := webhooks.Add(Webhook{Url: receiver.URL})
+	assert.NilError(t, err)
+	webhooks.Publish(testEvent)
+
+	t.Logf("should retry a failed delivery with the same id")
+	first, payload := receiveWebhook(t, requests)
+	second, retried := receiveWebhook(t, requests)
+	third, delivered := receiveWebhook(t, requests)
+	assert.Equal(t, retried.Id, payload.Id)
+	assert.Equal(t, delivered.Id, payload.Id)
+
+	t.Logf("should double the wait before each retry")
+	assert.Assert(t, second.received.Sub(first.received) >= backoff)
+	assert.Assert(t, third.received.Sub(second.received) >= 2*backoff)
+
+	t.Logf("should not dead-letter a delivery that succeeded")
+	assert.DeepEqual(t, listDeadLetters(t, webhooks), []DeadLetter{})
+}
+
+func TestWebhookDispatcherDeadLetters(t *testing.T) {
+	t.Log("WebhookDispatcherDeadLetters")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver, requests := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer receiver.Close()
+	webhooks := NewWebhookDispatcher(ctx, receiver.Client(), 2, time.Millisecond)
+	webhook, err := webhooks.Add(Webhook{Url: receiver.URL})
+	assert.NilError(t, err)
+	webhooks.Publish(testEvent)
+
+	t.Logf("should dead-letter a delivery once every attempt failed")
+	deadLetters := waitDeadLetters(t, webhooks, 1)
+	var payload WebhookPayload
+	for attempt := 1; attempt <= 3; attempt++ {
+		_, payload = receiveWebhook(t, requests)
+	}
+	assert.DeepEqual(t, deadLetters[0].Payload, payload)
+	assert.Equal(t, deadLetters[0].Payload.Webhook, webhook.Id)
+	assert.Equal(t, deadLetters[0].Url, receiver.URL)
+	assert.Equal(t, deadLetters[0].Attempts, 3)
+	assert.Assert(t, strings.Contains(deadLetters[0].Error, "500"))
+
+	t.Logf("should deliver the next events")
+	webhooks.Publish(testEvent)
+	_, payload = receiveWebhook(t, requests)
+	assert.Assert(t, payload.Id != deadLetters[0].Payload.Id)
+	assert.Equal(t, len(listDeadLetters(t, webhooks)), 1)
+}
+
+func TestServiceWebhooks(t *testing.T) {
+	t.Log("ServiceWebhooks")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	from := time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 16, 0, 0, 0, 0, time.UTC)
+	receiver, requests := newWebhookReceiver(t)
+	defer receiver.Close()
+	webhooks := NewWebhookDispatcher(ctx, receiver.Client(), 0, 0)
+	journal := NewMemoryJournal()
+	rs := NewRoomsServer(newReservationsTestStore(), validatorUser{},
+		WithAdmins("Admin"), WithJournal(journal), WithWebhooks(webhooks))
+
+	t.Logf("should return an error if the user is not an administrator")
+	_, err := rs.AddWebhook(ctx, "John", Webhook{Url: receiver.URL})
+	assert.DeepEqual(t, err, ErrNotAdmin())
+	_, err = rs.ListWebhooks(ctx, "John")
+	assert.DeepEqual(t, err, ErrNotAdmin())
+	_, err = rs.DeadLetters(ctx, "John")
+	assert.DeepEqual(t, err, ErrNotAdmin())
+
+	t.Logf("should add a webhook")
+	webhook, err := rs.AddWebhook(ctx, "Admin", Webhook{Url: receiver.URL, Events: []string{EventBooked, EventCancelled}})
+	assert.NilError(t, err)
+	listed, err := rs.ListWebhooks(ctx, "Admin")
+	assert.NilError(t, err)
+	assert.Equal(t, len(listed), 1)
+	assert.Equal(t, listed[0].Id, webhook.Id)
+
+	t.Logf("should post the bookings with their position in the journal")
+	booked, err := rs.Book(ctx, "John", "", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+	request, payload := receiveWebhook(t, requests)
+	assert.Assert(t, VerifyWebhook(webhook.Secret, request.body, request.header.Get(WebhookSignatureHeader)))
+	assert.Equal(t, payload.Event.Seq, uint64(2))
+	assert.Equal(t, payload.Event.Type, EventBooked)
+	assert.DeepEqual(t, payload.Event.Reservation, booked[0])
+
+	t.Logf("should post the cancellations")
+	assert.NilError(t, rs.Cancel(ctx, "John", booked[0].Id, 0))
+	_, payload = receiveWebhook(t, requests)
+	assert.Equal(t, payload.Event.Type, EventCancelled)
+	assert.Equal(t, payload.Event.Reservation.Id, booked[0].Id)
+
+	t.Logf("should keep the webhooks in the store and the journal")
+	restarted := newReservationsTestStore()
+	assert.NilError(t, Replay(journal, restarted))
+	replayed, err := restarted.Webhooks()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, replayed, []Webhook{webhook})
+
+	t.Logf("should post to the webhooks of the store")
+	restartedRs := NewRoomsServer(restarted, validatorUser{},
+		WithAdmins("Admin"), WithWebhooks(NewWebhookDispatcher(ctx, receiver.Client(), 0, 0)))
+	rebooked, err := restartedRs.Book(ctx, "John", "", 1, from, to, RoomFilter{})
+	assert.NilError(t, err)
+	request, payload = receiveWebhook(t, requests)
+	assert.Assert(t, VerifyWebhook(webhook.Secret, request.body, request.header.Get(WebhookSignatureHeader)))
+	assert.DeepEqual(t, payload.Event.Reservation, rebooked[0])
+
+	t.Logf("should remove a webhook")
+	assert.NilError(t, rs.RemoveWebhook(ctx, "Admin", webhook.Id))
+	assert.DeepEqual(t, rs.RemoveWebhook(ctx, "Admin", webhook.Id), ErrWebhookNotFound())
+	deadLetters, err := rs.DeadLetters(ctx, "Admin")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, deadLetters, []DeadLetter{})
+
+	t.Logf("should return an error if webhooks are not enabled")
+	rs = NewRoomsServer(newReservationsTestStore(), validatorUser{}, WithAdmins("Admin"))
+	_, err = rs.AddWebhook(ctx, "Admin", Webhook{Url: receiver.URL})
+	assert.DeepEqual(t, err, ErrWebhooksNotEnabled())
+}
@@ -11,6 +11,32 @@ import (
 	"gotest.tools/assert"
 )
 
+var testReservation = rooms.Reservation{
+	Id:      "6b1f0f0ee8d5e0b4b0a7f3c2a9d8c1e2",
+	Code:    "K7QX9M",
+	User:    "John",
+	Room:    1,
+	From:    time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:      time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+	Version: 1,
+}
+
+var testWaitlistEntry = rooms.WaitlistEntry{
+	Id:       "0c8e2f6a4b1d9e7f3a5c2b8d6e4f1a9c",
+	User:     "John",
+	From:     time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:       time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+	Position: 1,
+}
+
+var testBlackout = rooms.Blackout{
+	Id:     "B4CK0T",
+	Room:   1,
+	From:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+	To:     time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+	Reason: "painting",
+}
+
 type mockCorrectEndpoint struct{}
 
 func (m mockCorrectEndpoint) Authorize(ctx context.Context, user, password string) (string, error) {
@@ -21,14 +47,93 @@ func (m mockCorrectEndpoint) Validate(ctx context.Context, token string) (string
 	return "Jhon", nil
 }
 
-func (m mockCorrectEndpoint) Book(ctx context.Context, token string, date time.Time) (int, error) {
-	return 1, nil
+func (m mockCorrectEndpoint) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter rooms.RoomFilter) ([]rooms.Reservation, error) {
+	return []rooms.Reservation{testReservation}, nil
 }
 
-func (m mockCorrectEndpoint) Check(ctx context.Context, date time.Time) (int, error) {
+func (m mockCorrectEndpoint) Check(ctx context.Context, date time.Time, filter rooms.RoomFilter) (int, error) {
 	return 5, nil
 }
 
+func (m mockCorrectEndpoint) Cancel(ctx context.Context, token, id string, version int) error {
+	return nil
+}
+
+func (m mockCorrectEndpoint) Availability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) ([]rooms.DayAvailability, error) {
+	return []rooms.DayAvailability{{Date: from, Available: 5}}, nil
+}
+
+func (m mockCorrectEndpoint) WatchAvailability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) (<-chan []rooms.DayAvailability, error) {
+	updates := make(chan []rooms.DayAvailability, 1)
+	updates <- []rooms.DayAvailability{{Date: from, Available: 5}}
+	close(updates)
+	return updates, nil
+}
+
+func (m mockCorrectEndpoint) ListRooms(ctx context.Context) ([]rooms.RoomInfo, error) {
+	return []rooms.RoomInfo{{Id: 1, Name: "101"}}, nil
+}
+
+func (m mockCorrectEndpoint) CreateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return room, nil
+}
+
+func (m mockCorrectEndpoint) UpdateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return room, nil
+}
+
+func (m mockCorrectEndpoint) DecommissionRoom(ctx context.Context, token string, id int) error {
+	return nil
+}
+
+func (m mockCorrectEndpoint) ListBookings(ctx context.Context, token string, from, to time.Time) ([]rooms.Booking, error) {
+	return []rooms.Booking{
+		{Id: "r1", Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)},
+		{Id: "r1", Room: 1, Date: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+		{Id: "r2", Room: 2, Date: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+	}, nil
+}
+
+func (m mockCorrectEndpoint) GetReservation(ctx context.Context, token, id string) (rooms.Reservation, error) {
+	return testReservation, nil
+}
+
+func (m mockCorrectEndpoint) AddBlackout(ctx context.Context, token string, blackout rooms.Blackout) (rooms.Blackout, []rooms.Reservation, error) {
+	return testBlackout, []rooms.Reservation{testReservation}, nil
+}
+
+func (m mockCorrectEndpoint) ListBlackouts(ctx context.Context, token string) ([]rooms.Blackout, error) {
+	return []rooms.Blackout{testBlackout}, nil
+}
+
+func (m mockCorrectEndpoint) RemoveBlackout(ctx context.Context, token, id string) error {
+	return nil
+}
+
+func (m mockCorrectEndpoint) History(ctx context.Context, token, id string, room int) ([]rooms.Event, error) {
+	return []rooms.Event{{Seq: 1, Type: rooms.EventBooked, Reservation: testReservation}}, nil
+}
+
+func (m mockCorrectEndpoint) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (rooms.Reservation, error) {
+	return testReservation, nil
+}
+
+func (m mockCorrectEndpoint) Hold(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.Reservation, error) {
+	return testReservation, nil
+}
+
+func (m mockCorrectEndpoint) Confirm(ctx context.Context, token, id string, version int) (rooms.Reservation, error) {
+	return testReservation, nil
+}
+
+func (m mockCorrectEndpoint) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.WaitlistEntry, error) {
+	return testWaitlistEntry, nil
+}
+
+func (m mockCorrectEndpoint) WaitlistPosition(ctx context.Context, token, id string) (rooms.WaitlistEntry, error) {
+	return testWaitlistEntry, nil
+}
+
 type mockErrorEndpoint struct{}
 
 func (m mockErrorEndpoint) Authorize(ctx context.Context, user, password string) (string, error) {
@@ -39,14 +144,86 @@ func (m mockErrorEndpoint) Validate(ctx context.Context, token string) (string,
 	return "", clients.ErrUserNotFound()
 }
 
-func (m mockErrorEndpoint) Book(ctx context.Context, token string, date time.Time) (int, error) {
-	return 0, rooms.ErrNoRoomAvailable()
+func (m mockErrorEndpoint) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter rooms.RoomFilter) ([]rooms.Reservation, error) {
+	return nil, rooms.ErrNoRoomAvailable()
 }
 
-func (m mockErrorEndpoint) Check(ctx context.Context, date time.Time) (int, error) {
+func (m mockErrorEndpoint) Check(ctx context.Context, date time.Time, filter rooms.RoomFilter) (int, error) {
 	return 0, rooms.ErrNoRoomAvailable()
 }
 
+func (m mockErrorEndpoint) Cancel(ctx context.Context, token, id string, version int) error {
+	return rooms.ErrBookingNotFound()
+}
+
+func (m mockErrorEndpoint) Availability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) ([]rooms.DayAvailability, error) {
+	return nil, rooms.ErrInvalidDateRange()
+}
+
+func (m mockErrorEndpoint) WatchAvailability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) (<-chan []rooms.DayAvailability, error) {
+	return nil, rooms.ErrInvalidDateRange()
+}
+
+func (m mockErrorEndpoint) ListRooms(ctx context.Context) ([]rooms.RoomInfo, error) {
+	return nil, rooms.ErrNotAdmin()
+}
+
+func (m mockErrorEndpoint) CreateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return rooms.RoomInfo{}, rooms.ErrRoomExists()
+}
+
+func (m mockErrorEndpoint) UpdateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return rooms.RoomInfo{}, rooms.ErrRoomNotFound()
+}
+
+func (m mockErrorEndpoint) DecommissionRoom(ctx context.Context, token string, id int) error {
+	return rooms.ErrRoomHasBookings()
+}
+
+func (m mockErrorEndpoint) ListBookings(ctx context.Context, token string, from, to time.Time) ([]rooms.Booking, error) {
+	return nil, rooms.ErrInvalidDateRange()
+}
+
+func (m mockErrorEndpoint) GetReservation(ctx context.Context, token, id string) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrBookingNotFound()
+}
+
+func (m mockErrorEndpoint) AddBlackout(ctx context.Context, token string, blackout rooms.Blackout) (rooms.Blackout, []rooms.Reservation, error) {
+	return rooms.Blackout{}, nil, rooms.ErrRoomNotFound()
+}
+
+func (m mockErrorEndpoint) ListBlackouts(ctx context.Context, token string) ([]rooms.Blackout, error) {
+	return nil, rooms.ErrNotAdmin()
+}
+
+func (m mockErrorEndpoint) RemoveBlackout(ctx context.Context, token, id string) error {
+	return rooms.ErrBlackoutNotFound()
+}
+
+func (m mockErrorEndpoint) History(ctx context.Context, token, id string, room int) ([]rooms.Event, error) {
+	return nil, rooms.ErrHistoryNotRecorded()
+}
+
+func (m mockErrorEndpoint) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrRoomNotAvailable()
+}
+
+func (m mockErrorEndpoint) Hold(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrHoldExpired()
+}
+
+func (m mockErrorEndpoint) Confirm(ctx context.Context, token, id string, version int) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrHoldExpired()
+}
+
+func (m mockErrorEndpoint) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.WaitlistEntry, error) {
+	return rooms.WaitlistEntry{}, rooms.ErrWaitlistEntryNotFound()
+}
+
+func (m mockErrorEndpoint) WaitlistPosition(ctx context.Context, token, id string) (rooms.WaitlistEntry, error) {
+	return rooms.WaitlistEntry{}, rooms.ErrWaitlistEntryNotFound()
+}
+
 type mockInvalidEndpoint struct{}
 
 func (m mockInvalidEndpoint) Authorize(ctx context.Context, user, password string) (string, error) {
@@ -57,14 +234,86 @@ func (m mockInvalidEndpoint) Validate(ctx context.Context, token string) (string
 	return "", ErrInvalidResponseStructure()
 }
 
-func (m mockInvalidEndpoint) Book(ctx context.Context, token string, date time.Time) (int, error) {
-	return 0, rooms.ErrInvalidResponseStructure()
+func (m mockInvalidEndpoint) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter rooms.RoomFilter) ([]rooms.Reservation, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
 }
 
-func (m mockInvalidEndpoint) Check(ctx context.Context, date time.Time) (int, error) {
+func (m mockInvalidEndpoint) Check(ctx context.Context, date time.Time, filter rooms.RoomFilter) (int, error) {
 	return 0, rooms.ErrInvalidResponseStructure()
 }
 
+func (m mockInvalidEndpoint) Cancel(ctx context.Context, token, id string, version int) error {
+	return rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) Availability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) ([]rooms.DayAvailability, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) WatchAvailability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) (<-chan []rooms.DayAvailability, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) ListRooms(ctx context.Context) ([]rooms.RoomInfo, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) CreateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return rooms.RoomInfo{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) UpdateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return rooms.RoomInfo{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) DecommissionRoom(ctx context.Context, token string, id int) error {
+	return rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) ListBookings(ctx context.Context, token string, from, to time.Time) ([]rooms.Booking, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) GetReservation(ctx context.Context, token, id string) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) AddBlackout(ctx context.Context, token string, blackout rooms.Blackout) (rooms.Blackout, []rooms.Reservation, error) {
+	return rooms.Blackout{}, nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) ListBlackouts(ctx context.Context, token string) ([]rooms.Blackout, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) RemoveBlackout(ctx context.Context, token, id string) error {
+	return rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) History(ctx context.Context, token, id string, room int) ([]rooms.Event, error) {
+	return nil, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) Hold(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) Confirm(ctx context.Context, token, id string, version int) (rooms.Reservation, error) {
+	return rooms.Reservation{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.WaitlistEntry, error) {
+	return rooms.WaitlistEntry{}, rooms.ErrInvalidResponseStructure()
+}
+
+func (m mockInvalidEndpoint) WaitlistPosition(ctx context.Context, token, id string) (rooms.WaitlistEntry, error) {
+	return rooms.WaitlistEntry{}, rooms.ErrInvalidResponseStructure()
+}
+
 var endpointAuthorizeTest = []struct {
 	name              string
 	user              string
@@ -174,39 +423,41 @@ func TestEndpointValidate(t *testing.T) {
 var endpointBookTest = []struct {
 	name         string
 	token        string
-	date         time.Time
+	from         time.Time
+	to           time.Time
 	bookEndpoint endpoint.Endpoint
-	want         int
+	want         []rooms.Reservation
 	err          error
 }{
 	{
-		name:  "should return the room id",
+		name:  "should return the reservation",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		bookEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
-			return &BookResponse{1, nil}, nil
+			return &BookResponse{testReservation, []rooms.Reservation{testReservation}, nil}, nil
 		},
-		want: 1,
+		want: []rooms.Reservation{testReservation},
 	},
 	{
 		name:  "should return an error if the response has the wrong structure",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		bookEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
 			return 1, nil
 		},
-		want: 0,
-		err:  ErrInvalidResponseStructure(),
+		err: ErrInvalidResponseStructure(),
 	},
 	{
 		name:  "should return an error if the endpoint returns an error",
 		token: "jjj.www.ttt",
-		date:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		from:  time.Date(2020, 6, 13, 12, 0, 0, 0, time.UTC),
+		to:    time.Date(2020, 6, 14, 12, 0, 0, 0, time.UTC),
 		bookEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
 			return nil, rooms.ErrNoRoomAvailable()
 		},
-		want: 0,
-		err:  rooms.ErrNoRoomAvailable(),
+		err: rooms.ErrNoRoomAvailable(),
 	},
 }
 
@@ -219,9 +470,9 @@ func TestEndpointBook(t *testing.T) {
 		endpointMock := Endpoints{
 			BookEndpoint: testcase.bookEndpoint,
 		}
-		result, err := endpointMock.Book(context.Background(), testcase.token, testcase.date)
+		result, err := endpointMock.Book(context.Background(), testcase.token, "", 0, testcase.from, testcase.to, rooms.RoomFilter{})
 
-		assert.Equal(t, result, testcase.want)
+		assert.DeepEqual(t, result, testcase.want)
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
@@ -247,8 +498,7 @@ var endpointCheckTest = []struct {
 		checkEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
 			return 5, nil
 		},
-		want: 0,
-		err:  ErrInvalidResponseStructure(),
+		err: ErrInvalidResponseStructure(),
 	},
 	{
 		name: "should return an error if the endpoint returns an error",
@@ -256,8 +506,7 @@ var endpointCheckTest = []struct {
 		checkEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
 			return nil, rooms.ErrNoRoomAvailable()
 		},
-		want: 0,
-		err:  rooms.ErrNoRoomAvailable(),
+		err: rooms.ErrNoRoomAvailable(),
 	},
 }
 
@@ -270,9 +519,558 @@ func TestEndpointCheck(t *testing.T) {
 		endpointMock := Endpoints{
 			CheckEndpoint: testcase.checkEndpoint,
 		}
-		result, err := endpointMock.Check(context.Background(), testcase.date)
+		result, err := endpointMock.Check(context.Background(), testcase.date, rooms.RoomFilter{})
 
 		assert.Equal(t, result, testcase.want)
 		assert.DeepEqual(t, err, testcase.err)
 	}
 }
+
+var endpointCancelTest = []struct {
+	name           string
+	token          string
+	id             string
+	cancelEndpoint endpoint.Endpoint
+	err            error
+}{
+	{
+		name:  "should return no error if the booking was cancelled",
+		token: "jjj.www.ttt",
+		id:    "K7QX9M",
+		cancelEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &CancelResponse{nil}, nil
+		},
+	},
+	{
+		name:  "should return an error if the response has the wrong structure",
+		token: "jjj.www.ttt",
+		id:    "K7QX9M",
+		cancelEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name:  "should return an error if the endpoint returns an error",
+		token: "jjj.www.ttt",
+		id:    "K7QX9M",
+		cancelEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrNotBookingOwner()
+		},
+		err: rooms.ErrNotBookingOwner(),
+	},
+}
+
+func TestEndpointCancel(t *testing.T) {
+	t.Log("EndpointCancel")
+
+	for _, testcase := range endpointCancelTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			CancelEndpoint: testcase.cancelEndpoint,
+		}
+		err := endpointMock.Cancel(context.Background(), testcase.token, testcase.id, 0)
+
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointAvailabilityTest = []struct {
+	name                 string
+	from                 time.Time
+	to                   time.Time
+	availabilityEndpoint endpoint.Endpoint
+	want                 []rooms.DayAvailability
+	err                  error
+}{
+	{
+		name: "should return the number of available rooms for every day",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		availabilityEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &AvailabilityResponse{[]rooms.DayAvailability{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), Available: 5}}, nil}, nil
+		},
+		want: []rooms.DayAvailability{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), Available: 5}},
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		availabilityEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 5, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		from: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC),
+		to:   time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC),
+		availabilityEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrInvalidDateRange()
+		},
+		err: rooms.ErrInvalidDateRange(),
+	},
+}
+
+func TestEndpointAvailability(t *testing.T) {
+	t.Log("EndpointAvailability")
+
+	for _, testcase := range endpointAvailabilityTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			AvailabilityEndpoint: testcase.availabilityEndpoint,
+		}
+		result, err := endpointMock.Availability(context.Background(), testcase.from, testcase.to, rooms.RoomFilter{})
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointListBookingsTest = []struct {
+	name                 string
+	listBookingsEndpoint endpoint.Endpoint
+	want                 []rooms.Booking
+	total                int
+	err                  error
+}{
+	{
+		name: "should return the page of bookings and the total",
+		listBookingsEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &ListBookingsResponse{[]rooms.Booking{{Id: "r1", Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)}}, 3, nil}, nil
+		},
+		want:  []rooms.Booking{{Id: "r1", Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)}},
+		total: 3,
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		listBookingsEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 5, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		listBookingsEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrInvalidDateRange()
+		},
+		err: rooms.ErrInvalidDateRange(),
+	},
+}
+
+func TestEndpointListBookings(t *testing.T) {
+	t.Log("EndpointListBookings")
+
+	for _, testcase := range endpointListBookingsTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			ListBookingsEndpoint: testcase.listBookingsEndpoint,
+		}
+		result, total, err := endpointMock.ListBookings(context.Background(), "jjj.www.ttt", time.Time{}, time.Time{}, 0, 1)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.Equal(t, total, testcase.total)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointGetReservationTest = []struct {
+	name                string
+	id                  string
+	reservationEndpoint endpoint.Endpoint
+	want                rooms.Reservation
+	err                 error
+}{
+	{
+		name: "should return the reservation",
+		id:   "K7QX9M",
+		reservationEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &GetReservationResponse{testReservation, nil}, nil
+		},
+		want: testReservation,
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		id:   "K7QX9M",
+		reservationEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		id:   "K7QX9M",
+		reservationEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrNotBookingOwner()
+		},
+		err: rooms.ErrNotBookingOwner(),
+	},
+}
+
+func TestEndpointGetReservation(t *testing.T) {
+	t.Log("EndpointGetReservation")
+
+	for _, testcase := range endpointGetReservationTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			ReservationEndpoint: testcase.reservationEndpoint,
+		}
+		result, err := endpointMock.GetReservation(context.Background(), "jjj.www.ttt", testcase.id)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointWaitlistPositionTest = []struct {
+	name             string
+	id               string
+	waitlistEndpoint endpoint.Endpoint
+	want             rooms.WaitlistEntry
+	err              error
+}{
+	{
+		name: "should return the waitlist entry",
+		id:   testWaitlistEntry.Id,
+		waitlistEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &WaitlistResponse{testWaitlistEntry, nil}, nil
+		},
+		want: testWaitlistEntry,
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		id:   testWaitlistEntry.Id,
+		waitlistEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		id:   testWaitlistEntry.Id,
+		waitlistEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrWaitlistEntryNotFound()
+		},
+		err: rooms.ErrWaitlistEntryNotFound(),
+	},
+}
+
+func TestEndpointWaitlistPosition(t *testing.T) {
+	t.Log("EndpointWaitlistPosition")
+
+	for _, testcase := range endpointWaitlistPositionTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			WaitlistEndpoint: testcase.waitlistEndpoint,
+		}
+		result, err := endpointMock.WaitlistPosition(context.Background(), "jjj.www.ttt", testcase.id)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointConfirmTest = []struct {
+	name            string
+	id              string
+	confirmEndpoint endpoint.Endpoint
+	want            rooms.Reservation
+	err             error
+}{
+	{
+		name: "should return the confirmed reservation",
+		id:   "K7QX9M",
+		confirmEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &BookResponse{Reservation: testReservation}, nil
+		},
+		want: testReservation,
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		id:   "K7QX9M",
+		confirmEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		id:   "K7QX9M",
+		confirmEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrHoldExpired()
+		},
+		err: rooms.ErrHoldExpired(),
+	},
+}
+
+func TestEndpointConfirm(t *testing.T) {
+	t.Log("EndpointConfirm")
+
+	for _, testcase := range endpointConfirmTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			ConfirmEndpoint: testcase.confirmEndpoint,
+		}
+		result, err := endpointMock.Confirm(context.Background(), "jjj.www.ttt", testcase.id, 0)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointModifyBookingTest = []struct {
+	name           string
+	id             string
+	modifyEndpoint endpoint.Endpoint
+	want           rooms.Reservation
+	err            error
+}{
+	{
+		name: "should return the moved reservation",
+		id:   "K7QX9M",
+		modifyEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &BookResponse{Reservation: testReservation}, nil
+		},
+		want: testReservation,
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		id:   "K7QX9M",
+		modifyEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		id:   "K7QX9M",
+		modifyEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrRoomNotAvailable()
+		},
+		err: rooms.ErrRoomNotAvailable(),
+	},
+}
+
+func TestEndpointModifyBooking(t *testing.T) {
+	t.Log("EndpointModifyBooking")
+
+	for _, testcase := range endpointModifyBookingTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			ModifyEndpoint: testcase.modifyEndpoint,
+		}
+		result, err := endpointMock.ModifyBooking(context.Background(), "jjj.www.ttt", testcase.id, time.Time{}, time.Time{}, 2, 0)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointAddBlackoutTest = []struct {
+	name                string
+	addBlackoutEndpoint endpoint.Endpoint
+	want                rooms.Blackout
+	conflicts           []rooms.Reservation
+	err                 error
+}{
+	{
+		name: "should return the blackout and its conflicts",
+		addBlackoutEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &AddBlackoutResponse{Blackout: testBlackout, Conflicts: []rooms.Reservation{testReservation}}, nil
+		},
+		want:      testBlackout,
+		conflicts: []rooms.Reservation{testReservation},
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		addBlackoutEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return an error if the endpoint returns an error",
+		addBlackoutEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return nil, rooms.ErrNotAdmin()
+		},
+		err: rooms.ErrNotAdmin(),
+	},
+}
+
+func TestEndpointAddBlackout(t *testing.T) {
+	t.Log("EndpointAddBlackout")
+
+	for _, testcase := range endpointAddBlackoutTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			AddBlackoutEndpoint: testcase.addBlackoutEndpoint,
+		}
+		result, conflicts, err := endpointMock.AddBlackout(context.Background(), "jjj.www.ttt", testBlackout)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, conflicts, testcase.conflicts)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointRemoveBlackoutTest = []struct {
+	name                 string
+	liftBlackoutEndpoint endpoint.Endpoint
+	err                  error
+}{
+	{
+		name: "should remove the blackout",
+		liftBlackoutEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &RemoveBlackoutResponse{}, nil
+		},
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		liftBlackoutEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return the error of the response",
+		liftBlackoutEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &RemoveBlackoutResponse{Err: rooms.ErrBlackoutNotFound()}, nil
+		},
+		err: rooms.ErrBlackoutNotFound(),
+	},
+}
+
+func TestEndpointRemoveBlackout(t *testing.T) {
+	t.Log("EndpointRemoveBlackout")
+
+	for _, testcase := range endpointRemoveBlackoutTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			LiftBlackoutEndpoint: testcase.liftBlackoutEndpoint,
+		}
+		err := endpointMock.RemoveBlackout(context.Background(), "jjj.www.ttt", testBlackout.Id)
+
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+var endpointHistoryTest = []struct {
+	name            string
+	historyEndpoint endpoint.Endpoint
+	want            []rooms.Event
+	err             error
+}{
+	{
+		name: "should return the history",
+		historyEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &HistoryResponse{Events: []rooms.Event{{Seq: 1, Type: rooms.EventBooked, Reservation: testReservation}}}, nil
+		},
+		want: []rooms.Event{{Seq: 1, Type: rooms.EventBooked, Reservation: testReservation}},
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		historyEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return the error of the response",
+		historyEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &HistoryResponse{Err: rooms.ErrHistoryNotRecorded()}, nil
+		},
+		err: rooms.ErrHistoryNotRecorded(),
+	},
+}
+
+func TestEndpointHistory(t *testing.T) {
+	t.Log("EndpointHistory")
+
+	for _, testcase := range endpointHistoryTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			HistoryEndpoint: testcase.historyEndpoint,
+		}
+		result, err := endpointMock.History(context.Background(), "jjj.www.ttt", testReservation.Id, 0)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
+
+// Returns a closed channel with the updates
+func testUpdates(updates ...[]rooms.DayAvailability) <-chan []rooms.DayAvailability {
+	c := make(chan []rooms.DayAvailability, len(updates))
+	for _, update := range updates {
+		c <- update
+	}
+	close(c)
+	return c
+}
+
+var endpointWatchAvailabilityTest = []struct {
+	name          string
+	watchEndpoint endpoint.Endpoint
+	want          [][]rooms.DayAvailability
+	err           error
+}{
+	{
+		name: "should return the updates",
+		watchEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &WatchAvailabilityResponse{Updates: testUpdates(
+				[]rooms.DayAvailability{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), Available: 5}},
+				[]rooms.DayAvailability{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), Available: 4}},
+			)}, nil
+		},
+		want: [][]rooms.DayAvailability{
+			{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), Available: 5}},
+			{{Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC), Available: 4}},
+		},
+	},
+	{
+		name: "should return an error if the response has the wrong structure",
+		watchEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return 1, nil
+		},
+		err: ErrInvalidResponseStructure(),
+	},
+	{
+		name: "should return the error of the response",
+		watchEndpoint: func(_ context.Context, _ interface{}) (interface{}, error) {
+			return &WatchAvailabilityResponse{Err: rooms.ErrInvalidDateRange()}, nil
+		},
+		err: rooms.ErrInvalidDateRange(),
+	},
+}
+
+func TestEndpointWatchAvailability(t *testing.T) {
+	t.Log("EndpointWatchAvailability")
+
+	for _, testcase := range endpointWatchAvailabilityTest {
+		t.Logf(testcase.name)
+
+		endpointMock := Endpoints{
+			WatchEndpoint: testcase.watchEndpoint,
+		}
+		updates, err := endpointMock.WatchAvailability(context.Background(), time.Time{}, time.Time{}, rooms.RoomFilter{})
+
+		var result [][]rooms.DayAvailability
+		if updates != nil {
+			for update := range updates {
+				result = append(result, update)
+			}
+		}
+		assert.DeepEqual(t, result, testcase.want)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
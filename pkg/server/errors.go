@@ -3,6 +3,9 @@ package server
 const (
 	InvalidRequestStructure  = "Invalid request structure"
 	InvalidResponseStructure = "Invalid response structure"
+	InvalidPage              = "Invalid page"
+	StayAcrossShards         = "Stay spans dates owned by different shards"
+	InvalidPrecondition      = "Invalid If-Match header"
 )
 
 type ErrorWithMsg struct {
@@ -20,3 +23,15 @@ func ErrInvalidRequestStructure() error {
 func ErrInvalidResponseStructure() error {
 	return ErrorWithMsg{InvalidResponseStructure}
 }
+
+func ErrInvalidPage() error {
+	return ErrorWithMsg{InvalidPage}
+}
+
+func ErrStayAcrossShards() error {
+	return ErrorWithMsg{StayAcrossShards}
+}
+
+func ErrInvalidPrecondition() error {
+	return ErrorWithMsg{InvalidPrecondition}
+}
@@ -3,7 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"go-booking-service/pkg/clients"
@@ -18,18 +22,144 @@ func NewHTTPHandler(endpoint Endpoints) http.Handler {
 
 	m := mux.NewRouter()
 
+	// Requests taking an If-Match header report an invalid one as any other error
+	preconditioned := httptransport.ServerErrorEncoder(errorEncoder)
+
+	m.Methods("POST").Path("/book").Handler(httptransport.NewServer(
+		endpoint.BookEndpoint,
+		decodeHTTPBookStayRequest,
+		encodeHTTPGenericResponse,
+	))
+
 	m.Methods("POST").Path("/book/{date}").Handler(httptransport.NewServer(
 		endpoint.BookEndpoint,
 		decodeHTTPBookRequest,
 		encodeHTTPGenericResponse,
 	))
 
+	m.Methods("POST").Path("/holds").Handler(httptransport.NewServer(
+		endpoint.HoldEndpoint,
+		decodeHTTPBookStayRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("POST").Path("/holds/{id}/confirm").Handler(httptransport.NewServer(
+		endpoint.ConfirmEndpoint,
+		decodeHTTPConfirmRequest,
+		encodeHTTPGenericResponse,
+		preconditioned,
+	))
+
 	m.Methods("GET").Path("/check/{date}").Handler(httptransport.NewServer(
 		endpoint.CheckEndpoint,
 		decodeHTTPCheckRequest,
 		encodeHTTPGenericResponse,
 	))
 
+	m.Methods("GET").Path("/availability").Handler(httptransport.NewServer(
+		endpoint.AvailabilityEndpoint,
+		decodeHTTPAvailabilityRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("GET").Path("/availability/watch").Handler(httptransport.NewServer(
+		endpoint.WatchEndpoint,
+		decodeHTTPAvailabilityRequest,
+		encodeHTTPEventStream,
+	))
+
+	m.Methods("GET").Path("/bookings").Handler(httptransport.NewServer(
+		endpoint.ListBookingsEndpoint,
+		decodeHTTPListBookingsRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("GET").Path("/bookings/{id}").Handler(httptransport.NewServer(
+		endpoint.ReservationEndpoint,
+		decodeHTTPGetReservationRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("PATCH").Path("/bookings/{id}").Handler(httptransport.NewServer(
+		endpoint.ModifyEndpoint,
+		decodeHTTPModifyBookingRequest,
+		encodeHTTPGenericResponse,
+		preconditioned,
+	))
+
+	m.Methods("GET").Path("/bookings/{id}/history").Handler(httptransport.NewServer(
+		endpoint.HistoryEndpoint,
+		decodeHTTPBookingHistoryRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("DELETE").Path("/bookings/{id}").Handler(httptransport.NewServer(
+		endpoint.CancelEndpoint,
+		decodeHTTPCancelRequest,
+		encodeHTTPGenericResponse,
+		preconditioned,
+	))
+
+	m.Methods("POST").Path("/waitlist").Handler(httptransport.NewServer(
+		endpoint.JoinWaitlistEndpoint,
+		decodeHTTPJoinWaitlistRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("GET").Path("/waitlist/{id}").Handler(httptransport.NewServer(
+		endpoint.WaitlistEndpoint,
+		decodeHTTPWaitlistPositionRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("GET").Path("/rooms").Handler(httptransport.NewServer(
+		endpoint.ListRoomsEndpoint,
+		decodeHTTPListRoomsRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("POST").Path("/rooms").Handler(httptransport.NewServer(
+		endpoint.CreateRoomEndpoint,
+		decodeHTTPCreateRoomRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("PUT").Path("/rooms/{id}").Handler(httptransport.NewServer(
+		endpoint.UpdateRoomEndpoint,
+		decodeHTTPUpdateRoomRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("DELETE").Path("/rooms/{id}").Handler(httptransport.NewServer(
+		endpoint.DecommissionEndpoint,
+		decodeHTTPDecommissionRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("GET").Path("/rooms/{id}/history").Handler(httptransport.NewServer(
+		endpoint.HistoryEndpoint,
+		decodeHTTPRoomHistoryRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("POST").Path("/blackouts").Handler(httptransport.NewServer(
+		endpoint.AddBlackoutEndpoint,
+		decodeHTTPAddBlackoutRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("GET").Path("/blackouts").Handler(httptransport.NewServer(
+		endpoint.BlackoutsEndpoint,
+		decodeHTTPListBlackoutsRequest,
+		encodeHTTPGenericResponse,
+	))
+
+	m.Methods("DELETE").Path("/blackouts/{id}").Handler(httptransport.NewServer(
+		endpoint.LiftBlackoutEndpoint,
+		decodeHTTPRemoveBlackoutRequest,
+		encodeHTTPGenericResponse,
+	))
+
 	m.Methods("POST").Path("/authorize/").Handler(httptransport.NewServer(
 		endpoint.AuthorizeEndpoint,
 		decodeHTTPAuthorizeRequest,
@@ -53,15 +183,264 @@ func decodeHTTPBookRequest(_ context.Context, r *http.Request) (interface{}, err
 	}
 	d := mux.Vars(r)["date"]
 	date, err := time.Parse("2006-01-02", d)
-	req.Date = date
+	req.From = date
+	req.To = date.AddDate(0, 0, 1)
+	req.IdempotencyKey = r.Header.Get("Idempotency-Key")
 	return req, err
 }
 
+func decodeHTTPBookStayRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		Token  string           `json:"token"`
+		Count  int              `json:"count"`
+		From   string           `json:"from"`
+		To     string           `json:"to"`
+		Filter rooms.RoomFilter `json:"filter"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return BookRequest{}, err
+	}
+	from, err := time.Parse("2006-01-02", body.From)
+	if err != nil {
+		return BookRequest{}, err
+	}
+	to, err := time.Parse("2006-01-02", body.To)
+	key := r.Header.Get("Idempotency-Key")
+	return BookRequest{Token: body.Token, IdempotencyKey: key, Count: body.Count, From: from, To: to, Filter: body.Filter}, err
+}
+
+// The hold is identified by its reservation id or confirmation code
+func decodeHTTPConfirmRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req = ConfirmRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return req, err
+	}
+	req.Id = mux.Vars(r)["id"]
+	req.Version, err = decodeHTTPIfMatch(r)
+	return req, err
+}
+
+// The waitlisted stay is read as in /book
+func decodeHTTPJoinWaitlistRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	req, err := decodeHTTPBookStayRequest(ctx, r)
+	book := req.(BookRequest)
+	return JoinWaitlistRequest{Token: book.Token, From: book.From, To: book.To, Filter: book.Filter}, err
+}
+
+func decodeHTTPWaitlistPositionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return WaitlistPositionRequest{Token: bearerToken(r), Id: mux.Vars(r)["id"]}, nil
+}
+
 func decodeHTTPCheckRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	d := mux.Vars(r)["date"]
 	date, err := time.Parse("2006-01-02", d)
+	if err != nil {
+		return CheckRequest{}, err
+	}
+	filter, err := decodeHTTPRoomFilter(r.URL.Query())
 
-	return CheckRequest{date}, err
+	return CheckRequest{date, filter}, err
+}
+
+func decodeHTTPAvailabilityRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	from, err := time.Parse("2006-01-02", query.Get("from"))
+	if err != nil {
+		return AvailabilityRequest{}, err
+	}
+	to, err := time.Parse("2006-01-02", query.Get("to"))
+	if err != nil {
+		return AvailabilityRequest{}, err
+	}
+	filter, err := decodeHTTPRoomFilter(query)
+	return AvailabilityRequest{from, to, filter}, err
+}
+
+// Reads the room filter from the query parameters
+// type, min_capacity and amenities (comma separated)
+func decodeHTTPRoomFilter(query url.Values) (rooms.RoomFilter, error) {
+	filter := rooms.RoomFilter{Type: query.Get("type")}
+	if c := query.Get("min_capacity"); c != "" {
+		capacity, err := strconv.Atoi(c)
+		if err != nil {
+			return filter, err
+		}
+		filter.MinCapacity = capacity
+	}
+	if a := query.Get("amenities"); a != "" {
+		filter.Amenities = strings.Split(a, ",")
+	}
+	return filter, nil
+}
+
+func decodeHTTPCancelRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req = CancelRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return req, err
+	}
+	req.Id = mux.Vars(r)["id"]
+	req.Version, err = decodeHTTPIfMatch(r)
+	return req, err
+}
+
+// Returns the version of the reservation expected by the If-Match header,
+// the ETag ("<version>") of a previous response, or 0 for any if there is none or it is *
+func decodeHTTPIfMatch(r *http.Request) (int, error) {
+	etag := r.Header.Get("If-Match")
+	if etag == "" || etag == "*" {
+		return 0, nil
+	}
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, ErrInvalidPrecondition()
+	}
+	version, err := strconv.Atoi(etag[1 : len(etag)-1])
+	if err != nil || version < 1 {
+		return 0, ErrInvalidPrecondition()
+	}
+	return version, nil
+}
+
+// Dates and room are optional, those left out are kept
+func decodeHTTPModifyBookingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		Token string `json:"token"`
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Room  int    `json:"room"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return ModifyBookingRequest{}, err
+	}
+	req := ModifyBookingRequest{Token: body.Token, Id: mux.Vars(r)["id"], Room: body.Room}
+	if req.Version, err = decodeHTTPIfMatch(r); err != nil {
+		return ModifyBookingRequest{}, err
+	}
+	if body.From != "" || body.To != "" {
+		if req.From, err = time.Parse("2006-01-02", body.From); err != nil {
+			return ModifyBookingRequest{}, err
+		}
+		if req.To, err = time.Parse("2006-01-02", body.To); err != nil {
+			return ModifyBookingRequest{}, err
+		}
+	}
+	return req, nil
+}
+
+// The page is read from the offset and limit query parameters
+func decodeHTTPListBookingsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	req := ListBookingsRequest{Token: bearerToken(r)}
+	var err error
+	if req.From, err = time.Parse("2006-01-02", query.Get("from")); err != nil {
+		return req, err
+	}
+	if req.To, err = time.Parse("2006-01-02", query.Get("to")); err != nil {
+		return req, err
+	}
+	if o := query.Get("offset"); o != "" {
+		if req.Offset, err = strconv.Atoi(o); err != nil {
+			return req, err
+		}
+	}
+	if l := query.Get("limit"); l != "" {
+		if req.Limit, err = strconv.Atoi(l); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// The booking is identified by its reservation id or confirmation code
+func decodeHTTPGetReservationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return GetReservationRequest{Token: bearerToken(r), Id: mux.Vars(r)["id"]}, nil
+}
+
+// The booking is identified by its reservation id or confirmation code
+func decodeHTTPBookingHistoryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return HistoryRequest{Token: bearerToken(r), Id: mux.Vars(r)["id"]}, nil
+}
+
+func decodeHTTPRoomHistoryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	room, err := strconv.Atoi(mux.Vars(r)["id"])
+	return HistoryRequest{Token: bearerToken(r), Room: room}, err
+}
+
+// Returns the token of the Authorization header ("Bearer <token>")
+// used by GET requests, which have no body
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func decodeHTTPListRoomsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListRoomsRequest{}, nil
+}
+
+func decodeHTTPCreateRoomRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req = RoomRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+// The room id is taken from the path, any id in the body is ignored
+func decodeHTTPUpdateRoomRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req = RoomRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return req, err
+	}
+	req.Room.Id, err = strconv.Atoi(mux.Vars(r)["id"])
+	return req, err
+}
+
+func decodeHTTPDecommissionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req = DecommissionRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return req, err
+	}
+	req.Id, err = strconv.Atoi(mux.Vars(r)["id"])
+	return req, err
+}
+
+// Room 0 (or left out) blacks out the whole property
+func decodeHTTPAddBlackoutRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		Token  string `json:"token"`
+		Room   int    `json:"room"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Reason string `json:"reason"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return AddBlackoutRequest{}, err
+	}
+	req := AddBlackoutRequest{Token: body.Token, Blackout: rooms.Blackout{Room: body.Room, Reason: body.Reason}}
+	if req.Blackout.From, err = time.Parse("2006-01-02", body.From); err != nil {
+		return AddBlackoutRequest{}, err
+	}
+	if req.Blackout.To, err = time.Parse("2006-01-02", body.To); err != nil {
+		return AddBlackoutRequest{}, err
+	}
+	return req, nil
+}
+
+func decodeHTTPListBlackoutsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return ListBlackoutsRequest{Token: bearerToken(r)}, nil
+}
+
+func decodeHTTPRemoveBlackoutRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req = RemoveBlackoutRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return req, err
+	}
+	req.Id = mux.Vars(r)["id"]
+	return req, nil
 }
 
 func decodeHTTPAuthorizeRequest(_ context.Context, r *http.Request) (interface{}, error) {
@@ -76,24 +455,79 @@ func decodeHTTPValidateRequest(_ context.Context, r *http.Request) (interface{},
 	return req, err
 }
 
+// versioned is a response about a single reservation
+type versioned interface {
+	Version() int
+}
+
+// Responses about a single reservation send its version as their ETag
 func encodeHTTPGenericResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
 		errorEncoder(ctx, f.Failed(), w)
 		return nil
 	}
+	if v, ok := response.(versioned); ok && v.Version() > 0 {
+		w.Header().Set("ETag", `"`+strconv.Itoa(v.Version())+`"`)
+	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(w).Encode(response)
 }
 
+// Sends every update as a Server-Sent Event until the watch ends
+// Errors are sent as a regular response before the stream starts
+func encodeHTTPEventStream(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	resp, ok := response.(*WatchAvailabilityResponse)
+	if !ok {
+		return ErrInvalidResponseStructure()
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for days := range resp.Updates {
+		data, err := json.Marshal(struct {
+			Days []rooms.DayAvailability `json:"days"`
+		}{days})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: availability\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// Rule violations add their machine-readable reason to the body
 func errorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 	w.WriteHeader(err2code(err))
-	json.NewEncoder(w).Encode(errorWrapper{Error: err.Error()})
+	body := errorWrapper{Error: err.Error()}
+	if violation, ok := err.(rooms.RuleViolation); ok {
+		body.Reason = violation.Reason
+	}
+	json.NewEncoder(w).Encode(body)
 }
 
 func err2code(err error) int {
+	if _, ok := err.(rooms.RuleViolation); ok {
+		return http.StatusUnprocessableEntity
+	}
 	switch err.Error() {
 	case clients.InvalidRequestStructure:
 		return http.StatusBadRequest
+	case InvalidPage:
+		return http.StatusBadRequest
+	case StayAcrossShards:
+		return http.StatusUnprocessableEntity
+	case InvalidPrecondition:
+		return http.StatusBadRequest
 	case clients.InvalidResponseStructure:
 		return http.StatusBadRequest
 	case clients.InvalidCredentials:
@@ -106,10 +540,63 @@ func err2code(err error) int {
 		return http.StatusNotFound
 	case rooms.NoRoomAvailable:
 		return http.StatusNotFound
+	case rooms.RoomNotFound:
+		return http.StatusNotFound
+	case rooms.BookingNotFound:
+		return http.StatusNotFound
+	case rooms.NotBookingOwner:
+		return http.StatusForbidden
+	case rooms.InvalidDateRange:
+		return http.StatusBadRequest
+	case rooms.InvalidRoomType:
+		return http.StatusBadRequest
+	case rooms.InvalidRoom:
+		return http.StatusBadRequest
+	case rooms.RoomExists:
+		return http.StatusConflict
+	case rooms.RoomHasBookings:
+		return http.StatusConflict
+	case rooms.NotAdmin:
+		return http.StatusForbidden
+	case rooms.ReservationExists:
+		return http.StatusConflict
+	case rooms.WaitlistEntryNotFound:
+		return http.StatusNotFound
+	case rooms.NotHeld:
+		return http.StatusConflict
+	case rooms.HoldExpired:
+		return http.StatusGone
+	case rooms.InvalidIdempotencyKey:
+		return http.StatusBadRequest
+	case rooms.InvalidRoomCount:
+		return http.StatusBadRequest
+	case rooms.RoomNotAvailable:
+		return http.StatusConflict
+	case rooms.BlackoutNotFound:
+		return http.StatusNotFound
+	case rooms.QuotaExceeded:
+		return http.StatusTooManyRequests
+	case rooms.HistoryNotRecorded:
+		return http.StatusNotImplemented
+	case rooms.NotLeader:
+		return http.StatusServiceUnavailable
+	case rooms.ChangeNotCommitted:
+		return http.StatusServiceUnavailable
+	case rooms.VersionConflict:
+		return http.StatusConflict
+	case rooms.IdempotencyKeyUsed:
+		return http.StatusConflict
+	case rooms.InvalidWebhook:
+		return http.StatusBadRequest
+	case rooms.WebhookNotFound:
+		return http.StatusNotFound
+	case rooms.WebhooksNotEnabled:
+		return http.StatusNotImplemented
 	}
 	return http.StatusInternalServerError
 }
 
 type errorWrapper struct {
-	Error string `json:"error"`
+	Error  string `json:"error"`
+	Reason string `json:"reason,omitempty"`
 }
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-booking-service/commons"
+	"go-booking-service/pkg/rooms"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// versionTestRooms only changes the test reservation when no version
+// or its version is expected
+type versionTestRooms struct {
+	mockCorrectEndpoint
+}
+
+func (m versionTestRooms) Cancel(ctx context.Context, token, id string, version int) error {
+	if version != 0 && version != testReservation.Version {
+		return rooms.ErrVersionConflict()
+	}
+	return nil
+}
+
+func (m versionTestRooms) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (rooms.Reservation, error) {
+	if version != 0 && version != testReservation.Version {
+		return rooms.Reservation{}, rooms.ErrVersionConflict()
+	}
+	moved := testReservation
+	moved.Version++
+	return moved, nil
+}
+
+func (m versionTestRooms) Confirm(ctx context.Context, token, id string, version int) (rooms.Reservation, error) {
+	if version != 0 && version != testReservation.Version {
+		return rooms.Reservation{}, rooms.ErrVersionConflict()
+	}
+	return testReservation, nil
+}
+
+var httpVersionTest = []struct {
+	name    string
+	method  string
+	path    string
+	ifMatch string
+	code    int
+	etag    string
+}{
+	{
+		name:   "should send the version of a reservation as its ETag",
+		method: "GET",
+		path:   "/bookings/" + testReservation.Id,
+		code:   http.StatusOK,
+		etag:   `"1"`,
+	},
+	{
+		name:    "should modify a reservation with the version expected",
+		method:  "PATCH",
+		path:    "/bookings/" + testReservation.Id,
+		ifMatch: `"1"`,
+		code:    http.StatusOK,
+		etag:    `"2"`,
+	},
+	{
+		name:    "should reject modifying a reservation with another version",
+		method:  "PATCH",
+		path:    "/bookings/" + testReservation.Id,
+		ifMatch: `"2"`,
+		code:    http.StatusConflict,
+	},
+	{
+		name:    "should cancel a reservation with the version expected",
+		method:  "DELETE",
+		path:    "/bookings/" + testReservation.Id,
+		ifMatch: `"1"`,
+		code:    http.StatusOK,
+	},
+	{
+		name:    "should reject cancelling a reservation with another version",
+		method:  "DELETE",
+		path:    "/bookings/" + testReservation.Id,
+		ifMatch: `"3"`,
+		code:    http.StatusConflict,
+	},
+	{
+		name:    "should cancel a reservation with any version",
+		method:  "DELETE",
+		path:    "/bookings/" + testReservation.Id,
+		ifMatch: "*",
+		code:    http.StatusOK,
+	},
+	{
+		name:    "should reject confirming a hold with another version",
+		method:  "POST",
+		path:    "/holds/" + testReservation.Id + "/confirm",
+		ifMatch: `"2"`,
+		code:    http.StatusConflict,
+	},
+	{
+		name:    "should return an error if the If-Match header is invalid",
+		method:  "POST",
+		path:    "/holds/" + testReservation.Id + "/confirm",
+		ifMatch: "1",
+		code:    http.StatusBadRequest,
+	},
+}
+
+func TestHTTPVersions(t *testing.T) {
+	t.Log("HTTPVersions")
+	handler := NewHTTPHandler(MakeEndpoints(NewServer(mockCorrectEndpoint{}, versionTestRooms{})))
+
+	for _, testcase := range httpVersionTest {
+		t.Logf(testcase.name)
+
+		req := httptest.NewRequest(testcase.method, testcase.path, strings.NewReader(`{"token": "jjj.www.ttt"}`))
+		if testcase.ifMatch != "" {
+			req.Header.Set("If-Match", testcase.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, rec.Code, testcase.code)
+		assert.Equal(t, rec.Header().Get("ETag"), testcase.etag)
+	}
+}
+
+// Returns a gateway to a rooms service with the rooms given, validating tokens as user names
+func newServiceTestHandler(infos []rooms.RoomInfo, options ...rooms.Option) http.Handler {
+	inventory := make([]rooms.Room, len(infos))
+	for i, info := range infos {
+		inventory[i] = rooms.Room{RoomInfo: info, Book: map[time.Time]string{}, Mux: &sync.Mutex{}}
+	}
+	rs := rooms.NewRoomsServer(rooms.NewMemoryStore(inventory), shardValidator{}, options...)
+	return NewHTTPHandler(MakeEndpoints(NewServer(mockCorrectEndpoint{}, rs)))
+}
+
+func TestHTTPModifyBookingRules(t *testing.T) {
+	t.Log("HTTPModifyBookingRules")
+	handler := newServiceTestHandler(
+		[]rooms.RoomInfo{{Id: 1, Type: rooms.SingleRoom}, {Id: 2, Type: rooms.SuiteRoom}},
+		rooms.WithRules(rooms.SuiteRoom, rooms.MinStay(2)),
+	)
+	from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	body := fmt.Sprintf(`{"token": "John", "from": %q, "to": %q, "filter": {"type": %q}}`, from.Format("2006-01-02"), from.AddDate(0, 0, 1).Format("2006-01-02"), rooms.SingleRoom)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/book", strings.NewReader(body)))
+	assert.Equal(t, rec.Code, http.StatusOK)
+	var booked BookResponse
+	assert.NilError(t, json.NewDecoder(rec.Body).Decode(&booked))
+
+	t.Logf("should reject moving a booking to a room whose rules it breaks with its reason")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PATCH", "/bookings/"+booked.Reservation.Id, strings.NewReader(`{"token": "John", "room": 2}`)))
+	assert.Equal(t, rec.Code, http.StatusUnprocessableEntity)
+	var resp errorWrapper
+	assert.NilError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, resp.Reason, rooms.ReasonMinStay)
+}
+
+var httpQuotaTest = []struct {
+	name  string
+	quota *rooms.Quota
+	code  int
+	err   string
+}{
+	{
+		name:  "should book groups of any size with the default quota",
+		quota: &rooms.Quota{MaxActiveBookings: commons.RoomsMaxActiveBookings, MaxRoomsPerDate: commons.RoomsMaxRoomsPerDate},
+		code:  http.StatusOK,
+	},
+	{
+		name:  "should book groups within the quota",
+		quota: &rooms.Quota{MaxRoomsPerDate: 3},
+		code:  http.StatusOK,
+	},
+	{
+		name:  "should reject groups beyond the rooms per date of the quota",
+		quota: &rooms.Quota{MaxRoomsPerDate: 2},
+		code:  http.StatusTooManyRequests,
+		err:   rooms.QuotaExceeded,
+	},
+	{
+		name:  "should reject bookings beyond the active bookings of the quota",
+		quota: &rooms.Quota{MaxActiveBookings: 2},
+		code:  http.StatusTooManyRequests,
+		err:   rooms.QuotaExceeded,
+	},
+}
+
+func TestHTTPQuota(t *testing.T) {
+	t.Log("HTTPQuota")
+	from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	body := fmt.Sprintf(`{"token": "John", "count": 3, "from": %q, "to": %q}`, from.Format("2006-01-02"), from.AddDate(0, 0, 1).Format("2006-01-02"))
+
+	for _, testcase := range httpQuotaTest {
+		t.Logf(testcase.name)
+
+		var options []rooms.Option
+		if testcase.quota != nil {
+			options = append(options, rooms.WithQuota(*testcase.quota))
+		}
+		handler := newServiceTestHandler([]rooms.RoomInfo{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}}, options...)
+		req := httptest.NewRequest("POST", "/book", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, rec.Code, testcase.code)
+		if testcase.err != "" {
+			var resp errorWrapper
+			assert.NilError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, resp.Error, testcase.err)
+		}
+	}
+}
+
+var err2codeTest = []struct {
+	name string
+	err  error
+	code int
+}{
+	{
+		name: "should return 400 for an invalid webhook",
+		err:  rooms.ErrInvalidWebhook(),
+		code: http.StatusBadRequest,
+	},
+	{
+		name: "should return 404 for an unknown webhook",
+		err:  rooms.ErrWebhookNotFound(),
+		code: http.StatusNotFound,
+	},
+	{
+		name: "should return 501 if webhooks are not enabled",
+		err:  rooms.ErrWebhooksNotEnabled(),
+		code: http.StatusNotImplemented,
+	},
+}
+
+func TestErr2Code(t *testing.T) {
+	t.Log("Err2Code")
+
+	for _, testcase := range err2codeTest {
+		t.Logf(testcase.name)
+		assert.Equal(t, err2code(testcase.err), testcase.code)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// Points every shard gets on the ring, so keys spread evenly
+const ringReplicas = 128
+
+// hashRing assigns keys to shards by consistent hashing:
+// adding or removing a shard only moves keys to or from that shard
+type hashRing struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+func newHashRing(names []string) hashRing {
+	ring := hashRing{owners: map[uint32]string{}}
+	for _, name := range names {
+		for i := 0; i < ringReplicas; i++ {
+			point := crc32.ChecksumIEEE([]byte(name + "#" + strconv.Itoa(i)))
+			// Ties go to the lowest name so every ring built from the same shards agrees
+			if owner, taken := ring.owners[point]; taken && owner < name {
+				continue
+			}
+			if _, taken := ring.owners[point]; !taken {
+				ring.points = append(ring.points, point)
+			}
+			ring.owners[point] = name
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// Returns the shard owning a key, the first one clockwise from its hash
+// Returns an empty name if the ring has no shards
+func (r hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	t.Log("HashRing")
+	var keys []string
+	for year := 2020; year < 2040; year++ {
+		for month := 1; month <= 12; month++ {
+			keys = append(keys, fmt.Sprintf("%d-%02d", year, month))
+		}
+	}
+	two := newHashRing([]string{"a", "b"})
+	three := newHashRing([]string{"c", "b", "a"})
+
+	t.Logf("should spread keys over every shard")
+	owned := map[string]int{}
+	for _, key := range keys {
+		owned[three.owner(key)]++
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Assert(t, owned[name] > len(keys)/6, "shard %s owns %d keys", name, owned[name])
+	}
+
+	t.Logf("should only move keys to a shard added")
+	for _, key := range keys {
+		if before, after := two.owner(key), three.owner(key); before != after {
+			assert.Equal(t, after, "c")
+		}
+	}
+
+	t.Logf("should not depend on the order of the shards")
+	for _, key := range keys {
+		assert.Equal(t, newHashRing([]string{"a", "b", "c"}).owner(key), three.owner(key))
+	}
+
+	t.Logf("should return an empty name without shards")
+	assert.Equal(t, newHashRing(nil).owner(keys[0]), "")
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"go-booking-service/pkg/rooms"
 	"time"
 )
 
@@ -11,10 +12,34 @@ type ClientsService interface {
 }
 
 type RoomService interface {
-	Book(context.Context, string, time.Time) (int, error)
-	Check(context.Context, time.Time) (int, error)
+	Book(context.Context, string, string, int, time.Time, time.Time, rooms.RoomFilter) ([]rooms.Reservation, error)
+	Check(context.Context, time.Time, rooms.RoomFilter) (int, error)
+	Cancel(context.Context, string, string, int) error
+	ModifyBooking(context.Context, string, string, time.Time, time.Time, int, int) (rooms.Reservation, error)
+	Availability(context.Context, time.Time, time.Time, rooms.RoomFilter) ([]rooms.DayAvailability, error)
+	WatchAvailability(context.Context, time.Time, time.Time, rooms.RoomFilter) (<-chan []rooms.DayAvailability, error)
+	ListRooms(context.Context) ([]rooms.RoomInfo, error)
+	CreateRoom(context.Context, string, rooms.RoomInfo) (rooms.RoomInfo, error)
+	UpdateRoom(context.Context, string, rooms.RoomInfo) (rooms.RoomInfo, error)
+	DecommissionRoom(context.Context, string, int) error
+	ListBookings(context.Context, string, time.Time, time.Time) ([]rooms.Booking, error)
+	GetReservation(context.Context, string, string) (rooms.Reservation, error)
+	JoinWaitlist(context.Context, string, time.Time, time.Time, rooms.RoomFilter) (rooms.WaitlistEntry, error)
+	WaitlistPosition(context.Context, string, string) (rooms.WaitlistEntry, error)
+	Hold(context.Context, string, time.Time, time.Time, rooms.RoomFilter) (rooms.Reservation, error)
+	Confirm(context.Context, string, string, int) (rooms.Reservation, error)
+	AddBlackout(context.Context, string, rooms.Blackout) (rooms.Blackout, []rooms.Reservation, error)
+	ListBlackouts(context.Context, string) ([]rooms.Blackout, error)
+	RemoveBlackout(context.Context, string, string) error
+	History(context.Context, string, string, int) ([]rooms.Event, error)
 }
 
+// Page sizes of ListBookings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
 func NewServer(clientsClient ClientsService, roomsClient RoomService) ServerService {
 	return ServerService{ClientsClient: clientsClient, RoomClient: roomsClient}
 }
@@ -34,12 +59,122 @@ func (p ServerService) Validate(ctx context.Context, token string) (string, erro
 	return user, err
 }
 
-func (p ServerService) Book(ctx context.Context, token string, date time.Time) (int, error) {
-	book_id, err := p.RoomClient.Book(ctx, token, date)
-	return book_id, err
+func (p ServerService) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter rooms.RoomFilter) ([]rooms.Reservation, error) {
+	reservations, err := p.RoomClient.Book(ctx, token, key, count, from, to, filter)
+	return reservations, err
+}
+
+func (p ServerService) Hold(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.Reservation, error) {
+	reservation, err := p.RoomClient.Hold(ctx, token, from, to, filter)
+	return reservation, err
+}
+
+func (p ServerService) Confirm(ctx context.Context, token, id string, version int) (rooms.Reservation, error) {
+	reservation, err := p.RoomClient.Confirm(ctx, token, id, version)
+	return reservation, err
+}
+
+func (p ServerService) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (rooms.Reservation, error) {
+	reservation, err := p.RoomClient.ModifyBooking(ctx, token, id, from, to, room, version)
+	return reservation, err
 }
 
-func (p ServerService) Check(ctx context.Context, date time.Time) (int, error) {
-	available, err := p.RoomClient.Check(ctx, date)
+func (p ServerService) Check(ctx context.Context, date time.Time, filter rooms.RoomFilter) (int, error) {
+	available, err := p.RoomClient.Check(ctx, date, filter)
 	return available, err
 }
+
+func (p ServerService) GetReservation(ctx context.Context, token, id string) (rooms.Reservation, error) {
+	reservation, err := p.RoomClient.GetReservation(ctx, token, id)
+	return reservation, err
+}
+
+func (p ServerService) Cancel(ctx context.Context, token, id string, version int) error {
+	err := p.RoomClient.Cancel(ctx, token, id, version)
+	return err
+}
+
+func (p ServerService) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.WaitlistEntry, error) {
+	entry, err := p.RoomClient.JoinWaitlist(ctx, token, from, to, filter)
+	return entry, err
+}
+
+func (p ServerService) WaitlistPosition(ctx context.Context, token, id string) (rooms.WaitlistEntry, error) {
+	entry, err := p.RoomClient.WaitlistPosition(ctx, token, id)
+	return entry, err
+}
+
+func (p ServerService) Availability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) ([]rooms.DayAvailability, error) {
+	days, err := p.RoomClient.Availability(ctx, from, to, filter)
+	return days, err
+}
+
+func (p ServerService) WatchAvailability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) (<-chan []rooms.DayAvailability, error) {
+	updates, err := p.RoomClient.WatchAvailability(ctx, from, to, filter)
+	return updates, err
+}
+
+func (p ServerService) ListRooms(ctx context.Context) ([]rooms.RoomInfo, error) {
+	list, err := p.RoomClient.ListRooms(ctx)
+	return list, err
+}
+
+func (p ServerService) CreateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	created, err := p.RoomClient.CreateRoom(ctx, token, room)
+	return created, err
+}
+
+func (p ServerService) UpdateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	updated, err := p.RoomClient.UpdateRoom(ctx, token, room)
+	return updated, err
+}
+
+func (p ServerService) DecommissionRoom(ctx context.Context, token string, id int) error {
+	err := p.RoomClient.DecommissionRoom(ctx, token, id)
+	return err
+}
+
+func (p ServerService) AddBlackout(ctx context.Context, token string, blackout rooms.Blackout) (rooms.Blackout, []rooms.Reservation, error) {
+	added, conflicts, err := p.RoomClient.AddBlackout(ctx, token, blackout)
+	return added, conflicts, err
+}
+
+func (p ServerService) ListBlackouts(ctx context.Context, token string) ([]rooms.Blackout, error) {
+	blackouts, err := p.RoomClient.ListBlackouts(ctx, token)
+	return blackouts, err
+}
+
+func (p ServerService) RemoveBlackout(ctx context.Context, token, id string) error {
+	err := p.RoomClient.RemoveBlackout(ctx, token, id)
+	return err
+}
+
+func (p ServerService) History(ctx context.Context, token, id string, room int) ([]rooms.Event, error) {
+	events, err := p.RoomClient.History(ctx, token, id, room)
+	return events, err
+}
+
+// Returns the bookings of the user skipping the first offset ones,
+// up to limit (DefaultPageSize if 0), along with the total number of bookings
+func (p ServerService) ListBookings(ctx context.Context, token string, from, to time.Time, offset, limit int) ([]rooms.Booking, int, error) {
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if offset < 0 || limit < 0 || limit > MaxPageSize {
+		return nil, 0, ErrInvalidPage()
+	}
+	bookings, err := p.RoomClient.ListBookings(ctx, token, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := len(bookings)
+	if offset > total {
+		offset = total
+	}
+	if offset+limit < total {
+		bookings = bookings[offset : offset+limit]
+	} else {
+		bookings = bookings[offset:]
+	}
+	return bookings, total, nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"go-booking-service/pkg/rooms"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var serviceListBookingsTest = []struct {
+	name   string
+	client RoomService
+	offset int
+	limit  int
+	want   []rooms.Booking
+	total  int
+	err    error
+}{
+	{
+		name:   "should return the first page",
+		client: mockCorrectEndpoint{},
+		limit:  2,
+		want: []rooms.Booking{
+			{Id: "r1", Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)},
+			{Id: "r1", Room: 1, Date: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+		},
+		total: 3,
+	},
+	{
+		name:   "should return the last page",
+		client: mockCorrectEndpoint{},
+		offset: 2,
+		limit:  2,
+		want: []rooms.Booking{
+			{Id: "r2", Room: 2, Date: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+		},
+		total: 3,
+	},
+	{
+		name:   "should return every booking with the default page size",
+		client: mockCorrectEndpoint{},
+		want: []rooms.Booking{
+			{Id: "r1", Room: 1, Date: time.Date(2020, 6, 13, 0, 0, 0, 0, time.UTC)},
+			{Id: "r1", Room: 1, Date: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+			{Id: "r2", Room: 2, Date: time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)},
+		},
+		total: 3,
+	},
+	{
+		name:   "should return an empty page past the last booking",
+		client: mockCorrectEndpoint{},
+		offset: 5,
+		want:   []rooms.Booking{},
+		total:  3,
+	},
+	{
+		name:   "should return an error if the page is too large",
+		client: mockCorrectEndpoint{},
+		limit:  MaxPageSize + 1,
+		err:    ErrInvalidPage(),
+	},
+	{
+		name:   "should return an error if the offset is negative",
+		client: mockCorrectEndpoint{},
+		offset: -1,
+		err:    ErrInvalidPage(),
+	},
+	{
+		name:   "should return an error if the rooms service returns an error",
+		client: mockErrorEndpoint{},
+		err:    rooms.ErrInvalidDateRange(),
+	},
+}
+
+func TestServiceListBookings(t *testing.T) {
+	t.Log("ServiceListBookings")
+
+	for _, testcase := range serviceListBookingsTest {
+		t.Logf(testcase.name)
+
+		service := ServerService{RoomClient: testcase.client}
+		result, total, err := service.ListBookings(context.Background(), "jjj.www.ttt", time.Time{}, time.Time{}, testcase.offset, testcase.limit)
+
+		assert.DeepEqual(t, result, testcase.want)
+		assert.Equal(t, total, testcase.total)
+		assert.DeepEqual(t, err, testcase.err)
+	}
+}
@@ -0,0 +1,633 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-booking-service/pkg/rooms"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Months ahead of the current one checked for bookings when rebalancing,
+	// covering the longest lead time of the rooms service
+	shardHorizonMonths = 13
+	// Longest date range accepted by Availability, as in the rooms service
+	maxRangeDays = 366
+	// Separates the ids of the parts of a blackout added to several shards
+	blackoutIdSeparator = "+"
+)
+
+// Shard is a rooms service owning a slice of the calendar
+type Shard struct {
+	Name   string
+	Client RoomService
+}
+
+// ShardedRooms routes rooms requests to the shards owning the calendar months of their dates,
+// assigned by consistent hashing over the names of the shards.
+// Stays are booked on a single shard, so every night of a stay must be owned by the same one.
+// Requests about a booking by id are tried on every shard, and rooms changes applied to all of them or undone.
+// Months are pinned to the shard they were on when rebalancing moves them if it has bookings on them
+type ShardedRooms struct {
+	shards map[string]RoomService
+	names  []string
+	ring   hashRing
+	pins   map[string]string
+	// Requests hold the read lock until answered so rebalancing never misses a booking
+	mux sync.RWMutex
+}
+
+// NewShardedRooms returns a ShardedRooms routing to the shards,
+// with months pinned to shards by a previous rebalance
+func NewShardedRooms(shards []Shard, pins map[string]string) *ShardedRooms {
+	s := &ShardedRooms{}
+	s.setShards(shards, pins)
+	return s
+}
+
+// Must be called holding the write lock
+func (s *ShardedRooms) setShards(shards []Shard, pins map[string]string) {
+	s.shards = map[string]RoomService{}
+	s.names = make([]string, len(shards))
+	for i, shard := range shards {
+		s.shards[shard.Name] = shard.Client
+		s.names[i] = shard.Name
+	}
+	sort.Strings(s.names)
+	s.ring = newHashRing(s.names)
+	s.pins = map[string]string{}
+	for month, name := range pins {
+		s.pins[month] = name
+	}
+}
+
+// Returns the months pinned to a shard other than the one the ring assigns them
+func (s *ShardedRooms) Pins() map[string]string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	pins := map[string]string{}
+	for month, name := range s.pins {
+		pins[month] = name
+	}
+	return pins
+}
+
+// Rebalance replaces the shards, moving the months from the current one up to a horizon
+// to their shard in the new ring unless they have bookings (or blackouts) on their shard.
+// Requests wait until the months are checked.
+// Returns the months moved, or an error leaving the shards unchanged
+// if a shard removed has bookings in a month
+func (s *ShardedRooms) Rebalance(ctx context.Context, shards []Shard) ([]string, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("no shards to rebalance to")
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	next := NewShardedRooms(shards, nil)
+	pins := map[string]string{}
+	moved := []string{}
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= shardHorizonMonths; i++ {
+		month := first.AddDate(0, i, 0)
+		current, target := s.owner(month), next.owner(month)
+		if current == target {
+			continue
+		}
+		booked, err := monthBooked(ctx, s.shards[current], month)
+		if err != nil {
+			return nil, err
+		}
+		if !booked {
+			moved = append(moved, monthKey(month))
+			continue
+		}
+		if _, kept := next.shards[current]; !kept {
+			return nil, fmt.Errorf("shard %s has bookings in %s", current, monthKey(month))
+		}
+		pins[monthKey(month)] = current
+	}
+	s.setShards(shards, pins)
+	return moved, nil
+}
+
+// Returns whether any room of a shard is taken on any date of a month
+func monthBooked(ctx context.Context, shard RoomService, month time.Time) (bool, error) {
+	list, err := shard.ListRooms(ctx)
+	if err != nil {
+		return false, err
+	}
+	days, err := shard.Availability(ctx, month, month.AddDate(0, 1, -1), rooms.RoomFilter{})
+	if err != nil {
+		return false, err
+	}
+	for _, day := range days {
+		if day.Available < len(list) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func monthKey(date time.Time) string {
+	return date.UTC().Format("2006-01")
+}
+
+// Must be called holding the lock
+func (s *ShardedRooms) owner(date time.Time) string {
+	month := monthKey(date)
+	if name, ok := s.pins[month]; ok {
+		return name
+	}
+	return s.ring.owner(month)
+}
+
+// dateSegment is a range of dates (both included) owned by one shard
+type dateSegment struct {
+	shard    string
+	from, to time.Time
+}
+
+// Splits the dates from one to another (both included) by shard
+// Must be called holding the lock
+func (s *ShardedRooms) segments(from, to time.Time) []dateSegment {
+	var segments []dateSegment
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; !month.After(to); month = month.AddDate(0, 1, 0) {
+		start, end := month, month.AddDate(0, 1, 0).Add(-24*time.Hour)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		owner := s.owner(month)
+		if n := len(segments); n > 0 && segments[n-1].shard == owner {
+			segments[n-1].to = end
+			continue
+		}
+		segments = append(segments, dateSegment{owner, start, end})
+	}
+	return segments
+}
+
+// Returns the name of the shard owning every night from check-in to check-out
+// Invalid stays go to the shard of the check-in to be rejected
+// Must be called holding the lock
+func (s *ShardedRooms) stayShard(from, to time.Time) (string, error) {
+	if !to.After(from) {
+		return s.owner(from), nil
+	}
+	segments := s.segments(from, to.Add(-24*time.Hour))
+	if len(segments) > 1 {
+		return "", ErrStayAcrossShards()
+	}
+	return segments[0].shard, nil
+}
+
+// Calls a request on every shard in turn until one does not fail with an error
+// Returns the error if every shard fails with it
+// Must be called holding the lock
+func (s *ShardedRooms) untilFound(notFound error, call func(string) error) error {
+	for _, name := range s.names {
+		err := call(name)
+		if err == nil || err.Error() != notFound.Error() {
+			return err
+		}
+	}
+	return notFound
+}
+
+// Calls a change on every shard in order, stopping at the first error
+// and undoing it on the shards it was applied to, latest first
+// Undoing is best effort, a shard it fails on is left apart and the error of the change is returned
+// Must be called holding the lock
+func (s *ShardedRooms) all(change func(string) error, undo func(string)) error {
+	for i, name := range s.names {
+		if err := change(name); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				undo(s.names[j])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls a request on every shard in order, stopping at the first error
+// Must be called holding the lock
+func (s *ShardedRooms) each(call func(RoomService) error) error {
+	for _, name := range s.names {
+		if err := call(s.shards[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ShardedRooms) Book(ctx context.Context, token, key string, count int, from, to time.Time, filter rooms.RoomFilter) ([]rooms.Reservation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	shard, err := s.stayShard(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return s.shards[shard].Book(ctx, token, key, count, from, to, filter)
+}
+
+func (s *ShardedRooms) Check(ctx context.Context, date time.Time, filter rooms.RoomFilter) (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.shards[s.owner(date)].Check(ctx, date, filter)
+}
+
+func (s *ShardedRooms) Hold(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.Reservation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	shard, err := s.stayShard(from, to)
+	if err != nil {
+		return rooms.Reservation{}, err
+	}
+	return s.shards[shard].Hold(ctx, token, from, to, filter)
+}
+
+func (s *ShardedRooms) JoinWaitlist(ctx context.Context, token string, from, to time.Time, filter rooms.RoomFilter) (rooms.WaitlistEntry, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	shard, err := s.stayShard(from, to)
+	if err != nil {
+		return rooms.WaitlistEntry{}, err
+	}
+	return s.shards[shard].JoinWaitlist(ctx, token, from, to, filter)
+}
+
+func (s *ShardedRooms) Cancel(ctx context.Context, token, id string, version int) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.untilFound(rooms.ErrBookingNotFound(), func(name string) error {
+		return s.shards[name].Cancel(ctx, token, id, version)
+	})
+}
+
+func (s *ShardedRooms) Confirm(ctx context.Context, token, id string, version int) (rooms.Reservation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var reservation rooms.Reservation
+	err := s.untilFound(rooms.ErrBookingNotFound(), func(name string) (err error) {
+		reservation, err = s.shards[name].Confirm(ctx, token, id, version)
+		return err
+	})
+	return reservation, err
+}
+
+func (s *ShardedRooms) GetReservation(ctx context.Context, token, id string) (rooms.Reservation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var reservation rooms.Reservation
+	err := s.untilFound(rooms.ErrBookingNotFound(), func(name string) (err error) {
+		reservation, err = s.shards[name].GetReservation(ctx, token, id)
+		return err
+	})
+	return reservation, err
+}
+
+// The booking is modified on its shard, which must own the new nights
+func (s *ShardedRooms) ModifyBooking(ctx context.Context, token, id string, from, to time.Time, room, version int) (rooms.Reservation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var owner string
+	err := s.untilFound(rooms.ErrBookingNotFound(), func(name string) error {
+		_, err := s.shards[name].GetReservation(ctx, token, id)
+		owner = name
+		return err
+	})
+	if err != nil {
+		return rooms.Reservation{}, err
+	}
+	if to.After(from) {
+		shard, err := s.stayShard(from, to)
+		if err != nil {
+			return rooms.Reservation{}, err
+		}
+		if shard != owner {
+			return rooms.Reservation{}, ErrStayAcrossShards()
+		}
+	}
+	return s.shards[owner].ModifyBooking(ctx, token, id, from, to, room, version)
+}
+
+func (s *ShardedRooms) WaitlistPosition(ctx context.Context, token, id string) (rooms.WaitlistEntry, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var entry rooms.WaitlistEntry
+	err := s.untilFound(rooms.ErrWaitlistEntryNotFound(), func(name string) (err error) {
+		entry, err = s.shards[name].WaitlistPosition(ctx, token, id)
+		return err
+	})
+	return entry, err
+}
+
+// The availability of every shard is joined in date order
+func (s *ShardedRooms) Availability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) ([]rooms.DayAvailability, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if to.Before(from) || to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return s.shards[s.owner(from)].Availability(ctx, from, to, filter)
+	}
+	days := []rooms.DayAvailability{}
+	for _, segment := range s.segments(from, to) {
+		shardDays, err := s.shards[segment.shard].Availability(ctx, segment.from, segment.to, filter)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, shardDays...)
+	}
+	return days, nil
+}
+
+// The watches of every shard are joined: the first update holds the availability
+// of every date and the watch ends when the watch of any shard ends
+// Watches keep to the shards they started on when shards are rebalanced
+func (s *ShardedRooms) WatchAvailability(ctx context.Context, from, to time.Time, filter rooms.RoomFilter) (<-chan []rooms.DayAvailability, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if to.Before(from) || to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return s.shards[s.owner(from)].WatchAvailability(ctx, from, to, filter)
+	}
+	segments := s.segments(from, to)
+	if len(segments) == 1 {
+		return s.shards[segments[0].shard].WatchAvailability(ctx, from, to, filter)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	streams := make([]<-chan []rooms.DayAvailability, len(segments))
+	for i, segment := range segments {
+		stream, err := s.shards[segment.shard].WatchAvailability(ctx, segment.from, segment.to, filter)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		streams[i] = stream
+	}
+	current := []rooms.DayAvailability{}
+	for _, stream := range streams {
+		days, ok := <-stream
+		if !ok {
+			cancel()
+			return nil, errors.New("availability watch ended")
+		}
+		current = append(current, days...)
+	}
+
+	return mergeWatches(ctx, cancel, current, streams), nil
+}
+
+// Sends the first update given then the updates of every stream,
+// cancelling the watches when any stream ends
+func mergeWatches(ctx context.Context, cancel context.CancelFunc, first []rooms.DayAvailability, streams []<-chan []rooms.DayAvailability) <-chan []rooms.DayAvailability {
+	updates := make(chan []rooms.DayAvailability, 1)
+	updates <- first
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func(stream <-chan []rooms.DayAvailability) {
+			defer wg.Done()
+			defer cancel()
+			for days := range stream {
+				select {
+				case updates <- days:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(stream)
+	}
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+	return updates
+}
+
+// Every shard has every room, rooms missing on a shard are left out
+func (s *ShardedRooms) ListRooms(ctx context.Context) ([]rooms.RoomInfo, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	byShard, err := s.shardRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []rooms.RoomInfo{}
+	for id, room := range byShard[s.names[0]] {
+		if s.onEveryShard(byShard, id) {
+			list = append(list, room)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	return list, nil
+}
+
+// The room is created on every shard with the id the first one gives it,
+// and removed again from the shards it was created on if a shard refuses it
+func (s *ShardedRooms) CreateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if room.Id != 0 {
+		byShard, err := s.shardRooms(ctx)
+		if err != nil {
+			return rooms.RoomInfo{}, err
+		}
+		for _, name := range s.names {
+			if _, ok := byShard[name][room.Id]; ok {
+				return rooms.RoomInfo{}, rooms.ErrRoomExists()
+			}
+		}
+	}
+	created := room
+	err := s.all(func(name string) (err error) {
+		created, err = s.shards[name].CreateRoom(ctx, token, created)
+		return err
+	}, func(name string) {
+		s.shards[name].DecommissionRoom(ctx, token, created.Id)
+	})
+	return created, err
+}
+
+// The room is put back as it was on the shards already updated if a shard refuses the update
+func (s *ShardedRooms) UpdateRoom(ctx context.Context, token string, room rooms.RoomInfo) (rooms.RoomInfo, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	byShard, err := s.shardRooms(ctx)
+	if err != nil {
+		return rooms.RoomInfo{}, err
+	}
+	if !s.onEveryShard(byShard, room.Id) {
+		return rooms.RoomInfo{}, rooms.ErrRoomNotFound()
+	}
+	var updated rooms.RoomInfo
+	err = s.all(func(name string) (err error) {
+		updated, err = s.shards[name].UpdateRoom(ctx, token, room)
+		return err
+	}, func(name string) {
+		s.shards[name].UpdateRoom(ctx, token, byShard[name][room.Id])
+	})
+	return updated, err
+}
+
+// The room is created again on the shards it was already removed from if a shard refuses
+// to remove it, without the past reservations those shards had for it
+func (s *ShardedRooms) DecommissionRoom(ctx context.Context, token string, id int) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	byShard, err := s.shardRooms(ctx)
+	if err != nil {
+		return err
+	}
+	if !s.onEveryShard(byShard, id) {
+		return rooms.ErrRoomNotFound()
+	}
+	return s.all(func(name string) error {
+		return s.shards[name].DecommissionRoom(ctx, token, id)
+	}, func(name string) {
+		s.shards[name].CreateRoom(ctx, token, byShard[name][id])
+	})
+}
+
+// Returns the rooms of every shard by id
+// Must be called holding the lock
+func (s *ShardedRooms) shardRooms(ctx context.Context) (map[string]map[int]rooms.RoomInfo, error) {
+	byShard := map[string]map[int]rooms.RoomInfo{}
+	for _, name := range s.names {
+		list, err := s.shards[name].ListRooms(ctx)
+		if err != nil {
+			return nil, err
+		}
+		byShard[name] = map[int]rooms.RoomInfo{}
+		for _, room := range list {
+			byShard[name][room.Id] = room
+		}
+	}
+	return byShard, nil
+}
+
+// Must be called holding the lock
+func (s *ShardedRooms) onEveryShard(byShard map[string]map[int]rooms.RoomInfo, id int) bool {
+	for _, name := range s.names {
+		if _, ok := byShard[name][id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ShardedRooms) ListBookings(ctx context.Context, token string, from, to time.Time) ([]rooms.Booking, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	bookings := []rooms.Booking{}
+	err := s.each(func(shard RoomService) error {
+		shardBookings, err := shard.ListBookings(ctx, token, from, to)
+		bookings = append(bookings, shardBookings...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(bookings, func(i, j int) bool {
+		if !bookings[i].Date.Equal(bookings[j].Date) {
+			return bookings[i].Date.Before(bookings[j].Date)
+		}
+		return bookings[i].Room < bookings[j].Room
+	})
+	return bookings, nil
+}
+
+// The blackout is added to every shard owning any of its nights
+// and gets the ids of every part joined
+func (s *ShardedRooms) AddBlackout(ctx context.Context, token string, blackout rooms.Blackout) (rooms.Blackout, []rooms.Reservation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if !blackout.To.After(blackout.From) {
+		return s.shards[s.owner(blackout.From)].AddBlackout(ctx, token, blackout)
+	}
+	var ids []string
+	conflicts := []rooms.Reservation{}
+	for _, segment := range s.segments(blackout.From, blackout.To.Add(-24*time.Hour)) {
+		part := blackout
+		part.From, part.To = segment.from, segment.to.Add(24*time.Hour)
+		added, partConflicts, err := s.shards[segment.shard].AddBlackout(ctx, token, part)
+		if err != nil {
+			return rooms.Blackout{}, nil, err
+		}
+		ids = append(ids, added.Id)
+		conflicts = append(conflicts, partConflicts...)
+	}
+	blackout.Id = strings.Join(ids, blackoutIdSeparator)
+	return blackout, conflicts, nil
+}
+
+// Blackouts added to several shards are listed by part
+func (s *ShardedRooms) ListBlackouts(ctx context.Context, token string) ([]rooms.Blackout, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	blackouts := []rooms.Blackout{}
+	err := s.each(func(shard RoomService) error {
+		shardBlackouts, err := shard.ListBlackouts(ctx, token)
+		blackouts = append(blackouts, shardBlackouts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(blackouts, func(i, j int) bool {
+		if !blackouts[i].From.Equal(blackouts[j].From) {
+			return blackouts[i].From.Before(blackouts[j].From)
+		}
+		return blackouts[i].Id < blackouts[j].Id
+	})
+	return blackouts, nil
+}
+
+func (s *ShardedRooms) RemoveBlackout(ctx context.Context, token, id string) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, part := range strings.Split(id, blackoutIdSeparator) {
+		err := s.untilFound(rooms.ErrBlackoutNotFound(), func(name string) error {
+			return s.shards[name].RemoveBlackout(ctx, token, part)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The history of a room joins the histories of every shard by time
+func (s *ShardedRooms) History(ctx context.Context, token, id string, room int) ([]rooms.Event, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var events []rooms.Event
+	if id != "" {
+		err := s.untilFound(rooms.ErrBookingNotFound(), func(name string) (err error) {
+			events, err = s.shards[name].History(ctx, token, id, room)
+			return err
+		})
+		return events, err
+	}
+	events = []rooms.Event{}
+	err := s.each(func(shard RoomService) error {
+		shardEvents, err := shard.History(ctx, token, id, room)
+		events = append(events, shardEvents...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
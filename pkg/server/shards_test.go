@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"go-booking-service/pb"
+	"go-booking-service/pkg/rooms"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"gotest.tools/assert"
+)
+
+type shardValidator struct{}
+
+func (v shardValidator) Validate(_ context.Context, token string) (string, error) {
+	return token, nil
+}
+
+// Starts a rooms service with a number of rooms served over gRPC in memory
+// Returns a shard calling it and a function stopping it
+func newTestShard(t *testing.T, name string, n int) (Shard, func()) {
+	inventory := make([]rooms.Room, n)
+	for i := range inventory {
+		inventory[i] = rooms.Room{RoomInfo: rooms.RoomInfo{Id: i + 1}, Book: map[time.Time]string{}, Mux: &sync.Mutex{}}
+	}
+	rs := rooms.NewRoomsServer(rooms.NewMemoryStore(inventory), shardValidator{}, rooms.WithAdmins("Admin"))
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterRoomsServer(server, rooms.NewGRPCServer(rooms.MakeEndpoints(rs)))
+	go server.Serve(listener)
+	conn, err := grpc.Dial(name, grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	assert.NilError(t, err)
+	return Shard{Name: name, Client: rooms.NewGRPCClient(conn)}, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func newTestShards(t *testing.T, n int, names ...string) ([]Shard, func()) {
+	shards := make([]Shard, len(names))
+	stops := make([]func(), len(names))
+	for i, name := range names {
+		shards[i], stops[i] = newTestShard(t, name, n)
+	}
+	return shards, func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+// Returns the first month from the current one owned by a shard other than the one of the month before
+func testShardBoundary(t *testing.T, s *ShardedRooms) time.Time {
+	t.Helper()
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	for i := 0; i < shardHorizonMonths; i++ {
+		if s.owner(month) != s.owner(month.AddDate(0, -1, 0)) {
+			return month
+		}
+		month = month.AddDate(0, 1, 0)
+	}
+	t.Fatal("no months owned by different shards")
+	return month
+}
+
+// Returns the rooms available on a date on a shard, ignoring the others
+func testShardAvailable(t *testing.T, shard Shard, date time.Time) int {
+	t.Helper()
+	available, err := shard.Client.Check(context.Background(), date, rooms.RoomFilter{})
+	assert.NilError(t, err)
+	return available
+}
+
+func TestShardedRooms(t *testing.T) {
+	t.Log("ShardedRooms")
+	ctx := context.Background()
+	shards, stop := newTestShards(t, 2, "a", "b")
+	defer stop()
+	byName := map[string]Shard{"a": shards[0], "b": shards[1]}
+	s := NewShardedRooms(shards, nil)
+	boundary := testShardBoundary(t, s)
+	before, after := byName[s.owner(boundary.AddDate(0, 0, -1))], byName[s.owner(boundary)]
+
+	t.Logf("should book stays on the shard owning their dates")
+	booked, err := s.Book(ctx, "John", "", 1, boundary.AddDate(0, 0, -2), boundary, rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, testShardAvailable(t, before, boundary.AddDate(0, 0, -1)), 1)
+	assert.Equal(t, testShardAvailable(t, after, boundary.AddDate(0, 0, -1)), 2)
+	available, err := s.Check(ctx, boundary.AddDate(0, 0, -1), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, available, 1)
+	later, err := s.Book(ctx, "Anna", "", 2, boundary, boundary.AddDate(0, 0, 1), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, testShardAvailable(t, after, boundary), 0)
+	assert.Equal(t, testShardAvailable(t, before, boundary), 2)
+
+	t.Logf("should reject stays spanning dates owned by different shards")
+	_, err = s.Book(ctx, "John", "", 1, boundary.AddDate(0, 0, -1), boundary.AddDate(0, 0, 1), rooms.RoomFilter{})
+	assert.DeepEqual(t, err, ErrStayAcrossShards())
+	_, err = s.ModifyBooking(ctx, "John", booked[0].Id, boundary.AddDate(0, 0, -1), boundary.AddDate(0, 0, 1), 0, 0)
+	assert.DeepEqual(t, err, ErrStayAcrossShards())
+
+	t.Logf("should return the errors of the shard for invalid stays")
+	_, err = s.Book(ctx, "John", "", 1, boundary, boundary, rooms.RoomFilter{})
+	assert.Equal(t, err.Error(), rooms.InvalidDateRange)
+
+	t.Logf("should find reservations on any shard")
+	reservation, err := s.GetReservation(ctx, "Anna", later[1].Id)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reservation, later[1])
+	reservation, err = s.GetReservation(ctx, "John", booked[0].Code)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, reservation, booked[0])
+	_, err = s.GetReservation(ctx, "John", "unknown")
+	assert.Equal(t, err.Error(), rooms.BookingNotFound)
+	moved, err := s.ModifyBooking(ctx, "John", booked[0].Id, boundary.AddDate(0, 0, -3), boundary.AddDate(0, 0, -1), 0, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, moved.From, boundary.AddDate(0, 0, -3))
+	assert.NilError(t, s.Cancel(ctx, "Anna", later[0].Id, 0))
+	assert.Equal(t, testShardAvailable(t, after, boundary), 1)
+
+	t.Logf("should join the availability of every shard")
+	days, err := s.Availability(ctx, boundary.AddDate(0, 0, -2), boundary.AddDate(0, 0, 1), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, days, []rooms.DayAvailability{
+		{Date: boundary.AddDate(0, 0, -2), Available: 1},
+		{Date: boundary.AddDate(0, 0, -1), Available: 2},
+		{Date: boundary, Available: 1},
+		{Date: boundary.AddDate(0, 0, 1), Available: 2},
+	})
+	last, err := s.Book(ctx, "John", "", 1, boundary.AddDate(0, 0, 1), boundary.AddDate(0, 0, 2), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	bookings, err := s.ListBookings(ctx, "John", boundary.AddDate(0, 0, -3), boundary.AddDate(0, 0, 1))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, bookings, []rooms.Booking{
+		{Id: moved.Id, Code: moved.Code, Room: moved.Room, Date: boundary.AddDate(0, 0, -3)},
+		{Id: moved.Id, Code: moved.Code, Room: moved.Room, Date: boundary.AddDate(0, 0, -2)},
+		{Id: last[0].Id, Code: last[0].Code, Room: last[0].Room, Date: boundary.AddDate(0, 0, 1)},
+	})
+
+	t.Logf("should create rooms on every shard with the same id")
+	room, err := s.CreateRoom(ctx, "Admin", rooms.RoomInfo{Name: "Penthouse", Type: rooms.SuiteRoom, Capacity: 4})
+	assert.NilError(t, err)
+	for _, shard := range shards {
+		list, err := shard.Client.ListRooms(ctx)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, list[len(list)-1], room)
+	}
+
+	t.Logf("should split blackouts by shard")
+	blackout, conflicts, err := s.AddBlackout(ctx, "Admin", rooms.Blackout{Room: room.Id, From: boundary.AddDate(0, 0, -1), To: boundary.AddDate(0, 0, 2), Reason: "Works"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(conflicts), 0)
+	assert.Equal(t, len(strings.Split(blackout.Id, blackoutIdSeparator)), 2)
+	blackouts, err := s.ListBlackouts(ctx, "Admin")
+	assert.NilError(t, err)
+	assert.Equal(t, len(blackouts), 2)
+	assert.Equal(t, blackouts[0].To, boundary)
+	assert.Equal(t, blackouts[1].From, boundary)
+	assert.NilError(t, s.RemoveBlackout(ctx, "Admin", blackout.Id))
+	blackouts, err = s.ListBlackouts(ctx, "Admin")
+	assert.NilError(t, err)
+	assert.Equal(t, len(blackouts), 0)
+}
+
+// refusingShard fails every room update
+type refusingShard struct {
+	RoomService
+}
+
+func (s refusingShard) UpdateRoom(context.Context, string, rooms.RoomInfo) (rooms.RoomInfo, error) {
+	return rooms.RoomInfo{}, rooms.ErrInvalidRoom()
+}
+
+func TestShardedRoomsRefused(t *testing.T) {
+	t.Log("ShardedRoomsRefused")
+	ctx := context.Background()
+	shards, stop := newTestShards(t, 2, "a", "b")
+	defer stop()
+	s := NewShardedRooms(shards, nil)
+	first, err := shards[0].Client.ListRooms(ctx)
+	assert.NilError(t, err)
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	for s.owner(month) != "b" {
+		month = month.AddDate(0, 1, 0)
+	}
+
+	suite, err := s.CreateRoom(ctx, "Admin", rooms.RoomInfo{Name: "Penthouse", Type: rooms.SuiteRoom, Capacity: 4})
+	assert.NilError(t, err)
+	want := append(first, suite)
+
+	t.Logf("should not create a room taken on any shard")
+	_, err = shards[1].Client.CreateRoom(ctx, "Admin", rooms.RoomInfo{Id: 4, Name: "Loft", Type: rooms.DoubleRoom, Capacity: 2})
+	assert.NilError(t, err)
+	_, err = s.CreateRoom(ctx, "Admin", rooms.RoomInfo{Id: 4, Name: "Attic", Type: rooms.SingleRoom, Capacity: 1})
+	assert.Equal(t, err.Error(), rooms.RoomExists)
+	list, err := shards[0].Client.ListRooms(ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, list, want)
+
+	t.Logf("should only list the rooms of every shard")
+	list, err = s.ListRooms(ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, list, want)
+
+	t.Logf("should put a room back on the shards it was removed from when a shard refuses")
+	_, err = s.Book(ctx, "John", "", 1, month, month.AddDate(0, 0, 1), rooms.RoomFilter{Type: rooms.SuiteRoom})
+	assert.NilError(t, err)
+	err = s.DecommissionRoom(ctx, "Admin", suite.Id)
+	assert.Equal(t, err.Error(), rooms.RoomHasBookings)
+	list, err = shards[0].Client.ListRooms(ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, list, want)
+
+	t.Logf("should put a room back as it was when a shard refuses the update")
+	s = NewShardedRooms([]Shard{shards[0], {Name: "b", Client: refusingShard{shards[1].Client}}}, nil)
+	renamed := suite
+	renamed.Name = "Presidential"
+	_, err = s.UpdateRoom(ctx, "Admin", renamed)
+	assert.Equal(t, err.Error(), rooms.InvalidRoom)
+	list, err = shards[0].Client.ListRooms(ctx)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, list, want)
+}
+
+func TestShardedRoomsWatchAvailability(t *testing.T) {
+	t.Log("ShardedRoomsWatchAvailability")
+	ctx, cancel := context.WithCancel(context.Background())
+	shards, stop := newTestShards(t, 2, "a", "b")
+	defer stop()
+	s := NewShardedRooms(shards, nil)
+	boundary := testShardBoundary(t, s)
+
+	t.Logf("should send the availability of every shard first")
+	updates, err := s.WatchAvailability(ctx, boundary.AddDate(0, 0, -1), boundary, rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, <-updates, []rooms.DayAvailability{
+		{Date: boundary.AddDate(0, 0, -1), Available: 2},
+		{Date: boundary, Available: 2},
+	})
+
+	t.Logf("should send the changes of any shard")
+	_, err = s.Book(ctx, "John", "", 1, boundary, boundary.AddDate(0, 0, 1), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, <-updates, []rooms.DayAvailability{{Date: boundary, Available: 1}})
+
+	t.Logf("should end when the context is done")
+	cancel()
+	for range updates {
+	}
+}
+
+func TestShardedRoomsRebalance(t *testing.T) {
+	t.Log("ShardedRoomsRebalance")
+	ctx := context.Background()
+	shards, stop := newTestShards(t, 2, "a", "b", "c")
+	defer stop()
+	s := NewShardedRooms(shards[:2], nil)
+	next := NewShardedRooms(shards, nil)
+	now := time.Now().UTC()
+	var taken []time.Time
+	for i := 0; i <= shardHorizonMonths; i++ {
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, i, 0)
+		if next.owner(month) == "c" {
+			taken = append(taken, month)
+		}
+	}
+	assert.Assert(t, len(taken) >= 2, "shard c owns %d months", len(taken))
+	booked, moving := taken[0], taken[1:]
+	owner := s.owner(booked)
+	_, err := s.Book(ctx, "John", "", 1, booked.AddDate(0, 0, 14), booked.AddDate(0, 0, 15), rooms.RoomFilter{})
+	assert.NilError(t, err)
+
+	t.Logf("should move months without bookings to a shard added")
+	moved, err := s.Rebalance(ctx, shards)
+	assert.NilError(t, err)
+	want := []string{}
+	for _, month := range moving {
+		want = append(want, monthKey(month))
+	}
+	assert.DeepEqual(t, moved, want)
+	_, err = s.Book(ctx, "Anna", "", 2, moving[0], moving[0].AddDate(0, 0, 1), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, testShardAvailable(t, shards[2], moving[0]), 0)
+
+	t.Logf("should keep months with bookings on their shard")
+	assert.DeepEqual(t, s.Pins(), map[string]string{monthKey(booked): owner})
+	available, err := s.Check(ctx, booked.AddDate(0, 0, 14), rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, available, 1)
+
+	t.Logf("should not remove shards with bookings")
+	_, err = s.Rebalance(ctx, shards[:2])
+	assert.ErrorContains(t, err, "shard c has bookings in "+monthKey(moving[0]))
+	assert.DeepEqual(t, s.Pins(), map[string]string{monthKey(booked): owner})
+	available, err = s.Check(ctx, moving[0], rooms.RoomFilter{})
+	assert.NilError(t, err)
+	assert.Equal(t, available, 0)
+}
@@ -1,19 +1,103 @@
 package server
 
-import "time"
+import (
+	"go-booking-service/pkg/rooms"
+	"time"
+)
 
+// The idempotency key is read from the Idempotency-Key header
 type BookRequest struct {
-	Token string    `json:"token"`
-	Date  time.Time `json:"date"`
+	Token          string           `json:"token"`
+	IdempotencyKey string           `json:"-"`
+	Count          int              `json:"count"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	Filter         rooms.RoomFilter `json:"filter"`
 }
 
 type BookResponse struct {
-	Id  int   `json:"id"`
+	Reservation  rooms.Reservation   `json:"reservation"`
+	Reservations []rooms.Reservation `json:"reservations,omitempty"`
+	Err          error               `json:"err"`
+}
+
+type JoinWaitlistRequest struct {
+	Token  string           `json:"token"`
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Filter rooms.RoomFilter `json:"filter"`
+}
+
+type WaitlistPositionRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type WaitlistResponse struct {
+	Entry rooms.WaitlistEntry `json:"entry"`
+	Err   error               `json:"err"`
+}
+
+// The version expected is read from the If-Match header
+type ConfirmRequest struct {
+	Token   string `json:"token"`
+	Id      string `json:"id"`
+	Version int    `json:"-"`
+}
+
+// The version expected is read from the If-Match header
+type ModifyBookingRequest struct {
+	Token   string    `json:"token"`
+	Id      string    `json:"id"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Room    int       `json:"room"`
+	Version int       `json:"-"`
+}
+
+type AddBlackoutRequest struct {
+	Token    string         `json:"token"`
+	Blackout rooms.Blackout `json:"blackout"`
+}
+
+type AddBlackoutResponse struct {
+	Blackout  rooms.Blackout      `json:"blackout"`
+	Conflicts []rooms.Reservation `json:"conflicts"`
+	Err       error               `json:"err"`
+}
+
+type ListBlackoutsRequest struct {
+	Token string `json:"token"`
+}
+
+type ListBlackoutsResponse struct {
+	Blackouts []rooms.Blackout `json:"blackouts"`
+	Err       error            `json:"err"`
+}
+
+type RemoveBlackoutRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type RemoveBlackoutResponse struct {
 	Err error `json:"err"`
 }
 
+type HistoryRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+	Room  int    `json:"room"`
+}
+
+type HistoryResponse struct {
+	Events []rooms.Event `json:"events"`
+	Err    error         `json:"err"`
+}
+
 type CheckRequest struct {
-	Date time.Time `json:"date"`
+	Date   time.Time        `json:"date"`
+	Filter rooms.RoomFilter `json:"filter"`
 }
 
 type CheckResponse struct {
@@ -21,6 +105,84 @@ type CheckResponse struct {
 	Err       error `json:"err"`
 }
 
+// The version expected is read from the If-Match header
+type CancelRequest struct {
+	Token   string `json:"token"`
+	Id      string `json:"id"`
+	Version int    `json:"-"`
+}
+
+type CancelResponse struct {
+	Err error `json:"err"`
+}
+
+type AvailabilityRequest struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Filter rooms.RoomFilter `json:"filter"`
+}
+
+type AvailabilityResponse struct {
+	Days []rooms.DayAvailability `json:"days"`
+	Err  error                   `json:"err"`
+}
+
+// Updates is closed when the watch ends
+type WatchAvailabilityResponse struct {
+	Updates <-chan []rooms.DayAvailability `json:"-"`
+	Err     error                          `json:"err"`
+}
+
+type ListRoomsRequest struct{}
+
+type ListRoomsResponse struct {
+	Rooms []rooms.RoomInfo `json:"rooms"`
+	Err   error            `json:"err"`
+}
+
+type RoomRequest struct {
+	Token string         `json:"token"`
+	Room  rooms.RoomInfo `json:"room"`
+}
+
+type RoomResponse struct {
+	Room rooms.RoomInfo `json:"room"`
+	Err  error          `json:"err"`
+}
+
+type DecommissionRequest struct {
+	Token string `json:"token"`
+	Id    int    `json:"id"`
+}
+
+type DecommissionResponse struct {
+	Err error `json:"err"`
+}
+
+type GetReservationRequest struct {
+	Token string `json:"token"`
+	Id    string `json:"id"`
+}
+
+type GetReservationResponse struct {
+	Reservation rooms.Reservation `json:"reservation"`
+	Err         error             `json:"err"`
+}
+
+type ListBookingsRequest struct {
+	Token  string    `json:"token"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Offset int       `json:"offset"`
+	Limit  int       `json:"limit"`
+}
+
+type ListBookingsResponse struct {
+	Bookings []rooms.Booking `json:"bookings"`
+	Total    int             `json:"total"`
+	Err      error           `json:"err"`
+}
+
 type AuthorizeRequest struct {
 	User     string `json:"user"`
 	Password string `json:"password"`
@@ -44,6 +206,10 @@ func (r *AuthorizeResponse) Failed() error {
 	return r.Err
 }
 
+func (r *WaitlistResponse) Failed() error {
+	return r.Err
+}
+
 func (r *ValidateResponse) Failed() error {
 	return r.Err
 }
@@ -55,3 +221,63 @@ func (r *BookResponse) Failed() error {
 func (r *CheckResponse) Failed() error {
 	return r.Err
 }
+
+func (r *CancelResponse) Failed() error {
+	return r.Err
+}
+
+func (r *AvailabilityResponse) Failed() error {
+	return r.Err
+}
+
+func (r *WatchAvailabilityResponse) Failed() error {
+	return r.Err
+}
+
+func (r *ListRoomsResponse) Failed() error {
+	return r.Err
+}
+
+func (r *RoomResponse) Failed() error {
+	return r.Err
+}
+
+func (r *DecommissionResponse) Failed() error {
+	return r.Err
+}
+
+func (r *ListBookingsResponse) Failed() error {
+	return r.Err
+}
+
+func (r *GetReservationResponse) Failed() error {
+	return r.Err
+}
+
+// Returns the version of the reservation, 0 if several were booked
+func (r *BookResponse) Version() int {
+	if len(r.Reservations) > 1 {
+		return 0
+	}
+	return r.Reservation.Version
+}
+
+func (r *GetReservationResponse) Version() int {
+	return r.Reservation.Version
+}
+
+func (r *AddBlackoutResponse) Failed() error {
+	return r.Err
+}
+
+func (r *ListBlackoutsResponse) Failed() error {
+	return r.Err
+}
+
+func (r *HistoryResponse) Failed() error {
+	return r.Err
+}
+
+func (r *RemoveBlackoutResponse) Failed() error {
+	return r.Err
+}